@@ -0,0 +1,81 @@
+// Package accounts keeps the users allowed to mine and their secret keys.
+// The store behind it is a JSON file, which every server of a deployment may
+// share, or an SQL database.
+package accounts
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+)
+
+// Errors returned by a UserStore
+var (
+	ErrUnknown  = errors.New("Unknown user")
+	ErrDisabled = errors.New("User disabled")
+	ErrExists   = errors.New("User exists")
+)
+
+// User is a mining account
+type User struct {
+	ID       uint32    `json:"id"`
+	Key      string    `json:"key"`
+	Disabled bool      `json:"disabled"`
+	Created  time.Time `json:"created"`
+	Rotated  time.Time `json:"rotated"` // last time the key changed
+}
+
+// UserStore holds the users and their keys
+type UserStore interface {
+	// Key returns the key of an enabled user
+	Key(id uint32) (string, error)
+	// Create adds user id with key, a new random key if key is empty
+	Create(id uint32, key string) (User, error)
+	// Disable stops user id logging in, Enable lets it again
+	Disable(id uint32) error
+	Enable(id uint32) error
+	// Rotate gives user id a new random key
+	Rotate(id uint32) (User, error)
+	// List returns every user by id
+	List() ([]User, error)
+	Close() error
+}
+
+// Open opens the store described by spec: a JSON file path, or
+// sql:driver:dsn for a database whose driver is linked in
+func Open(spec string) (UserStore, error) {
+	if strings.HasPrefix(spec, "sql:") {
+		w := strings.SplitN(spec, ":", 3)
+		if len(w) != 3 {
+			return nil, errors.New("expected sql:driver:dsn, got " + spec)
+		}
+		if w[1] == "mysql" {
+			w[2] = parseTime(w[2])
+		}
+		return OpenSQL(w[1], w[2])
+	}
+	return OpenFile(spec), nil
+}
+
+// parseTime adds parseTime=true to a MySQL dsn that does not set it: without
+// it the driver cannot scan a TIMESTAMP into the time.Time of a User
+func parseTime(dsn string) string {
+	if strings.Contains(dsn, "parseTime=") {
+		return dsn
+	}
+	if strings.Contains(dsn, "?") {
+		return dsn + "&parseTime=true"
+	}
+	return dsn + "?parseTime=true"
+}
+
+// NewKey returns a random key
+func NewKey() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err) // no randomness, no keys
+	}
+	return hex.EncodeToString(b)
+}
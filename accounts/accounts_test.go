@@ -0,0 +1,237 @@
+package accounts
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.json")
+	store, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Key(1); err != ErrUnknown {
+		t.Errorf("expected ErrUnknown from an empty store, got %v", err)
+	}
+	if _, err := store.Create(1, "thekey"); err != nil {
+		t.Fatal(err)
+	}
+	u, err := store.Create(2, "")
+	if err != nil || len(u.Key) != 32 {
+		t.Fatalf("expected a generated key, got %+v %v", u, err)
+	}
+	if _, err := store.Create(1, "again"); err != ErrExists {
+		t.Errorf("expected ErrExists, got %v", err)
+	}
+
+	// a second server sharing the file sees every change
+	other := OpenFile(path)
+	if key, err := other.Key(1); err != nil || key != "thekey" {
+		t.Errorf("expected thekey, got %q %v", key, err)
+	}
+	if err := store.Disable(1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := other.Key(1); err != ErrDisabled {
+		t.Errorf("expected ErrDisabled, got %v", err)
+	}
+	if err := other.Enable(1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Key(1); err != nil {
+		t.Errorf("expected user 1 enabled again, got %v", err)
+	}
+	rotated, err := other.Rotate(2)
+	if err != nil || rotated.Key == u.Key {
+		t.Errorf("expected a new key for user 2, got %+v %v", rotated, err)
+	}
+	if key, _ := store.Key(2); key != rotated.Key {
+		t.Errorf("store should see the rotated key\nExp: %s\nGot: %s\n", rotated.Key, key)
+	}
+	if err := store.Disable(3); err != ErrUnknown {
+		t.Errorf("expected ErrUnknown, got %v", err)
+	}
+	list, err := store.List()
+	if err != nil || len(list) != 2 || list[0].ID != 1 || list[1].ID != 2 {
+		t.Errorf("unexpected list %+v %v", list, err)
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	for dsn, expected := range map[string]string{
+		"coin:pw@tcp(db:3306)/coin":                 "coin:pw@tcp(db:3306)/coin?parseTime=true",
+		"coin:pw@tcp(db:3306)/coin?tls=true":        "coin:pw@tcp(db:3306)/coin?tls=true&parseTime=true",
+		"coin:pw@tcp(db:3306)/coin?parseTime=false": "coin:pw@tcp(db:3306)/coin?parseTime=false",
+	} {
+		if got := parseTime(dsn); got != expected {
+			t.Errorf("%s: expected %s, got %s", dsn, expected, got)
+		}
+	}
+}
+
+func TestGuard(t *testing.T) {
+	now := time.Unix(0x57f00000, 0)
+	g := NewGuard(2 * time.Minute)
+	hexTime := func(d time.Duration) string { return fmt.Sprintf("%x", now.Add(d).Unix()) }
+
+	// a login within the window is accepted once
+	t0 := hexTime(-time.Minute)
+	if err := g.Check(1, t0, now); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Use(1, "abc", t0, now); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Use(1, "abc", t0, now.Add(time.Second)); err != ErrReplay {
+		t.Errorf("expected ErrReplay, got %v", err)
+	}
+	// ... and once it has expired the window refuses it anyway
+	err := g.Check(1, t0, now.Add(2*time.Minute))
+	if skew, ok := err.(SkewError); !ok || skew.Skew != 3*time.Minute {
+		t.Errorf("expected a 3m skew error, got %v", err)
+	}
+	if len(g.used) != 0 {
+		t.Errorf("expired tokens should be forgotten, %d left", len(g.used))
+	}
+	if _, ok := g.Check(1, hexTime(5*time.Minute), now).(SkewError); !ok {
+		t.Error("expected a skew error for a clock running ahead")
+	}
+	if err := g.Check(1, "not hex", now); err != ErrChallenge {
+		t.Errorf("expected ErrChallenge, got %v", err)
+	}
+
+	// a challenge is for its user, and for one login
+	nonce, _ := g.Challenge(2, now)
+	if err := g.Check(1, nonce, now); err != ErrChallenge {
+		t.Errorf("another user's nonce: expected ErrChallenge, got %v", err)
+	}
+	if err := g.Check(2, nonce, now.Add(time.Minute)); err != nil {
+		t.Errorf("expected the nonce to be good, got %v", err)
+	}
+	if err := g.Use(2, "def", nonce, now.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Check(2, nonce, now.Add(time.Minute)); err != ErrChallenge {
+		t.Errorf("a used nonce: expected ErrChallenge, got %v", err)
+	}
+	nonce, _ = g.Challenge(2, now)
+	if err := g.Check(2, nonce, now.Add(3*time.Minute)); err != ErrChallenge {
+		t.Errorf("an expired nonce: expected ErrChallenge, got %v", err)
+	}
+}
+
+func TestGuardChallengesPerUser(t *testing.T) {
+	g := NewGuard(2 * time.Minute)
+	now := time.Unix(1700000000, 0)
+	first, _ := g.Challenge(1, now)
+	used, _ := g.Challenge(1, now)
+	if err := g.Use(1, "login", used, now); err != nil {
+		t.Fatal(err)
+	}
+	var kept, last string // the used nonce does not count against the cap
+	for i := 0; i < perUser; i++ {
+		var flood bool
+		last, flood = g.Challenge(1, now)
+		if i == 0 {
+			kept = last
+		}
+		if flood != (i == perUser-1) {
+			t.Errorf("challenge %d: flood %v", i, flood)
+		}
+	}
+	if err := g.Check(1, first, now); err != ErrChallenge {
+		t.Errorf("the oldest nonce past the cap: expected ErrChallenge, got %v", err)
+	}
+	if err := g.Check(1, kept, now); err != nil {
+		t.Errorf("the oldest nonce kept: %v", err)
+	}
+	if err := g.Check(1, last, now); err != nil {
+		t.Errorf("the latest nonce: %v", err)
+	}
+	if n := len(g.challenges); n != perUser {
+		t.Errorf("expected %d nonces outstanding, got %d", perUser, n)
+	}
+	other, _ := g.Challenge(2, now) // another user's are not dropped
+	if err := g.Check(2, other, now); err != nil || len(g.challenges) != perUser+1 {
+		t.Errorf("another user's nonce: %v, %d outstanding", err, len(g.challenges))
+	}
+	g.Challenge(1, now.Add(3*time.Minute)) // the rest expire
+	if n := len(g.issued[1]); n != 1 || len(g.issued) != 1 {
+		t.Errorf("expected expired nonces forgotten, %d left of user 1, %d users", n, len(g.issued))
+	}
+}
+
+func TestGuardChallengesFull(t *testing.T) {
+	g := NewGuard(2 * time.Minute)
+	now := time.Unix(1700000000, 0)
+	for user := uint32(0); user < maxChallenges/perUser; user++ {
+		for i := 0; i < perUser; i++ {
+			if _, flood := g.Challenge(user, now); flood {
+				t.Fatalf("user %d challenge %d: flood before the guard is full", user, i)
+			}
+		}
+	}
+	if nonce, flood := g.Challenge(1<<20, now); nonce != "" || !flood {
+		t.Errorf("a full guard issued %q, flood %v", nonce, flood)
+	}
+	if len(g.challenges) != maxChallenges {
+		t.Errorf("expected %d outstanding, got %d", maxChallenges, len(g.challenges))
+	}
+	if nonce, flood := g.Challenge(1<<20, now.Add(3*time.Minute)); nonce == "" || flood {
+		t.Errorf("expected a nonce once the rest expired, got %q, flood %v", nonce, flood)
+	}
+}
+
+func TestSessions(t *testing.T) {
+	now := time.Unix(0x57f00000, 0)
+	s := NewSessions(10 * time.Minute)
+	token, expires := s.Issue("abc", 1, now)
+	if !expires.Equal(now.Add(10 * time.Minute)) {
+		t.Errorf("\nExp: %s\nGot: %s\n", now.Add(10*time.Minute), expires)
+	}
+	// each use keeps the session alive
+	for i := 1; i <= 3; i++ {
+		ss, err := s.Check(token, now.Add(time.Duration(i)*9*time.Minute))
+		if err != nil || ss.Name != "abc" || ss.User != 1 {
+			t.Fatalf("check %d: %+v %v", i, ss, err)
+		}
+	}
+	if _, err := s.Check(token, now.Add(38*time.Minute)); err != ErrExpired {
+		t.Errorf("expected ErrExpired, got %v", err)
+	}
+	if _, err := s.Check(token, now.Add(38*time.Minute)); err != ErrNoSession {
+		t.Errorf("an expired token is forgotten: expected ErrNoSession, got %v", err)
+	}
+
+	// logging in again or out ends the old session
+	old, _ := s.Issue("abc", 1, now)
+	token, _ = s.Issue("abc", 1, now)
+	if _, err := s.Check(old, now); err != ErrNoSession {
+		t.Errorf("expected ErrNoSession for the replaced token, got %v", err)
+	}
+	s.End("abc")
+	if _, err := s.Check(token, now); err != ErrNoSession {
+		t.Errorf("expected ErrNoSession after End, got %v", err)
+	}
+
+	// a session saved by one run resumes in the next
+	token, expires = s.Issue("def", 2, now)
+	saved, until, ok := s.Token("def")
+	if !ok || saved != token || !until.Equal(expires) {
+		t.Fatalf("Token: %q %s %v", saved, until, ok)
+	}
+	restarted := NewSessions(10 * time.Minute)
+	restarted.Resume(saved, Session{"def", 2, until})
+	if ss, err := restarted.Check(token, now.Add(time.Minute)); err != nil || ss.Name != "def" || ss.User != 2 {
+		t.Errorf("resumed session: %+v %v", ss, err)
+	}
+	if _, _, ok := restarted.Token("abc"); ok {
+		t.Error("Token: expected no session for abc")
+	}
+	if SameKey("", "") || !SameKey("k", "k") || SameKey("k", "j") {
+		t.Error("SameKey: an empty key must never match, others only themselves")
+	}
+}
@@ -0,0 +1,202 @@
+package accounts
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// FileStore keeps users in a JSON file. Changes are written under an
+// exclusive lock, and each server reloads the file when it has changed, so
+// servers sharing it see users added or disabled elsewhere
+type FileStore struct {
+	sync.Mutex
+	path  string
+	users map[uint32]User
+	mod   time.Time // of the file as last read
+	size  int64
+}
+
+// OpenFile returns the store kept at path, the file is created on the first change
+func OpenFile(path string) *FileStore {
+	return &FileStore{path: path}
+}
+
+// Key implements UserStore
+func (s *FileStore) Key(id uint32) (string, error) {
+	s.Lock()
+	defer s.Unlock()
+	if err := s.refresh(); err != nil {
+		return "", err
+	}
+	u, ok := s.users[id]
+	switch {
+	case !ok:
+		return "", ErrUnknown
+	case u.Disabled:
+		return "", ErrDisabled
+	}
+	return u.Key, nil
+}
+
+// List implements UserStore
+func (s *FileStore) List() ([]User, error) {
+	s.Lock()
+	defer s.Unlock()
+	if err := s.refresh(); err != nil {
+		return nil, err
+	}
+	list := make([]User, 0, len(s.users))
+	for _, u := range s.users {
+		list = append(list, u)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list, nil
+}
+
+// Create implements UserStore
+func (s *FileStore) Create(id uint32, key string) (User, error) {
+	if key == "" {
+		key = NewKey()
+	}
+	now := time.Now().UTC()
+	u := User{ID: id, Key: key, Created: now, Rotated: now}
+	return u, s.change(func(users map[uint32]User) error {
+		if _, ok := users[id]; ok {
+			return ErrExists
+		}
+		users[id] = u
+		return nil
+	})
+}
+
+// Disable implements UserStore
+func (s *FileStore) Disable(id uint32) error {
+	return s.change(func(users map[uint32]User) error { return setDisabled(users, id, true) })
+}
+
+// Enable implements UserStore
+func (s *FileStore) Enable(id uint32) error {
+	return s.change(func(users map[uint32]User) error { return setDisabled(users, id, false) })
+}
+
+func setDisabled(users map[uint32]User, id uint32, disabled bool) error {
+	u, ok := users[id]
+	if !ok {
+		return ErrUnknown
+	}
+	u.Disabled = disabled
+	users[id] = u
+	return nil
+}
+
+// Rotate implements UserStore
+func (s *FileStore) Rotate(id uint32) (User, error) {
+	var u User
+	return u, s.change(func(users map[uint32]User) error {
+		var ok bool
+		if u, ok = users[id]; !ok {
+			return ErrUnknown
+		}
+		u.Key = NewKey()
+		u.Rotated = time.Now().UTC()
+		users[id] = u
+		return nil
+	})
+}
+
+// Close implements UserStore
+func (s *FileStore) Close() error {
+	return nil
+}
+
+// refresh rereads the file if it has changed since it was last read
+func (s *FileStore) refresh() error {
+	info, err := os.Stat(s.path)
+	if os.IsNotExist(err) {
+		s.users = make(map[uint32]User)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if s.users != nil && info.ModTime().Equal(s.mod) && info.Size() == s.size {
+		return nil
+	}
+	f, err := os.Open(s.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_SH); err != nil {
+		return err
+	}
+	defer syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+	users, err := decode(f)
+	if err != nil {
+		return err
+	}
+	s.users, s.mod, s.size = users, info.ModTime(), info.Size()
+	return nil
+}
+
+// change applies edit to the users on file under an exclusive lock
+func (s *FileStore) change(edit func(users map[uint32]User) error) error {
+	s.Lock()
+	defer s.Unlock()
+	f, err := os.OpenFile(s.path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		return err
+	}
+	defer syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+	users, err := decode(f)
+	if err != nil {
+		return err
+	}
+	if err := edit(users); err != nil {
+		return err
+	}
+	list := make([]User, 0, len(users))
+	for _, u := range users {
+		list = append(list, u)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := f.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := f.WriteAt(data, 0); err != nil {
+		return err
+	}
+	s.users = nil // reread on next use
+	return f.Sync()
+}
+
+func decode(r io.Reader) (map[uint32]User, error) {
+	var list []User
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &list); err != nil {
+			return nil, err
+		}
+	}
+	users := make(map[uint32]User, len(list))
+	for _, u := range list {
+		users[u.ID] = u
+	}
+	return users, nil
+}
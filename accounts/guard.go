@@ -0,0 +1,177 @@
+package accounts
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Errors returned by a Guard
+var (
+	ErrReplay    = errors.New("Login already used")
+	ErrChallenge = errors.New("Login time is neither a unix time nor a challenge")
+)
+
+// SkewError refuses a login whose time is too far from the server's clock
+type SkewError struct {
+	Skew, Window time.Duration
+}
+
+func (e SkewError) Error() string {
+	return fmt.Sprintf("Clock skew %v is outside the %v window", e.Skew, e.Window)
+}
+
+type challenge struct {
+	user    uint32
+	expires time.Time
+}
+
+// Guard stops logins being replayed. A login carries either the client's
+// unix time, which must be within the window of the server's clock, or a
+// challenge nonce the server issued, which may be used once. A login is
+// remembered until its time leaves the window, so it is only accepted once
+type Guard struct {
+	sync.Mutex
+	window     time.Duration
+	used       map[string]time.Time // login tokens, until they expire
+	challenges map[string]challenge // outstanding nonces
+	issued     map[uint32][]string  // each user's outstanding nonces, oldest first
+	pruned     time.Time            // expired tokens and nonces are forgotten every pruneEvery
+}
+
+// pruneEvery keeps a flood of logins from pruning on every call
+const pruneEvery = time.Second
+
+// perUser is how many nonces a user may have outstanding, enough for each of
+// its devices to log in at once. Past it the oldest is dropped, so that
+// asking for nonces and never logging in cannot fill the guard. Past
+// maxChallenges, across the users, no more are issued until some are used
+// or expire
+const (
+	perUser       = 128
+	maxChallenges = 1 << 16
+)
+
+// NewGuard accepts login times within window of the server's clock
+func NewGuard(window time.Duration) *Guard {
+	return &Guard{window: window, used: make(map[string]time.Time), challenges: make(map[string]challenge),
+		issued: make(map[uint32][]string)}
+}
+
+// Challenge issues a nonce for user to log in with instead of the time.
+// Flood reports that too many were outstanding: the oldest of user's was
+// dropped, or, if the guard is full, no nonce is issued
+func (g *Guard) Challenge(user uint32, now time.Time) (nonce string, flood bool) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	nonce = hex.EncodeToString(b)
+	g.Lock()
+	defer g.Unlock()
+	g.prune(now)
+	if len(g.challenges) >= maxChallenges {
+		return "", true
+	}
+	g.challenges[nonce] = challenge{user, now.Add(g.window)}
+	issued := append(g.outstanding(user), nonce)
+	for len(issued) > perUser {
+		delete(g.challenges, issued[0])
+		issued = issued[1:]
+		flood = true
+	}
+	g.issued[user] = issued
+	return nonce, flood
+}
+
+// outstanding is the nonces issued to user still to be used, g must be locked
+func (g *Guard) outstanding(user uint32) []string {
+	var issued []string
+	for _, nonce := range g.issued[user] {
+		if _, ok := g.challenges[nonce]; ok {
+			issued = append(issued, nonce)
+		}
+	}
+	return issued
+}
+
+// Check vets the time t of a login by user before the login is verified
+func (g *Guard) Check(user uint32, t string, now time.Time) error {
+	g.Lock()
+	defer g.Unlock()
+	g.prune(now)
+	if c, ok := g.challenges[t]; ok && now.Before(c.expires) {
+		if c.user != user {
+			return ErrChallenge
+		}
+		return nil
+	}
+	_, err := g.expiry(t, now)
+	return err
+}
+
+// Use records the verified login of user at t, failing if it has been used before
+func (g *Guard) Use(user uint32, login, t string, now time.Time) error {
+	g.Lock()
+	defer g.Unlock()
+	if c, ok := g.challenges[t]; ok && c.user == user && now.Before(c.expires) {
+		delete(g.challenges, t) // once only
+		return nil
+	}
+	expires, err := g.expiry(t, now)
+	if err != nil {
+		return err
+	}
+	token := fmt.Sprintf("%d/%s/%s", user, login, t)
+	if _, ok := g.used[token]; ok {
+		return ErrReplay
+	}
+	g.used[token] = expires
+	return nil
+}
+
+// expiry checks the hex unix time t is within the window and returns when it leaves it
+func (g *Guard) expiry(t string, now time.Time) (time.Time, error) {
+	secs, err := strconv.ParseUint(t, 16, 32)
+	if err != nil || len(t) > 8 {
+		return time.Time{}, ErrChallenge
+	}
+	at := time.Unix(int64(secs), 0)
+	skew := now.Sub(at)
+	if skew < 0 {
+		skew = -skew
+	}
+	if skew > g.window {
+		return time.Time{}, SkewError{skew.Truncate(time.Second), g.window}
+	}
+	return at.Add(g.window), nil
+}
+
+// prune forgets tokens and nonces that have expired
+func (g *Guard) prune(now time.Time) {
+	if now.Sub(g.pruned) < pruneEvery {
+		return
+	}
+	g.pruned = now
+	for token, expires := range g.used {
+		if now.After(expires) {
+			delete(g.used, token)
+		}
+	}
+	for nonce, c := range g.challenges {
+		if now.After(c.expires) {
+			delete(g.challenges, nonce)
+		}
+	}
+	for user := range g.issued {
+		if issued := g.outstanding(user); len(issued) > 0 {
+			g.issued[user] = issued
+		} else {
+			delete(g.issued, user)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package accounts
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"sync"
+	"time"
+)
+
+// The gRPC metadata keys carrying a miner's session token, the conductor's
+// key and an operator's admin key
+const (
+	SessionHeader   = "coin-session"
+	ConductorHeader = "coin-conductor"
+	AdminHeader     = "coin-admin"
+)
+
+// Errors returned by Sessions
+var (
+	ErrNoSession = errors.New("No session, log in first")
+	ErrExpired   = errors.New("Session expired, log in again")
+)
+
+// Session is a logged in miner
+type Session struct {
+	Name    string // the login
+	User    uint32
+	Expires time.Time
+}
+
+// Sessions issues the tokens miners present on every call after Login.
+// A session expires when it has not been used for the ttl
+type Sessions struct {
+	sync.Mutex
+	ttl     time.Duration
+	byToken map[string]*Session
+	byName  map[string]string // login to token
+	pruned  time.Time         // see pruneEvery
+}
+
+// NewSessions keeps sessions alive for ttl after each use
+func NewSessions(ttl time.Duration) *Sessions {
+	return &Sessions{ttl: ttl, byToken: make(map[string]*Session), byName: make(map[string]string)}
+}
+
+// Issue starts a session for login name of user, ending any it had before
+func (s *Sessions) Issue(name string, user uint32, now time.Time) (string, time.Time) {
+	token := NewKey()
+	s.Lock()
+	defer s.Unlock()
+	s.prune(now)
+	s.end(name)
+	s.byToken[token] = &Session{name, user, now.Add(s.ttl)}
+	s.byName[name] = token
+	return token, now.Add(s.ttl)
+}
+
+// Check returns the session of token, extending it
+func (s *Sessions) Check(token string, now time.Time) (Session, error) {
+	s.Lock()
+	defer s.Unlock()
+	ss, ok := s.byToken[token]
+	if !ok {
+		return Session{}, ErrNoSession
+	}
+	if now.After(ss.Expires) {
+		s.end(ss.Name)
+		return Session{}, ErrExpired
+	}
+	ss.Expires = now.Add(s.ttl)
+	return *ss, nil
+}
+
+// Token is the token of login name's session and when it expires, to be
+// saved for Resume
+func (s *Sessions) Token(name string) (string, time.Time, bool) {
+	s.Lock()
+	defer s.Unlock()
+	token, ok := s.byName[name]
+	if !ok {
+		return "", time.Time{}, false
+	}
+	return token, s.byToken[token].Expires, true
+}
+
+// Resume restores a session saved from an earlier run of the server
+func (s *Sessions) Resume(token string, ss Session) {
+	s.Lock()
+	defer s.Unlock()
+	s.end(ss.Name)
+	s.byToken[token] = &ss
+	s.byName[ss.Name] = token
+}
+
+// End ends the session of login name, if it has one
+func (s *Sessions) End(name string) {
+	s.Lock()
+	s.end(name)
+	s.Unlock()
+}
+
+func (s *Sessions) end(name string) {
+	if token, ok := s.byName[name]; ok {
+		delete(s.byToken, token)
+		delete(s.byName, name)
+	}
+}
+
+// prune ends the sessions that have expired
+func (s *Sessions) prune(now time.Time) {
+	if now.Sub(s.pruned) < pruneEvery {
+		return
+	}
+	s.pruned = now
+	for _, ss := range s.byToken {
+		if now.After(ss.Expires) {
+			s.end(ss.Name)
+		}
+	}
+}
+
+// SameKey compares a presented key with the expected one in constant time
+func SameKey(got, want string) bool {
+	return want != "" && subtle.ConstantTimeCompare([]byte(got), []byte(want)) == 1
+}
+
+// Credentials attaches a token to every call made on a gRPC connection, it
+// implements credentials.PerRPCCredentials. The token may change, eg after
+// logging in again
+type Credentials struct {
+	sync.Mutex
+	header, token string
+}
+
+// NewCredentials sends token under the metadata key header
+func NewCredentials(header, token string) *Credentials {
+	return &Credentials{header: header, token: token}
+}
+
+// Set replaces the token
+func (c *Credentials) Set(token string) {
+	c.Lock()
+	c.token = token
+	c.Unlock()
+}
+
+// GetRequestMetadata implements credentials.PerRPCCredentials
+func (c *Credentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	c.Lock()
+	defer c.Unlock()
+	if c.token == "" { // not logged in yet
+		return nil, nil
+	}
+	return map[string]string{c.header: c.token}, nil
+}
+
+// RequireTransportSecurity implements credentials.PerRPCCredentials, tokens
+// are sent on plain connections too
+func (c *Credentials) RequireTransportSecurity() bool {
+	return false
+}
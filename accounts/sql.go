@@ -0,0 +1,127 @@
+package accounts
+
+import (
+	"database/sql"
+	"time"
+)
+
+// SQLStore keeps users in the table
+//
+//	users (id INTEGER PRIMARY KEY, secret TEXT, disabled BOOLEAN, created TIMESTAMP, rotated TIMESTAMP)
+//
+// The driver is not part of this package: link one into the binary, eg the
+// server built with -tags mysql. A MySQL dsn must set parseTime=true, Open
+// adds it
+type SQLStore struct {
+	db *sql.DB
+}
+
+// OpenSQL connects to the database at dsn through driver
+func OpenSQL(driver, dsn string) (*SQLStore, error) {
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &SQLStore{db}, nil
+}
+
+// Key implements UserStore
+func (s *SQLStore) Key(id uint32) (string, error) {
+	var (
+		key      string
+		disabled bool
+	)
+	err := s.db.QueryRow("SELECT secret, disabled FROM users WHERE id = ?", id).Scan(&key, &disabled)
+	switch {
+	case err == sql.ErrNoRows:
+		return "", ErrUnknown
+	case err != nil:
+		return "", err
+	case disabled:
+		return "", ErrDisabled
+	}
+	return key, nil
+}
+
+// Create implements UserStore
+func (s *SQLStore) Create(id uint32, key string) (User, error) {
+	if key == "" {
+		key = NewKey()
+	}
+	now := time.Now().UTC()
+	u := User{ID: id, Key: key, Created: now, Rotated: now}
+	if _, err := s.Key(id); err != ErrUnknown {
+		if err == nil || err == ErrDisabled {
+			err = ErrExists
+		}
+		return u, err
+	}
+	_, err := s.db.Exec("INSERT INTO users (id, secret, disabled, created, rotated) VALUES (?, ?, ?, ?, ?)",
+		u.ID, u.Key, false, u.Created, u.Rotated)
+	return u, err
+}
+
+// Disable implements UserStore
+func (s *SQLStore) Disable(id uint32) error {
+	return s.update("UPDATE users SET disabled = ? WHERE id = ?", true, id)
+}
+
+// Enable implements UserStore
+func (s *SQLStore) Enable(id uint32) error {
+	return s.update("UPDATE users SET disabled = ? WHERE id = ?", false, id)
+}
+
+// Rotate implements UserStore
+func (s *SQLStore) Rotate(id uint32) (User, error) {
+	key, now := NewKey(), time.Now().UTC()
+	if err := s.update("UPDATE users SET secret = ?, rotated = ? WHERE id = ?", key, now, id); err != nil {
+		return User{}, err
+	}
+	var u User
+	err := s.db.QueryRow("SELECT id, secret, disabled, created, rotated FROM users WHERE id = ?", id).
+		Scan(&u.ID, &u.Key, &u.Disabled, &u.Created, &u.Rotated)
+	return u, err
+}
+
+// List implements UserStore
+func (s *SQLStore) List() ([]User, error) {
+	rows, err := s.db.Query("SELECT id, secret, disabled, created, rotated FROM users ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var list []User
+	for rows.Next() {
+		var u User
+		if err := rows.Scan(&u.ID, &u.Key, &u.Disabled, &u.Created, &u.Rotated); err != nil {
+			return nil, err
+		}
+		list = append(list, u)
+	}
+	return list, rows.Err()
+}
+
+// Close implements UserStore
+func (s *SQLStore) Close() error {
+	return s.db.Close()
+}
+
+// update runs a statement that must touch exactly one user
+func (s *SQLStore) update(query string, args ...interface{}) error {
+	res, err := s.db.Exec(query, args...)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrUnknown
+	}
+	return nil
+}
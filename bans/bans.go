@@ -0,0 +1,190 @@
+// Package bans scores misbehaviour, by user and by IP address, and bans an
+// offender for a while once its score reaches a threshold. Scores halve every
+// half life, so a miner that now and then submits a stale share is never
+// banned while one that floods the server with garbage soon is.
+package bans
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Offence is a kind of misbehaviour
+type Offence string
+
+// The offences and what each adds to the score of the offender
+const (
+	Invalid   Offence = "invalid"   // a solution or share that is not our work or misses its target
+	Auth      Offence = "auth"      // a failed login or call without a valid session
+	Duplicate Offence = "duplicate" // a share already submitted
+	Stale     Offence = "stale"     // work on a job that can no longer make a block
+	Flood     Offence = "flood"     // more login challenges asked for than are used
+)
+
+var weights = map[Offence]float64{Invalid: 25, Auth: 20, Duplicate: 5, Flood: 2, Stale: 1}
+
+// User is the key of user id
+func User(id uint32) string {
+	return fmt.Sprintf("user:%d", id)
+}
+
+// IP is the key of the host of addr, a host:port or a bare host
+func IP(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		addr = host
+	}
+	return "ip:" + addr
+}
+
+// Ban is a ban in force
+type Ban struct {
+	Key    string
+	Until  time.Time
+	Reason string
+}
+
+type score struct {
+	value float64
+	at    time.Time
+}
+
+// Table holds the scores and bans of every offender
+type Table struct {
+	sync.Mutex
+	threshold float64
+	duration  time.Duration
+	halfLife  time.Duration
+	scores    map[string]score
+	bans      map[string]Ban
+	pruned    time.Time // forgiven scores and lapsed bans are dropped every pruneEvery
+}
+
+// pruneEvery keeps a flood of offences from pruning on every call
+const pruneEvery = time.Minute
+
+// forgiven is a score decayed so far that it may as well be 0, the least
+// offence is 1
+const forgiven = 0.01
+
+// New bans an offender for duration once its score reaches threshold
+func New(threshold float64, duration, halfLife time.Duration) *Table {
+	return &Table{threshold: threshold, duration: duration, halfLife: halfLife,
+		scores: make(map[string]score), bans: make(map[string]Ban)}
+}
+
+// Score is the score of key at now
+func (t *Table) Score(key string, now time.Time) float64 {
+	t.Lock()
+	defer t.Unlock()
+	return t.score(key, now)
+}
+
+func (t *Table) score(key string, now time.Time) float64 {
+	s, ok := t.scores[key]
+	if !ok {
+		return 0
+	}
+	return s.value * math.Exp2(-now.Sub(s.at).Seconds()/t.halfLife.Seconds())
+}
+
+// Offend scores offence o against each of keys, banning those that reach
+// the threshold. It returns the new bans
+func (t *Table) Offend(o Offence, now time.Time, keys ...string) []Ban {
+	t.Lock()
+	defer t.Unlock()
+	t.prune(now)
+	var banned []Ban
+	for _, key := range keys {
+		if _, ok := t.banned(key, now); ok {
+			continue
+		}
+		v := t.score(key, now) + weights[o]
+		if v < t.threshold {
+			t.scores[key] = score{v, now}
+			continue
+		}
+		delete(t.scores, key)
+		b := Ban{key, now.Add(t.duration), fmt.Sprintf("scored %.0f, last for %s", v, o)}
+		t.bans[key] = b
+		banned = append(banned, b)
+	}
+	return banned
+}
+
+// Banned returns the first ban in force on keys
+func (t *Table) Banned(now time.Time, keys ...string) (Ban, bool) {
+	t.Lock()
+	defer t.Unlock()
+	for _, key := range keys {
+		if b, ok := t.banned(key, now); ok {
+			return b, true
+		}
+	}
+	return Ban{}, false
+}
+
+func (t *Table) banned(key string, now time.Time) (Ban, bool) {
+	b, ok := t.bans[key]
+	if ok && !now.Before(b.Until) {
+		delete(t.bans, key)
+		return Ban{}, false
+	}
+	return b, ok
+}
+
+// Ban bans key for d, the Table's duration if d is 0
+func (t *Table) Ban(key, reason string, d time.Duration, now time.Time) Ban {
+	if d <= 0 {
+		d = t.duration
+	}
+	t.Lock()
+	defer t.Unlock()
+	b := Ban{key, now.Add(d), reason}
+	t.bans[key] = b
+	return b
+}
+
+// Unban lifts the ban on key and clears its score. It reports whether key was banned
+func (t *Table) Unban(key string, now time.Time) bool {
+	t.Lock()
+	defer t.Unlock()
+	_, ok := t.banned(key, now)
+	delete(t.bans, key)
+	delete(t.scores, key)
+	return ok
+}
+
+// List returns the bans in force, soonest lifted first
+func (t *Table) List(now time.Time) []Ban {
+	t.Lock()
+	defer t.Unlock()
+	var list []Ban
+	for key := range t.bans {
+		if b, ok := t.banned(key, now); ok {
+			list = append(list, b)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Until.Before(list[j].Until) })
+	return list
+}
+
+// prune drops the scores that have decayed to nothing and the bans that
+// have lapsed, or every offender ever seen would be kept
+func (t *Table) prune(now time.Time) {
+	if now.Sub(t.pruned) < pruneEvery {
+		return
+	}
+	t.pruned = now
+	for key := range t.scores {
+		if t.score(key, now) < forgiven {
+			delete(t.scores, key)
+		}
+	}
+	for key := range t.bans {
+		t.banned(key, now)
+	}
+}
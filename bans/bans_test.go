@@ -0,0 +1,74 @@
+package bans
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestBans(t *testing.T) {
+	now := time.Date(2016, 10, 1, 12, 0, 0, 0, time.UTC)
+	table := New(100, time.Hour, 10*time.Minute)
+	user, ip := User(7), IP("10.0.0.9:50123")
+	if user != "user:7" || ip != "ip:10.0.0.9" {
+		t.Fatalf("keys %q %q", user, ip)
+	}
+
+	// a stale share now and then never adds up
+	for i := 0; i < 50; i++ {
+		if b := table.Offend(Stale, now.Add(time.Duration(i)*time.Minute), user); b != nil {
+			t.Fatalf("banned for stale shares a minute apart: %v", b)
+		}
+	}
+	if s := table.Score(user, now.Add(50*time.Minute)); s > 15 {
+		t.Errorf("expected the score to level off, got %v", s)
+	}
+
+	// four invalid solutions in quick succession ban both keys
+	for i := 0; i < 3; i++ {
+		if b := table.Offend(Invalid, now.Add(time.Hour), user, ip); b != nil {
+			t.Fatalf("banned on invalid solution %d: %v", i+1, b)
+		}
+	}
+	if banned := table.Offend(Invalid, now.Add(time.Hour), user, ip); len(banned) != 2 {
+		t.Fatalf("expected both keys banned on the fourth, got %v", banned)
+	}
+	if b, ok := table.Banned(now.Add(90*time.Minute), "user:8", user); !ok || b.Key != user || !b.Until.Equal(now.Add(2*time.Hour)) {
+		t.Errorf("expected user banned for an hour, got %v %v", b, ok)
+	}
+	if list := table.List(now.Add(90 * time.Minute)); len(list) != 2 {
+		t.Errorf("expected 2 bans, got %v", list)
+	}
+
+	// bans lapse, or are lifted
+	if _, ok := table.Banned(now.Add(2*time.Hour), user); ok {
+		t.Error("expected the ban to have lapsed")
+	}
+	table.Ban("ip:10.0.0.1", "by hand", 0, now)
+	if !table.Unban("ip:10.0.0.1", now) || table.Unban("ip:10.0.0.1", now) {
+		t.Error("Unban: expected true once, then false")
+	}
+	if _, ok := table.Banned(now, "ip:10.0.0.1"); ok {
+		t.Error("expected no ban once lifted")
+	}
+}
+
+func TestPrune(t *testing.T) {
+	table := New(100, time.Minute, time.Minute)
+	now := time.Unix(1700000000, 0)
+	for i := 0; i < 1000; i++ {
+		table.Offend(Stale, now, IP(fmt.Sprintf("10.0.%d.%d", i/256, i%256)))
+	}
+	table.Ban("user:7", "by an operator", time.Minute, now)
+	if len(table.scores) != 1000 {
+		t.Fatalf("expected 1000 scores, got %d", len(table.scores))
+	}
+	later := now.Add(10 * time.Minute) // 10 half lives, a stale share is forgotten
+	table.Offend(Stale, later, IP("10.9.9.9"))
+	if len(table.scores) != 1 || len(table.bans) != 0 {
+		t.Errorf("expected the forgiven scores and lapsed bans dropped, %d scores and %d bans left", len(table.scores), len(table.bans))
+	}
+	if got := table.Score(IP("10.9.9.9"), later); got != 1 {
+		t.Errorf("the new offence scored %v", got)
+	}
+}
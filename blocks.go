@@ -7,6 +7,7 @@ import (
 	"encoding/binary"
 	"encoding/hex"
 	"errors"
+	"math/big"
 )
 
 const (
@@ -63,18 +64,60 @@ func BlockHeader(Version int, PrevBlock string, TimeStamp int, Bits int) (Block,
 // Bits2Target converts uint32 bits to a 32-byte sequence target
 // which is compared to block hashes. A
 // target is given by m*2**(8*(r-3)) where bits = r|m, r occupying
-// the top byte and m the lower 3 bytes of this uint32.
+// the top byte and m the lower 3 bytes of this uint32. The target is
+// big-endian, the same order as Block.Hash
 func Bits2Target(bits uint32) []byte {
-	r := bits >> 24           // top byte
+	r := int(bits >> 24)      // top byte
 	m := (bits << 8) >> 8     // remaining three bytes
 	b := make([]byte, 32)     // expect 32 byte result but item is shorter
 	mBytes := make([]byte, 4) // receives m
-	pos := 32 - r
-	binary.LittleEndian.PutUint32(mBytes, m)
-	copy(b[pos:pos+3], mBytes[1:])
+	binary.BigEndian.PutUint32(mBytes, m)
+	for i := 0; i < 3; i++ { // m occupies bytes 32-r .. 34-r, anything outside is lost
+		pos := 32 - r + i
+		if pos >= 0 && pos < 32 {
+			b[pos] = mBytes[1+i]
+		}
+	}
 	return b
 }
 
+// Target2Bits is the inverse of Bits2Target, rounding target down to the 3
+// bytes of precision that bits can hold. Targets above 32 bytes are capped
+func Target2Bits(target []byte) uint32 {
+	n := new(big.Int).SetBytes(target)
+	size := uint32(len(n.Bytes()))
+	var m uint32
+	if size <= 3 {
+		m = uint32(n.Uint64()) << (8 * (3 - size))
+	} else {
+		m = uint32(new(big.Int).Rsh(n, uint(8*(size-3))).Uint64())
+	}
+	if m&0x00800000 != 0 { // the top bit of m would make it negative
+		m >>= 8
+		size++
+	}
+	if size > 32 {
+		return 0x207fffff
+	}
+	return size<<24 | m
+}
+
+// MeetsTarget reports whether hash, as returned by Block.Hash, is no greater than target
+func MeetsTarget(hash, target []byte) bool {
+	return len(hash) == 32 && len(target) == 32 && bytes.Compare(hash, target) <= 0
+}
+
+// Difficulty expresses target as a multiple of the difficulty 1 target, 0x1d00ffff
+func Difficulty(target []byte) float64 {
+	t := new(big.Float).SetInt(new(big.Int).SetBytes(target))
+	if t.Sign() == 0 {
+		return 0
+	}
+	one := new(big.Float).SetInt(new(big.Int).SetBytes(Bits2Target(0x1d00ffff)))
+	d, _ := new(big.Float).Quo(one, t).Float64()
+	return d
+}
+
 // ShareTarget  returns a 32 byte sequence with k leading 0's
 // and the rest of the elements 0xff as a challenge that is
 // easier than the actual target
@@ -179,6 +222,23 @@ func Skel2Merkle(cbBytes []byte, skeleton []byte) ([]byte, error) {
 	return root, nil
 }
 
+// CoinbaseMerkle computes the merkle root, in blockheader byte order, of a block
+// whose first transaction is coinbase and whose others are summarised by skeleton
+func CoinbaseMerkle(coinbase []byte, skeleton []byte) ([]byte, error) {
+	if len(skeleton)%32 != 0 {
+		return nil, errors.New("skeleton is not a sequence of hashes")
+	}
+	txid, err := DoubleSha256(coinbase)
+	if err != nil {
+		return nil, err
+	}
+	root, err := Skel2Merkle(Reverse(txid), skeleton)
+	if err != nil {
+		return nil, err
+	}
+	return Reverse(root), nil
+}
+
 // Skeleton produces just the skeleton - NO coinbase involved given list txns of hashed txns in hex
 func Skeleton(txns []string) ([]byte, error) {
 	any := "0000000000000000000000000000000000000000000000000000000000000000" // 32 bytes of 0
@@ -202,3 +262,16 @@ func (b Block) AddMerkle(mrhash []byte) error {
 func (b Block) PutNonce(num uint32) {
 	binary.LittleEndian.PutUint32(b[nonceposition:], num)
 }
+
+// Hash returns the double Sha256 of the blockheader reversed, as block
+// explorers display it and as it is compared with the target
+func (b Block) Hash() ([]byte, error) {
+	if len(b) != 80 {
+		return nil, errors.New("wrong blockheader size")
+	}
+	hash, err := DoubleSha256(b)
+	if err != nil {
+		return nil, err
+	}
+	return Reverse(hash), nil
+}
@@ -1,6 +1,7 @@
 package coin
 
 import (
+	"bytes"
 	"encoding/hex"
 	"fmt"
 	"testing"
@@ -182,6 +183,117 @@ func TestAddMrk(t *testing.T) {
 	fmt.Printf("%x\n%x\n%x\n%x\n", testBlock, testBlock[0:36], testBlock[36:37], testBlock[36:36+32])
 }
 
+func TestBlockHash(t *testing.T) {
+	// the genesis block
+	genesis, err := BlockHeader(1, "0000000000000000000000000000000000000000000000000000000000000000", 0x495fab29, 0x1d00ffff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	merkle, _ := hex.DecodeString("4a5e1e4baab89f3a32518a88c31bc87f618f76673e2cc77ab2127b7afdeda33b")
+	genesis.AddMerkle(Reverse(merkle))
+	genesis.PutNonce(2083236893)
+	hash, err := genesis.Hash()
+	if err != nil {
+		t.Error(err)
+	}
+	expected := "000000000019d6689c085ae165831e934ff763ae46a2a6c172b3f1b60a8ce26f"
+	if got := fmt.Sprintf("%x", hash); got != expected {
+		t.Errorf("\nExp: %s\nGot: %s\n", expected, got)
+	}
+	if _, err := Block(genesis[:79]).Hash(); err == nil {
+		t.Error("expected an error for a short blockheader")
+	}
+}
+
+func TestCoinbaseMerkle(t *testing.T) {
+	// the genesis block has just the coinbase
+	genesisCoinbase := "01000000010000000000000000000000000000000000000000000000000000000000000000ffffffff4d04ffff001d0104455468652054696d65732030332f4a616e2f32303039204368616e63656c6c6f72206f6e206272696e6b206f66207365636f6e64206261696c6f757420666f722062616e6b73ffffffff0100f2052a01000000434104678afdb0fe5548271967f1a67130b7105cd6a828e03909a67962e0ea1f61deb649f6bc3f4cef38c4f35504e51ec112de5c384df7ba0b8d578a4c702b6bf11d5fac00000000"
+	cb, _ := hex.DecodeString(genesisCoinbase)
+	root, err := CoinbaseMerkle(cb, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "3ba3edfd7a7b12b27ac72c3e67768f617fc81bc3888a51323a9fb8aa4b1e5e4a" // header order
+	if got := fmt.Sprintf("%x", root); got != expected {
+		t.Errorf("\nExp: %s\nGot: %s\n", expected, got)
+	}
+	// with a skeleton it agrees with Merkle over the full list
+	txid, _ := DoubleSha256(cb)
+	others := []string{
+		"91c5e9f288437262f218c60f986e8bc10fb35ab3b9f6de477ff0eb554da89dea",
+		"46685c94b82b84fa05b6a0f36de6ff46475520113d5cb8c6fb060e043a0dbc5c",
+		"ba7ed2544c78ad793ef5bb0ebe0b1c62e8eb9404691165ffcb08662d1733d7a8",
+	}
+	skel, _ := Skeleton(others)
+	full, _, _ := Merkle(fmt.Sprintf("%x", Reverse(txid)), others)
+	root, _ = CoinbaseMerkle(cb, skel)
+	if !bytes.Equal(root, Reverse(full)) {
+		t.Errorf("\nExp: %x\nGot: %x\n", Reverse(full), root)
+	}
+	if _, err := CoinbaseMerkle(cb, skel[:31]); err == nil {
+		t.Error("expected an error for a broken skeleton")
+	}
+}
+
+func TestTarget2Bits(t *testing.T) {
+	for _, bits := range []uint32{0x1d00ffff, 0x19015f53, 0x207fffff, 0x201fffff, 0x1f123456} {
+		if got := Target2Bits(Bits2Target(bits)); got != bits {
+			t.Errorf("\nExp: %x\nGot: %x\n", bits, got)
+		}
+	}
+	// precision beyond 3 bytes is lost, the top bit moves into the exponent
+	target, _ := hex.DecodeString("00000000ffffffff000000000000000000000000000000000000000000000000")
+	if got := Target2Bits(target); got != 0x1d00ffff {
+		t.Errorf("\nExp: %x\nGot: %x\n", 0x1d00ffff, got)
+	}
+	if got := Target2Bits(nil); got != 0 {
+		t.Errorf("zero target\nExp: 0\nGot: %x\n", got)
+	}
+}
+
+func TestDifficulty(t *testing.T) {
+	tests := []struct {
+		bits uint32
+		diff float64
+	}{
+		{0x1d00ffff, 1},
+		{0x1c00ffff, 256},
+		{0x1d01fffe, 0.5},
+	}
+	for _, test := range tests {
+		if got := Difficulty(Bits2Target(test.bits)); got != test.diff {
+			t.Errorf("bits %x\nExp: %v\nGot: %v\n", test.bits, test.diff, got)
+		}
+	}
+	if Difficulty(ShareTarget(32)) != 0 {
+		t.Error("a zero target has no difficulty")
+	}
+}
+
+func TestBits2Target(t *testing.T) {
+	tests := []struct {
+		bits   uint32
+		target string
+	}{
+		{0x1d00ffff, "00000000ffff0000000000000000000000000000000000000000000000000000"},
+		{0x19015f53, "00000000000000015f5300000000000000000000000000000000000000000000"},
+		{0x207fffff, "7fffff0000000000000000000000000000000000000000000000000000000000"},
+	}
+	for _, test := range tests {
+		got := fmt.Sprintf("%x", Bits2Target(test.bits))
+		if got != test.target {
+			t.Errorf("bits %x\nExp: %s\nGot: %s\n", test.bits, test.target, got)
+		}
+	}
+	hash, _ := hex.DecodeString("000000000019d6689c085ae165831e934ff763ae46a2a6c172b3f1b60a8ce26f")
+	if !MeetsTarget(hash, Bits2Target(0x1d00ffff)) {
+		t.Error("genesis hash should meet its own target")
+	}
+	if MeetsTarget(hash, Bits2Target(0x19015f53)) {
+		t.Error("genesis hash should not meet a harder target")
+	}
+}
+
 /*
 func TestGetMerkle(t *testing.T) {
 	test := tests[4] 				// only one we have complete data for
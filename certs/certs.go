@@ -0,0 +1,164 @@
+// Package certs configures TLS for the gRPC links between conductor, servers
+// and clients, and makes the private CA and certificates of a small deployment.
+// Certificates and keys are PEM files, keys are ECDSA P-256 in PKCS#8.
+package certs
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"time"
+)
+
+// Usage says what a leaf certificate is for
+type Usage int
+
+// Server certificates identify servers to conductor and clients, client
+// certificates identify a conductor to its servers
+const (
+	Server Usage = iota
+	Client
+)
+
+// NewCA makes a self-signed CA certificate and its key, valid for the given time
+func NewCA(name string, valid time.Duration) (certPEM, keyPEM []byte, err error) {
+	tmpl, err := template(name, valid)
+	if err != nil {
+		return nil, nil, err
+	}
+	tmpl.IsCA = true
+	tmpl.BasicConstraintsValid = true
+	tmpl.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageCRLSign
+	return create(tmpl, nil, nil)
+}
+
+// Issue makes a certificate for name signed by the CA, with hosts (names or
+// IP addresses) as its subject alternative names
+func Issue(caCertPEM, caKeyPEM []byte, name string, hosts []string, usage Usage, valid time.Duration) (certPEM, keyPEM []byte, err error) {
+	ca, err := tls.X509KeyPair(caCertPEM, caKeyPEM)
+	if err != nil {
+		return nil, nil, err
+	}
+	caCert, err := x509.ParseCertificate(ca.Certificate[0])
+	if err != nil {
+		return nil, nil, err
+	}
+	tmpl, err := template(name, valid)
+	if err != nil {
+		return nil, nil, err
+	}
+	tmpl.KeyUsage = x509.KeyUsageDigitalSignature
+	tmpl.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+	if usage == Client {
+		tmpl.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+	}
+	for _, h := range hosts {
+		if ip := net.ParseIP(h); ip != nil {
+			tmpl.IPAddresses = append(tmpl.IPAddresses, ip)
+		} else {
+			tmpl.DNSNames = append(tmpl.DNSNames, h)
+		}
+	}
+	return create(tmpl, caCert, ca.PrivateKey)
+}
+
+func template(name string, valid time.Duration) (*x509.Certificate, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	return &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: name, Organization: []string{"coin"}},
+		NotBefore:    now.Add(-time.Hour), // allow for clocks behind ours
+		NotAfter:     now.Add(valid),
+	}, nil
+}
+
+// create makes a new key and signs tmpl for it, self-signed if parent is nil
+func create(tmpl, parent *x509.Certificate, parentKey interface{}) (certPEM, keyPEM []byte, err error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	if parent == nil {
+		parent, parentKey = tmpl, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), nil
+}
+
+// ServerConfig serves certFile and keyFile. With a CA bundle, clients
+// presenting a certificate must have one it signed, and with requireClient
+// every client must
+func ServerConfig(certFile, keyFile, caFile string, requireClient bool) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	config := &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+	if caFile != "" {
+		if config.ClientCAs, err = pool(caFile); err != nil {
+			return nil, err
+		}
+		config.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	if requireClient {
+		if caFile == "" {
+			return nil, errors.New("client certificates need a CA bundle")
+		}
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return config, nil
+}
+
+// ClientConfig trusts the servers signed by the CA bundle, or by the system's
+// roots if caFile is empty. With certFile and keyFile it presents a
+// certificate for mutual TLS
+func ClientConfig(caFile, certFile, keyFile string) (*tls.Config, error) {
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	var err error
+	if caFile != "" {
+		if config.RootCAs, err = pool(caFile); err != nil {
+			return nil, err
+		}
+	}
+	if certFile != "" || keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+// pool reads a bundle of PEM certificates
+func pool(caFile string) (*x509.CertPool, error) {
+	data, err := ioutil.ReadFile(caFile)
+	if err != nil {
+		return nil, err
+	}
+	p := x509.NewCertPool()
+	if !p.AppendCertsFromPEM(data) {
+		return nil, errors.New("no certificates in " + caFile)
+	}
+	return p, nil
+}
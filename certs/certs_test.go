@@ -0,0 +1,103 @@
+package certs
+
+import (
+	"crypto/tls"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// write puts a certificate and key in dir as name.pem and name.key
+func write(t *testing.T, dir, name string, cert, key []byte) {
+	if err := ioutil.WriteFile(filepath.Join(dir, name+".pem"), cert, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, name+".key"), key, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// handshake connects client to server over a pipe, returning the client's
+// and the server's errors
+func handshake(server, client *tls.Config) (error, error) {
+	a, b := net.Pipe()
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- tls.Server(a, server).Handshake()
+		a.Close() // a client waiting on a refused handshake sees EOF
+	}()
+	clientErr := tls.Client(b, client).Handshake()
+	b.Close()
+	return clientErr, <-serverErr
+}
+
+func TestMutualTLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "certs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	f := func(name string) string { return filepath.Join(dir, name) }
+	const year = 365 * 24 * time.Hour
+
+	caCert, caKey, err := NewCA("coin test CA", year)
+	if err != nil {
+		t.Fatal(err)
+	}
+	write(t, dir, "ca", caCert, caKey)
+	for _, leaf := range []struct {
+		name  string
+		usage Usage
+	}{{"server", Server}, {"conductor", Client}} {
+		cert, key, err := Issue(caCert, caKey, leaf.name, []string{"localhost", "127.0.0.1"}, leaf.usage, year)
+		if err != nil {
+			t.Fatal(err)
+		}
+		write(t, dir, leaf.name, cert, key)
+	}
+	otherCert, otherKey, _ := NewCA("another CA", year)
+	cert, key, _ := Issue(otherCert, otherKey, "intruder", nil, Client, year)
+	write(t, dir, "intruder", cert, key)
+
+	mtls, err := ServerConfig(f("server.pem"), f("server.key"), f("ca.pem"), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conductor, err := ClientConfig(f("ca.pem"), f("conductor.pem"), f("conductor.key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	conductor.ServerName = "localhost"
+	if c, s := handshake(mtls, conductor); c != nil || s != nil {
+		t.Errorf("conductor: %v, server: %v", c, s)
+	}
+
+	// a miner without a certificate is only welcome if certificates are optional
+	miner, _ := ClientConfig(f("ca.pem"), "", "")
+	miner.ServerName = "127.0.0.1"
+	optional, _ := ServerConfig(f("server.pem"), f("server.key"), f("ca.pem"), false)
+	if c, s := handshake(optional, miner); c != nil || s != nil {
+		t.Errorf("miner: %v, server: %v", c, s)
+	}
+	if _, s := handshake(mtls, miner); s == nil {
+		t.Error("expected the server to require a client certificate")
+	}
+
+	// servers and clients of another CA are refused
+	intruder, _ := ClientConfig(f("ca.pem"), f("intruder.pem"), f("intruder.key"))
+	intruder.ServerName = "localhost"
+	if _, s := handshake(mtls, intruder); s == nil {
+		t.Error("expected the server to refuse a certificate from another CA")
+	}
+	stranger, _ := ClientConfig(f("intruder.pem"), "", "")
+	stranger.ServerName = "localhost"
+	if c, _ := handshake(optional, stranger); c == nil {
+		t.Error("expected a client trusting another CA to refuse the server")
+	}
+	if _, err := ServerConfig(f("server.pem"), f("server.key"), "", true); err == nil {
+		t.Error("expected required client certificates without a CA to fail")
+	}
+}
@@ -2,11 +2,19 @@ package main
 
 import (
 	"coin"
+	"coin/accounts"
+	"coin/certs"
+	"coin/metrics"
 	"flag"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"math/rand"
+	"os"
+	"os/signal"
+	"strings"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	cpb "coin/service"
@@ -15,6 +23,9 @@ import (
 
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
 )
 
 var (
@@ -26,26 +37,52 @@ var (
 	serverPort  = flag.Int("p", -1, "server port offset from 50051.") // no default, see checkMandatoryF
 	maxSleep    = flag.Int("quit", 4, "number of multiples of 5 seconds before server declared dead")
 	config      = flag.String("f", "", "config file of options")
+	metricsAddr = flag.String("metrics", "", "address for the /metrics endpoint, eg :9092")
+	grace       = flag.Duration("grace", 5*time.Second, "deadline for logging out on SIGINT/SIGTERM")
+	device      = flag.String("device", "", "name of this machine, keeps its miner id (default hostname)")
+	challenge   = flag.Bool("challenge", false, "log in with a server nonce rather than the clock")
+	useTLS      = flag.Bool("tls", false, "connect with TLS, implied by -ca")
+	caFile      = flag.String("ca", "", "CA bundle the server's certificate must be signed by (default system roots)")
+	useStream   = flag.Bool("stream", false, "take work over one MineStream rather than long-polls")
+	heartbeat   = flag.Duration("heartbeat", 10*time.Second, "interval of heartbeats, on the MineStream or between long-polls")
 	serverAlive bool
 	name        string
 )
 
+// loggedInAs is name as of the last successful login, for the heartbeats
+var loggedInAs atomic.Value
+
+var (
+	hashRate      = metrics.NewGauge("coin_client_hashes_per_second", "Hash attempts per second over the last search.")
+	worksFetched  = metrics.NewCounter("coin_client_works_fetched_total", "Work units received from the server.")
+	cancellations = metrics.NewCounter("coin_client_cancellations_total", "Cancellations received from the server.")
+	reconnects    = metrics.NewCounter("coin_client_reconnects_total", "Logins after losing the server.")
+	sharesSent    = metrics.NewCounter("coin_client_shares_total", "Shares submitted by the server's verdict.", "result")
+)
+
 // annouceWin is what causes the server to issue a cancellation
-func annouceWin(c cpb.CoinClient, nonce uint32, block []byte, winner string) bool {
-	win := &cpb.Win{Block: block, Nonce: nonce, Identity: winner}
+func annouceWin(c cpb.CoinClient, nonce uint32, block []byte, winner string, job uint64) bool {
+	win := &cpb.Win{Block: block, Nonce: nonce, Identity: winner, Job: job}
 	r, err := c.Announce(context.Background(), &cpb.AnnounceRequest{Win: win})
 	if skipF("could not announce win", err) {
 		return false
 	}
+	if !r.Ok {
+		log.Printf("win refused: %s\n", r.Reason)
+	}
 	return r.Ok
 }
 
-// getCancel makes a blocking request to the server
-func getCancel(c cpb.CoinClient, name string, stopLooking chan struct{}, endLoop chan struct{}) {
-	_, err := c.GetCancel(context.Background(), &cpb.GetCancelRequest{Name: name})
-	skipF("could not request cancellation", err) // drop through
-	stopLooking <- struct{}{}                    // stop search
-	endLoop <- struct{}{}                        // quit loop
+// getCancel makes a blocking request to the server, it returns early if ctx is cancelled
+func getCancel(ctx context.Context, c cpb.CoinClient, name string, stopLooking chan struct{}, endLoop chan struct{}) {
+	_, err := c.GetCancel(ctx, &cpb.GetCancelRequest{Name: name})
+	switch {
+	case ctx.Err() != nil: // we are leaving, not a server failure
+	case !skipF("could not request cancellation", err): // drop through on error
+		cancellations.Inc()
+	}
+	stopLooking <- struct{}{} // stop search
+	endLoop <- struct{}{}     // quit loop
 }
 
 // dice
@@ -65,18 +102,25 @@ func rolls(n int) bool {
 	return ok
 }
 
-// search tosses two dice waiting for a double 5. exit on cancel or win
-func search(work *cpb.Work, stopLooking chan struct{}) (uint32, bool) {
+// search hashes the header for each nonce until it meets the target, exit on
+// cancel or win. The dice pace the toy, a nonce wins only if its hash also
+// meets the target. Each hash meeting the share target is submitted to c
+func search(c cpb.CoinClient, work *cpb.Work, stopLooking chan struct{}) (uint32, bool) {
 	// we must combine the coinbase + rest of block here  ...
 	prepare(work)
 	// toy version
 	var theNonce uint32
 	var ok bool
+	start := time.Now()
 	tick := time.Tick(1 * time.Second)
+	defer func() { // each roll stands in for a hash
+		hashRate.Set(float64(theNonce+1) / time.Since(start).Seconds())
+	}()
 	for cn := 0; ; cn++ {
-		if rolls(*tosses) { // a win?
+		theNonce = uint32(cn)
+		tryShare(c, theNonce)
+		if rolls(*tosses) && solves(theNonce) { // a win?
 			// if cn == 6 { // debug - all fire at once
-			theNonce = uint32(cn)
 			debugF("winning! nonce: %d\n", cn)
 			ok = true
 			break
@@ -98,19 +142,24 @@ var (
 	coinbase      coin.Transaction
 	block         coin.Block
 	target, share []byte
+	job           uint64 // of the work, returned with shares
 )
 
 func prepare(work *cpb.Work) { //{Coinbase: coinbaseBytes, Block: partblock, Skel: merkSkel}
 	//work.Coinbase
 	coinbase = coin.Transaction(work.Coinbase)
 	block = coin.Block(work.Block)
-	cbhash := coin.Sha256(coinbase)                        // TODO - confirm this is not a double hash!!
-	merkleroot, err := coin.Skel2Merkle(cbhash, work.Skel) // TODO - remove error here ...
+	merkleroot, err := coin.CoinbaseMerkle(coinbase, work.Skel)
 	if err != nil {
 		log.Fatal("failed to create merkelroot")
 	}
 	block.AddMerkle(merkleroot)
 	target = coin.Bits2Target(work.Bits)
+	job = work.Job
+	share = nil // servers without shares send none
+	if work.Share != 0 {
+		share = coin.Bits2Target(work.Share)
+	}
 	/*
 		this routine should place the coinbase in the blockheader
 		compute the new merkle root hash and put in place
@@ -119,6 +168,52 @@ func prepare(work *cpb.Work) { //{Coinbase: coinbaseBytes, Block: partblock, Ske
 	*/
 }
 
+// solves hashes the prepared block with nonce, reporting whether it meets
+// the target, as the server checks a win
+func solves(nonce uint32) bool {
+	block.PutNonce(nonce)
+	hash, err := block.Hash()
+	return err == nil && coin.MeetsTarget(hash, target)
+}
+
+// tryShare hashes the prepared block with nonce and submits it if it meets
+// the share target, on the stream if we have one
+func tryShare(c cpb.CoinClient, nonce uint32) {
+	if bits := atomic.SwapUint32(&newBits, 0); bits != 0 {
+		share = coin.Bits2Target(bits)
+	}
+	if share == nil {
+		return
+	}
+	block.PutNonce(nonce)
+	hash, err := block.Hash()
+	if err != nil || !coin.MeetsTarget(hash, share) {
+		return
+	}
+	header := make([]byte, len(block))
+	copy(header, block)
+	if streamOut != nil {
+		select {
+		case streamOut <- &cpb.MinerMessage{Kind: "share", Block: header, Job: job}:
+		default: // the stream is stuck, a lost share is not worth waiting for
+			debugF("share dropped")
+		}
+		return
+	}
+	go submitShare(c, name, header, job)
+}
+
+// submitShare sends a share found by login name, a lost share is not worth a reconnection
+func submitShare(c cpb.CoinClient, name string, header []byte, job uint64) {
+	r, err := c.SubmitShare(context.Background(), &cpb.SubmitShareRequest{Name: name, Block: header, Job: job})
+	if err != nil {
+		debugF("could not submit share: %v", err)
+		return
+	}
+	sharesSent.Inc(r.Result)
+	debugF("share %s %s\n", r.Result, r.Reason)
+}
+
 // genName takes userid and key to generate
 // a login and time
 func genName(user uint32, key string) (string, string, error) {
@@ -130,11 +225,22 @@ func genName(user uint32, key string) (string, string, error) {
 	return login, time, nil //
 }
 
+// challengeName generates a login from a server nonce instead of the time
+func challengeName(c cpb.CoinClient, user uint32, key string) (string, string, error) {
+	r, err := c.Challenge(context.Background(), &cpb.ChallengeRequest{User: user})
+	if err != nil {
+		return "", "", err
+	}
+	login, err := coin.GenLogin(user, key, r.Nonce)
+	return login, r.Nonce, err
+}
+
 type jsonConfig struct {
 	User   int
 	Key    string
 	Server string
 	Port   int
+	Device string
 }
 
 func readConfig(filename string) {
@@ -152,6 +258,7 @@ func readConfig(filename string) {
 	*serverHost = config.Server
 	*serverPort = config.Port
 	*key = config.Key
+	*device = config.Device
 }
 
 func main() {
@@ -161,28 +268,53 @@ func main() {
 		readConfig(*config)
 	}
 	checkMandatoryF() // ensure enough config data
+	if *device == "" {
+		*device, _ = os.Hostname()
+	}
+	metrics.Serve(*metricsAddr)
 	address := fmt.Sprintf("%s:%d", *serverHost, 50051+*serverPort)
 	debugF("connecting to server %s", address)
-	conn, err := grpc.Dial(address, grpc.WithInsecure())
+	session := accounts.NewCredentials(accounts.SessionHeader, "") // the token arrives with Login
+	conn, err := grpc.Dial(address, transport(), grpc.WithPerRPCCredentials(session))
 	if err != nil {
 		log.Fatalf("did not connect: %v", err)
 	}
 	defer conn.Close()
 
 	c := cpb.NewCoinClient(conn)
+	if *useStream {
+		streamOut = make(chan *cpb.MinerMessage, 16)
+	} else {
+		go heartbeats(c)
+	}
 	serverAlive = true
 	countdown := 0
+	loggedIn := false // becomes true after the first successful login
+	ctx, leave := context.WithCancel(context.Background())
+	go leaveOnSignal(leave)
 	// outer OMIT
 	for {
+		if ctx.Err() != nil { // not logged in, nothing to undo
+			return
+		}
 		userID := uint32(*user)
 		n, t, err := genName(userID, *key) // use time as well as these two
 		if err != nil {
 			log.Fatalf("getname error %v\n", err)
 		}
-		log.Printf("name: %s,time: %s\n", n, t)
-		name = n
-		r, err := c.Login(context.Background(), &cpb.LoginRequest{Name: name, User: userID, Time: t}) // HL
-		if skipF("could not login", err) {                                                            // HL
+		if *challenge { // a server nonce in place of the time
+			n, t, err = challengeName(c, userID, *key)
+		}
+		var r *cpb.LoginReply
+		if err == nil {
+			log.Printf("name: %s,time: %s\n", n, t)
+			name = n
+			r, err = c.Login(context.Background(), &cpb.LoginRequest{Name: name, User: userID, Time: t, Device: *device}) // HL
+		}
+		if err != nil && strings.Contains(err.Error(), "Clock skew") {
+			log.Printf("%v - set the clock or use -challenge\n", err)
+		}
+		if skipF("could not login", err) { // HL
 			time.Sleep(5 * time.Second)
 			countdown++
 			if countdown > *maxSleep {
@@ -194,6 +326,24 @@ func main() {
 			serverAlive = true // we are back
 		}
 		log.Printf("Login successful. Assigned id: %d\n", r.Id)
+		session.Set(r.Token) // until it expires, then we fail and log in again
+		loggedInAs.Store(name)
+		if loggedIn {
+			reconnects.Inc()
+		}
+		loggedIn = true
+		if *useStream {
+			err := mineStream(ctx, c)
+			for serverGone(ctx, err, &countdown) { // it may come back with our session
+				err = mineStream(ctx, c)
+			}
+			if ctx.Err() != nil {
+				logout(c)
+				return
+			}
+			skipF("stream lost", err)
+			continue // log in again
+		}
 		// main cycle OMIT
 		for {
 			var ( // OMIT
@@ -203,31 +353,116 @@ func main() {
 				ok                   bool          // OMIT
 			) // OMIT
 			fmt.Printf("Fetching work %s ..\n", name)
-			r, err := c.GetWork(context.Background(), &cpb.GetWorkRequest{Name: name})
+			r, err := c.GetWork(ctx, &cpb.GetWorkRequest{Name: name})
+			if ctx.Err() != nil {
+				logout(c)
+				return
+			}
+			if serverGone(ctx, err, &countdown) { // it may come back with our session
+				continue
+			}
 			if skipF("could not get work", err) {
 				break
 			}
+			countdown, serverAlive = 0, true
+			worksFetched.Inc()
 			work = r.Work                        // HL
 			stopLooking = make(chan struct{}, 1) // HL
 			endLoop = make(chan struct{}, 1)     // HL
 			// look out for  cancellation
-			go getCancel(c, name, stopLooking, endLoop) // HL
+			go getCancel(ctx, c, name, stopLooking, endLoop) // HL
 			// search blocks
-			theNonce, ok = search(work, stopLooking) // HL
-			if ok {                                  // we completed search
+			theNonce, ok = search(c, work, stopLooking) // HL
+			if ok {                                     // we completed search
 				fmt.Printf("%s ... sending solution (%d) \n", name, theNonce)
-				win := annouceWin(c, theNonce, work.Block, name) // HL
-				if win {                                         // late?
+				win := annouceWin(c, theNonce, work.Block, name, work.Job) // HL
+				if win {                                                   // late?
 					fmt.Printf("== %s == FOUND -> %d\n", name, theNonce)
 				}
 			}
 			<-endLoop // wait here for cancel from server
 			fmt.Printf("-----------------------\n")
+			if ctx.Err() != nil { // search abandoned
+				logout(c)
+				return
+			}
 		}
 		// main end OMIT
 	}
 } // outerend OMIT
 
+// serverGone waits 5 seconds if err says the server cannot be reached,
+// reporting whether to try again with the same session: a server restarted
+// with its round state still knows it. It gives up after -quit waits
+func serverGone(ctx context.Context, err error, countdown *int) bool {
+	if status.Code(err) != codes.Unavailable || ctx.Err() != nil || *countdown >= *maxSleep {
+		return false
+	}
+	if serverAlive {
+		log.Println("server gone, waiting to resume the session")
+		serverAlive = false
+	}
+	*countdown++
+	select {
+	case <-time.After(5 * time.Second):
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// heartbeats keeps us alive on the server while we long-poll, the server
+// drops a miner it has not heard from for a while
+func heartbeats(c cpb.CoinClient) {
+	warned := false
+	for range time.Tick(*heartbeat) {
+		current, ok := loggedInAs.Load().(string)
+		if !ok { // not logged in yet
+			continue
+		}
+		r, err := c.Heartbeat(context.Background(), &cpb.HeartbeatRequest{Name: current})
+		if err != nil {
+			debugF("heartbeat: %v", err) // not logged in, or the server has gone
+			continue
+		}
+		if !warned && time.Duration(r.Grace)*time.Second <= *heartbeat {
+			log.Printf("the server drops miners silent for %ds, use a shorter -heartbeat\n", r.Grace)
+			warned = true
+		}
+	}
+}
+
+// leaveOnSignal calls leave on SIGINT or SIGTERM, abandoning the current search
+func leaveOnSignal(leave context.CancelFunc) {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	sig := <-sigs
+	log.Printf("%v: leaving ...\n", sig)
+	leave()
+}
+
+// logout tells the server we are going, giving up after -grace
+func logout(c cpb.CoinClient) {
+	ctx, cancel := context.WithTimeout(context.Background(), *grace)
+	defer cancel()
+	_, err := c.Logout(ctx, &cpb.LogoutRequest{Name: name})
+	if !skipF("could not logout", err) {
+		log.Printf("%s logged out\n", name)
+	}
+}
+
+// transport is plaintext unless -tls or -ca is given
+func transport() grpc.DialOption {
+	if !*useTLS && *caFile == "" {
+		return grpc.WithInsecure()
+	}
+	config, err := certs.ClientConfig(*caFile, "", "")
+	if err != nil {
+		log.Fatalf("failed to load CA bundle: %v", err)
+	}
+	return grpc.WithTransportCredentials(credentials.NewTLS(config))
+}
+
 // utilities
 
 func checkMandatoryF() {
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"coin"
+	"testing"
+
+	cpb "coin/service"
+)
+
+// testWork is work as a server gives it, on target bits
+func testWork(t *testing.T, bits uint32) *cpb.Work {
+	upper, lower, err := coin.CoinbaseTemplates(433789, 8756123, "0225c141d69b74adac8ab984a8eb9fee42c4ce79cf6cb2be166b1ddc0356b37086")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cb, err := coin.GenCoinbase(upper, lower, 433789, 5001, "0:abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	header, err := coin.BlockHeader(2, "000000000000000117c80378b8da0e33559b5997f2ad55e2f7d18ec1975b9717", 0x53058b35, int(bits))
+	if err != nil {
+		t.Fatal(err)
+	}
+	skel, err := coin.Skeleton([]string{"91c5e9f288437262f218c60f986e8bc10fb35ab3b9f6de477ff0eb554da89dea"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &cpb.Work{Coinbase: cb, Block: header, Skel: skel, Bits: bits, Job: 7}
+}
+
+func TestSearchWins(t *testing.T) {
+	saved := *tosses
+	*tosses = 0 // no dice, the hash alone decides
+	defer func() { *tosses = saved }()
+	work := testWork(t, 0x207fffff)
+	nonce, ok := search(nil, work, make(chan struct{}, 1))
+	if !ok {
+		t.Fatal("expected a win")
+	}
+	// what annouceWin sends, checked as the server does
+	header := make(coin.Block, 80)
+	copy(header, work.Block)
+	header.PutNonce(nonce)
+	root, err := coin.CoinbaseMerkle(work.Coinbase, work.Skel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(header[36:68], root) {
+		t.Error("merkle root is not of the coinbase")
+	}
+	hash, err := header.Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !coin.MeetsTarget(hash, coin.Bits2Target(work.Bits)) {
+		t.Errorf("win %x does not meet the target", hash)
+	}
+}
+
+func TestSearchNeedsTheTarget(t *testing.T) {
+	saved := *tosses
+	*tosses = 0
+	defer func() { *tosses = saved }()
+	work := testWork(t, 0x03000001) // no hash meets it
+	stop := make(chan struct{}, 1)
+	done := make(chan bool)
+	go func() {
+		_, ok := search(nil, work, stop)
+		done <- ok
+	}()
+	stop <- struct{}{}
+	if <-done {
+		t.Fatal("expected no win short of the target")
+	}
+}
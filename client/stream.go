@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sync/atomic"
+	"time"
+
+	cpb "coin/service"
+
+	"golang.org/x/net/context"
+)
+
+// streamOut queues the messages for MineStream, shares included, nil when we long-poll
+var streamOut chan *cpb.MinerMessage
+
+// newBits is a share target pushed by the server, taken up by tryShare
+var newBits uint32
+
+// mineStream mines the work pushed on a MineStream until ctx is done or the
+// stream fails
+func mineStream(ctx context.Context, c cpb.CoinClient) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream, err := c.MineStream(ctx)
+	if err != nil {
+		return err
+	}
+	go func() { // the only sender, with a heartbeat while there is nothing to say
+		tick := time.NewTicker(*heartbeat)
+		defer tick.Stop()
+		for {
+			var m *cpb.MinerMessage
+			select {
+			case m = <-streamOut:
+			case <-tick.C:
+				m = &cpb.MinerMessage{Kind: "heartbeat"}
+			case <-ctx.Done():
+				return
+			}
+			if stream.Send(m) != nil {
+				return // Recv below fails too
+			}
+		}
+	}()
+	var stopLooking, searched chan struct{}
+	stopSearch := func() {
+		if stopLooking != nil {
+			stopLooking <- struct{}{}
+			<-searched // prepare must not run under a live search
+			stopLooking = nil
+		}
+	}
+	defer stopSearch()
+	for {
+		m, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+		switch m.Kind {
+		case "work":
+			stopSearch()
+			worksFetched.Inc()
+			fmt.Printf("Work %d for %s ..\n", m.Work.Job, name)
+			stopLooking, searched = make(chan struct{}, 1), make(chan struct{})
+			go streamSearch(m.Work, stopLooking, searched)
+		case "cancel":
+			cancellations.Inc()
+			stopSearch()
+			fmt.Printf("-----------------------\n")
+		case "share":
+			sharesSent.Inc(m.Share.Result)
+			debugF("share %s %s\n", m.Share.Result, m.Share.Reason)
+		case "win":
+			if m.Win.Ok {
+				fmt.Printf("== %s == FOUND\n", name)
+			} else {
+				log.Printf("win refused: %s\n", m.Win.Reason)
+			}
+		case "difficulty":
+			atomic.StoreUint32(&newBits, m.Bits)
+			debugF("share target now %x\n", m.Bits)
+		}
+	}
+}
+
+// streamSearch searches work until stopped, sending a win on the stream
+func streamSearch(work *cpb.Work, stopLooking, searched chan struct{}) {
+	defer close(searched)
+	theNonce, ok := search(nil, work, stopLooking)
+	if !ok {
+		return
+	}
+	fmt.Printf("%s ... sending solution (%d) \n", name, theNonce)
+	win := &cpb.Win{Block: work.Block, Nonce: theNonce, Identity: name, Job: work.Job}
+	select {
+	case streamOut <- &cpb.MinerMessage{Kind: "win", Win: win}:
+	case <-stopLooking: // the stream has gone
+		return
+	}
+	<-stopLooking // the cancellation follows the verdict
+}
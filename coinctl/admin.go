@@ -0,0 +1,307 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"coin"
+	"coin/accounts"
+	"coin/certs"
+	cpb "coin/service"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// adminFlags are the flags of the commands that call the Admin service of a
+// server, or of the conductor
+type adminFlags struct {
+	addr, key, ca *string
+	tls           *bool
+}
+
+// newAdminFlags adds the flags, the address as -name
+func newAdminFlags(fs *flag.FlagSet, name, addr, usage string) adminFlags {
+	return adminFlags{
+		addr: fs.String(name, addr, usage),
+		key:  fs.String("akey", os.Getenv("COIN_ADMIN_KEY"), "the -akey of the "+name+", $COIN_ADMIN_KEY by default"),
+		ca:   fs.String("ca", "", "CA bundle the "+name+"'s certificate is checked against, implies -tls"),
+		tls:  fs.Bool("tls", false, "dial the "+name+" with TLS, checked against the system roots unless -ca is given"),
+	}
+}
+
+func newServerFlags(fs *flag.FlagSet) adminFlags {
+	return newAdminFlags(fs, "server", "localhost:50051", "server to administer, host:port")
+}
+
+// dial connects to the Admin service at the address
+func (f adminFlags) dial() *grpc.ClientConn {
+	if *f.key == "" {
+		fatalF("no admin key", fmt.Errorf("use -akey or $COIN_ADMIN_KEY"))
+	}
+	opt := grpc.WithInsecure()
+	if *f.tls || *f.ca != "" {
+		config, err := certs.ClientConfig(*f.ca, "", "")
+		fatalF("failed to load CA bundle", err)
+		opt = grpc.WithTransportCredentials(credentials.NewTLS(config))
+	}
+	conn, err := grpc.Dial(*f.addr, opt, grpc.WithPerRPCCredentials(accounts.NewCredentials(accounts.AdminHeader, *f.key)))
+	fatalF("failed to dial "+*f.addr, err)
+	return conn
+}
+
+func callContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), 10*time.Second)
+}
+
+// banUsers lists, imposes and lifts bans on users, user:ID, and addresses,
+// ip:ADDR. A ban on a miner, miner:NAME, bans its user
+func banUsers(args []string) {
+	fs := flag.NewFlagSet("bans", flag.ExitOnError)
+	af := newServerFlags(fs)
+	fs.Parse(args)
+	args = fs.Args()
+	if len(args) == 0 || (args[0] != "list" && len(args) < 2) {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	conn := af.dial()
+	defer conn.Close()
+	c := cpb.NewAdminClient(conn)
+	ctx, cancel := callContext()
+	defer cancel()
+
+	switch args[0] {
+	case "list":
+		r, err := c.Bans(ctx, &cpb.BansRequest{})
+		fatalF("failed to list bans", err)
+		for _, b := range r.Bans {
+			fmt.Printf("%-24s  until %s  %s\n", b.Key, time.Unix(b.Until, 0).Format("2006-01-02 15:04:05"), b.Reason)
+		}
+	case "ban":
+		req := &cpb.BanRequest{Key: args[1]}
+		if len(args) > 2 {
+			d, err := time.ParseDuration(args[2])
+			fatalF("bad duration", err)
+			req.Seconds = int64(d / time.Second)
+		}
+		if len(args) > 3 {
+			req.Reason = strings.Join(args[3:], " ")
+		}
+		r, err := c.Ban(ctx, req)
+		fatalF("failed to ban "+args[1], err)
+		fmt.Printf("%s banned until %s\n", args[1], time.Unix(r.Until, 0).Format("2006-01-02 15:04:05"))
+	case "unban":
+		r, err := c.Unban(ctx, &cpb.BanRequest{Key: args[1]})
+		fatalF("failed to unban "+args[1], err)
+		if !r.Ok {
+			fmt.Printf("%s was not banned\n", args[1])
+		}
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
+
+// listMiners lists the miners logged in to a server, those of USER if given
+func listMiners(args []string) {
+	fs := flag.NewFlagSet("miners", flag.ExitOnError)
+	af := newServerFlags(fs)
+	fs.Parse(args)
+	req := &cpb.MinersRequest{}
+	if fs.NArg() > 0 {
+		id, err := strconv.ParseUint(fs.Arg(0), 10, 32)
+		fatalF("bad user id", err)
+		req.User = uint32(id)
+	}
+	conn := af.dial()
+	defer conn.Close()
+	ctx, cancel := callContext()
+	defer cancel()
+	r, err := cpb.NewAdminClient(conn).Miners(ctx, req)
+	fatalF("failed to list miners", err)
+	now := time.Now()
+	for _, m := range r.Miners {
+		seen := "stratum"
+		if m.Seen != 0 {
+			seen = now.Sub(time.Unix(m.Seen, 0)).Truncate(time.Second).String() + " ago"
+		}
+		t := m.Tally
+		fmt.Printf("%-24s  user %-6d  id %-6d  extranonce %-6x  %-10s  shares %d accepted, %d stale, %d duplicate, %d invalid\n",
+			m.Name, m.User, m.Id, m.Extranonce, seen, t.Accepted, t.Stale, t.Duplicate, t.Invalid)
+	}
+}
+
+// showRound reports the round of a server
+func showRound(args []string) {
+	fs := flag.NewFlagSet("round", flag.ExitOnError)
+	af := newServerFlags(fs)
+	fs.Parse(args)
+	conn := af.dial()
+	defer conn.Close()
+	ctx, cancel := callContext()
+	defer cancel()
+	r, err := cpb.NewAdminClient(conn).Round(ctx, &cpb.RoundRequest{})
+	fatalF("failed to read the round", err)
+	printRound(r)
+	fmt.Printf("job %d, %d miners\n", r.Job, r.Miners)
+}
+
+func printRound(r *cpb.RoundReply) {
+	if r.Started == 0 {
+		fmt.Println("no block issued yet")
+		return
+	}
+	state := "over"
+	if r.Racing {
+		state = "racing"
+	}
+	fmt.Printf("height %d on %s, bits %08x, epoch %d, issued %s, %s\n",
+		r.Height, r.Prev, r.Bits, r.Epoch, time.Unix(r.Started, 0).Format("2006-01-02 15:04:05"), state)
+}
+
+// drain stops a server as SIGTERM would
+func drain(args []string) {
+	fs := flag.NewFlagSet("drain", flag.ExitOnError)
+	af := newServerFlags(fs)
+	fs.Parse(args)
+	conn := af.dial()
+	defer conn.Close()
+	ctx, cancel := callContext()
+	defer cancel()
+	_, err := cpb.NewAdminClient(conn).Drain(ctx, &cpb.DrainRequest{})
+	fatalF("failed to drain "+*af.addr, err)
+	fmt.Printf("%s draining\n", *af.addr)
+}
+
+// conduct lists the conductor's servers, reports its round and forces a
+// new template
+func conduct(args []string) {
+	fs := flag.NewFlagSet("conductor", flag.ExitOnError)
+	af := newAdminFlags(fs, "conductor", "localhost:50050", "the conductor's -admin address, host:port")
+	fs.Parse(args)
+	args = fs.Args()
+	if len(args) != 1 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	conn := af.dial()
+	defer conn.Close()
+	c := cpb.NewConductorAdminClient(conn)
+	ctx, cancel := callContext()
+	defer cancel()
+
+	switch args[0] {
+	case "servers":
+		r, err := c.Servers(ctx, &cpb.ServersRequest{})
+		fatalF("failed to list servers", err)
+		for _, s := range r.Servers {
+			fmt.Printf("%-24s  %s\n", s.Address, s.Status)
+		}
+	case "round":
+		r, err := c.Round(ctx, &cpb.RoundRequest{})
+		fatalF("failed to read the round", err)
+		printRound(r)
+	case "template":
+		r, err := c.NewTemplate(ctx, &cpb.TemplateRequest{})
+		fatalF("failed to force a template", err)
+		if r.Ok {
+			fmt.Println("round ended, a new template follows")
+		} else {
+			fmt.Println("no round in progress, the next has a new template")
+		}
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
+
+// genLogin prints the login of USER with KEY at TIME, hex unix seconds or a
+// challenge nonce, by default now
+func genLogin(args []string) {
+	if len(args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	id, err := strconv.ParseUint(args[0], 10, 32)
+	fatalF("bad user id", err)
+	t := fmt.Sprintf("%x", uint32(time.Now().Unix()))
+	if len(args) > 2 {
+		t = args[2]
+	}
+	login, err := coin.GenLogin(uint32(id), args[1], t)
+	fatalF("failed to generate login", err)
+	fmt.Printf("login %s time %s\n", login, t)
+}
+
+// remoteStore is the user store of a server, over its Admin service
+type remoteStore struct {
+	c    cpb.AdminClient
+	conn *grpc.ClientConn
+}
+
+func (s remoteStore) Key(id uint32) (string, error) {
+	return "", errors.New("keys are not read over the admin service")
+}
+
+func (s remoteStore) Create(id uint32, key string) (accounts.User, error) {
+	ctx, cancel := callContext()
+	defer cancel()
+	r, err := s.c.AddUser(ctx, &cpb.UserRequest{Id: id, Key: key})
+	if err != nil {
+		return accounts.User{}, err
+	}
+	return userOf(r.User), nil
+}
+
+func (s remoteStore) Disable(id uint32) error {
+	ctx, cancel := callContext()
+	defer cancel()
+	_, err := s.c.DisableUser(ctx, &cpb.UserRequest{Id: id})
+	return err
+}
+
+func (s remoteStore) Enable(id uint32) error {
+	ctx, cancel := callContext()
+	defer cancel()
+	_, err := s.c.EnableUser(ctx, &cpb.UserRequest{Id: id})
+	return err
+}
+
+func (s remoteStore) Rotate(id uint32) (accounts.User, error) {
+	ctx, cancel := callContext()
+	defer cancel()
+	r, err := s.c.RotateKey(ctx, &cpb.UserRequest{Id: id})
+	if err != nil {
+		return accounts.User{}, err
+	}
+	return userOf(r.User), nil
+}
+
+func (s remoteStore) List() ([]accounts.User, error) {
+	ctx, cancel := callContext()
+	defer cancel()
+	r, err := s.c.Users(ctx, &cpb.UsersRequest{})
+	if err != nil {
+		return nil, err
+	}
+	list := make([]accounts.User, len(r.Users))
+	for i, u := range r.Users {
+		list[i] = userOf(u)
+	}
+	return list, nil
+}
+
+func (s remoteStore) Close() error {
+	return s.conn.Close()
+}
+
+func userOf(u *cpb.User) accounts.User {
+	return accounts.User{ID: u.Id, Key: u.Key, Disabled: u.Disabled, Created: time.Unix(u.Created, 0), Rotated: time.Unix(u.Rotated, 0)}
+}
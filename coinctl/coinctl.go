@@ -0,0 +1,201 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"coin/accounts"
+	"coin/certs"
+	cpb "coin/service"
+)
+
+const usage = `coinctl administers a coin deployment
+
+	coinctl users [-store users.json | -server HOST:PORT -akey KEY] list
+	coinctl users [-store users.json | -server HOST:PORT -akey KEY] add ID [KEY]
+	coinctl users [-store users.json | -server HOST:PORT -akey KEY] disable|enable|rotate ID
+	coinctl certs [-dir .] [-days 365] ca [NAME]
+	coinctl certs [-dir .] [-days 365] server NAME HOST[,HOST...]
+	coinctl certs [-dir .] [-days 365] client NAME
+	coinctl bans [-server localhost:50051] [-akey KEY] [-ca ca.pem] list
+	coinctl bans [-server localhost:50051] [-akey KEY] [-ca ca.pem] ban user:ID|ip:ADDR|miner:NAME [DURATION [REASON]]
+	coinctl bans [-server localhost:50051] [-akey KEY] [-ca ca.pem] unban user:ID|ip:ADDR
+	coinctl miners [-server localhost:50051] [-akey KEY] [-ca ca.pem] [USER]
+	coinctl round [-server localhost:50051] [-akey KEY] [-ca ca.pem]
+	coinctl drain [-server localhost:50051] [-akey KEY] [-ca ca.pem]
+	coinctl conductor [-conductor localhost:50050] [-akey KEY] [-ca ca.pem] servers|round|template
+	coinctl genlogin USER KEY [TIME]
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	switch os.Args[1] {
+	case "users":
+		users(os.Args[2:])
+	case "certs":
+		makeCerts(os.Args[2:])
+	case "bans":
+		banUsers(os.Args[2:])
+	case "miners":
+		listMiners(os.Args[2:])
+	case "round":
+		showRound(os.Args[2:])
+	case "drain":
+		drain(os.Args[2:])
+	case "conductor":
+		conduct(os.Args[2:])
+	case "genlogin":
+		genLogin(os.Args[2:])
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
+
+// users manages the user store shared by the servers, directly or through
+// a server's Admin service
+func users(args []string) {
+	fs := flag.NewFlagSet("users", flag.ExitOnError)
+	path := fs.String("store", "users.json", "user store, a JSON file or sql:driver:dsn")
+	af := newAdminFlags(fs, "server", "", "server whose store to manage, host:port, in place of -store")
+	fs.Parse(args)
+	args = fs.Args()
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	var store accounts.UserStore
+	if *af.addr != "" {
+		conn := af.dial()
+		store = remoteStore{cpb.NewAdminClient(conn), conn}
+	} else {
+		var err error
+		store, err = accounts.Open(*path)
+		fatalF("failed to open user store", err)
+	}
+	defer store.Close()
+
+	if args[0] == "list" {
+		list, err := store.List()
+		fatalF("failed to list users", err)
+		for _, u := range list {
+			state := "enabled"
+			if u.Disabled {
+				state = "disabled"
+			}
+			fmt.Printf("%6d  %-8s  key rotated %s\n", u.ID, state, u.Rotated.Format("2006-01-02 15:04"))
+		}
+		return
+	}
+	if len(args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	id64, err := strconv.ParseUint(args[1], 10, 32)
+	fatalF("bad user id", err)
+	id := uint32(id64)
+	switch args[0] {
+	case "add":
+		key := ""
+		if len(args) > 2 {
+			key = args[2]
+		}
+		u, err := store.Create(id, key)
+		fatalF("failed to add user", err)
+		fmt.Printf("user %d key %s\n", u.ID, u.Key)
+	case "disable":
+		fatalF("failed to disable user", store.Disable(id))
+	case "enable":
+		fatalF("failed to enable user", store.Enable(id))
+	case "rotate":
+		u, err := store.Rotate(id)
+		fatalF("failed to rotate key", err)
+		fmt.Printf("user %d key %s\n", u.ID, u.Key)
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
+
+// makeCerts makes a private CA, ca.pem and ca.key, and the certificates it
+// signs, NAME.pem and NAME.key: one for each server, with the hosts it is
+// dialed by, and a client certificate for the conductor
+func makeCerts(args []string) {
+	fs := flag.NewFlagSet("certs", flag.ExitOnError)
+	dir := fs.String("dir", ".", "directory of the CA and the certificates")
+	days := fs.Int("days", 365, "days the certificates are valid")
+	fs.Parse(args)
+	args = fs.Args()
+	valid := time.Duration(*days) * 24 * time.Hour
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	if args[0] == "ca" {
+		name := "coin CA"
+		if len(args) > 1 {
+			name = args[1]
+		}
+		cert, key, err := certs.NewCA(name, valid)
+		fatalF("failed to make CA", err)
+		writePair(*dir, "ca", cert, key)
+		return
+	}
+	if len(args) < 2 || (args[0] == "server") != (len(args) == 3) {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	caCert, err := ioutil.ReadFile(filepath.Join(*dir, "ca.pem"))
+	fatalF("failed to read CA, make one with coinctl certs ca", err)
+	caKey, err := ioutil.ReadFile(filepath.Join(*dir, "ca.key"))
+	fatalF("failed to read CA key", err)
+	var cert, key []byte
+	switch args[0] {
+	case "server":
+		cert, key, err = certs.Issue(caCert, caKey, args[1], strings.Split(args[2], ","), certs.Server, valid)
+	case "client":
+		cert, key, err = certs.Issue(caCert, caKey, args[1], nil, certs.Client, valid)
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	fatalF("failed to make certificate", err)
+	writePair(*dir, args[1], cert, key)
+}
+
+// writePair writes name.pem and name.key in dir, never over existing files
+func writePair(dir, name string, cert, key []byte) {
+	for _, f := range []struct {
+		ext  string
+		data []byte
+		perm os.FileMode
+	}{{".pem", cert, 0644}, {".key", key, 0600}} {
+		path := filepath.Join(dir, name+f.ext)
+		out, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, f.perm)
+		fatalF("failed to create "+path, err)
+		_, err = out.Write(f.data)
+		if err == nil {
+			err = out.Close()
+		}
+		fatalF("failed to write "+path, err)
+		fmt.Println(path)
+	}
+}
+
+// utilities
+
+func fatalF(message string, err error) {
+	if err != nil {
+		log.Fatalf(message+": %v", err)
+	}
+}
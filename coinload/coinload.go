@@ -0,0 +1,277 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+
+	"coin"
+	"coin/accounts"
+	"coin/certs"
+	cpb "coin/service"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// coinload loads a server with simulated miners. Each logs in as a device of
+// one user, then long-polls for work and cancellations as the client does,
+// with heartbeats, but never searches. With a conductor running races it
+// reports how long logins take and how far apart the miners have their
+// work and their cancellations in each race. The server must let the user
+// have that many devices, run it with -devices 0:
+//
+//	coinload -p 0 -u 1 -k KEY -n 5000 -for 2m
+
+var (
+	serverHost = flag.String("s", "localhost", "server hostname")
+	serverPort = flag.Int("p", 0, "server port offset from 50051")
+	user       = flag.Int("u", 0, "user the miners log in as, each a device of its own")
+	key        = flag.String("k", "", "key of -u")
+	miners     = flag.Int("n", 5000, "simulated miners")
+	conns      = flag.Int("conns", 50, "connections the miners share")
+	parallel   = flag.Int("parallel", 100, "logins in flight at once, past 128 the server drops the user's challenges")
+	duration   = flag.Duration("for", time.Minute, "how long to keep the miners racing")
+	heartbeat  = flag.Duration("heartbeat", 10*time.Second, "interval of each miner's heartbeats")
+	useTLS     = flag.Bool("tls", false, "connect with TLS, implied by -ca")
+	caFile     = flag.String("ca", "", "CA bundle the server's certificate must be signed by (default system roots)")
+	device     = flag.String("device", "load", "device names are this and the miner's number")
+)
+
+// race is what the miners saw of one race
+type race struct {
+	job           uint64
+	work, cancels []time.Time
+}
+
+type lockStats struct {
+	sync.Mutex
+	logins   []time.Duration
+	failures map[string]int // by what failed
+	races    map[uint64]*race
+}
+
+var stats = lockStats{failures: make(map[string]int), races: make(map[uint64]*race)}
+
+func (s *lockStats) fail(what string, err error) {
+	s.Lock()
+	s.failures[what]++
+	first := s.failures[what] == 1
+	s.Unlock()
+	if first {
+		log.Printf("%s: %v (reported once)", what, err)
+	}
+}
+
+func (s *lockStats) race(job uint64) *race {
+	r, ok := s.races[job]
+	if !ok {
+		r = &race{job: job}
+		s.races[job] = r
+	}
+	return r
+}
+
+func (s *lockStats) gotWork(job uint64, t time.Time) {
+	s.Lock()
+	defer s.Unlock()
+	r := s.race(job)
+	r.work = append(r.work, t)
+}
+
+func (s *lockStats) gotCancel(job uint64, t time.Time) {
+	s.Lock()
+	defer s.Unlock()
+	r := s.race(job)
+	r.cancels = append(r.cancels, t)
+}
+
+func main() {
+	flag.Parse()
+	if *user == 0 || *key == "" {
+		log.Fatalf("%s\n", "the miners need a user and its key, use -u and -k")
+	}
+	address := fmt.Sprintf("%s:%d", *serverHost, 50051+*serverPort)
+	clients := make([]cpb.CoinClient, *conns)
+	for i := range clients {
+		conn, err := grpc.Dial(address, transport())
+		if err != nil {
+			log.Fatalf("did not connect: %v", err)
+		}
+		defer conn.Close()
+		clients[i] = cpb.NewCoinClient(conn)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *duration)
+	defer cancel()
+	go func() {
+		sigs := make(chan os.Signal, 1)
+		signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+		<-sigs
+		cancel()
+	}()
+
+	fmt.Printf("%d miners on %s over %d connections\n", *miners, address, *conns)
+	start := time.Now()
+	var loggedIn, done sync.WaitGroup
+	slots := make(chan struct{}, *parallel)
+	for i := 0; i < *miners; i++ {
+		loggedIn.Add(1)
+		done.Add(1)
+		go func(i int) {
+			defer done.Done()
+			mine(ctx, clients[i%len(clients)], i, slots, &loggedIn)
+		}(i)
+	}
+	loggedIn.Wait()
+	stats.Lock()
+	n := len(stats.logins)
+	stats.Unlock()
+	fmt.Printf("%d of %d miners logged in in %v\n", n, *miners, time.Since(start).Truncate(time.Millisecond))
+	done.Wait()
+	report()
+}
+
+// mine is simulated miner i: it logs in, then takes part in every race
+// until ctx is done, and logs out
+func mine(ctx context.Context, c cpb.CoinClient, i int, slots chan struct{}, loggedIn *sync.WaitGroup) {
+	slots <- struct{}{}
+	name, creds, err := login(c, i)
+	<-slots
+	loggedIn.Done()
+	if err != nil {
+		return
+	}
+	go func() {
+		tick := time.NewTicker(*heartbeat)
+		defer tick.Stop()
+		for {
+			select {
+			case <-tick.C:
+				if _, err := c.Heartbeat(ctx, &cpb.HeartbeatRequest{Name: name}, creds); err != nil && ctx.Err() == nil {
+					stats.fail("heartbeat", err)
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	for ctx.Err() == nil {
+		r, err := c.GetWork(ctx, &cpb.GetWorkRequest{Name: name}, creds)
+		if err != nil {
+			if ctx.Err() == nil {
+				stats.fail("work", err)
+			}
+			break
+		}
+		stats.gotWork(r.Work.Job, time.Now())
+		if _, err := c.GetCancel(ctx, &cpb.GetCancelRequest{Name: name}, creds); err != nil {
+			if ctx.Err() == nil {
+				stats.fail("cancel", err)
+			}
+			break
+		}
+		stats.gotCancel(r.Work.Job, time.Now())
+	}
+	bye, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := c.Logout(bye, &cpb.LogoutRequest{Name: name}, creds); err != nil {
+		stats.fail("logout", err)
+	}
+}
+
+// login logs in miner i with a challenge, the miners share a user and may
+// well share the second
+func login(c cpb.CoinClient, i int) (string, grpc.CallOption, error) {
+	start := time.Now()
+	uid := uint32(*user)
+	ch, err := c.Challenge(context.Background(), &cpb.ChallengeRequest{User: uid})
+	if err != nil {
+		stats.fail("challenge", err)
+		return "", nil, err
+	}
+	name, err := coin.GenLogin(uid, *key, ch.Nonce)
+	if err != nil {
+		log.Fatalf("error: %v", err)
+	}
+	dev := fmt.Sprintf("%s-%d", *device, i)
+	r, err := c.Login(context.Background(), &cpb.LoginRequest{Name: name, User: uid, Time: ch.Nonce, Device: dev})
+	if err != nil {
+		stats.fail("login", err)
+		return "", nil, err
+	}
+	stats.Lock()
+	stats.logins = append(stats.logins, time.Since(start))
+	stats.Unlock()
+	return name, grpc.PerRPCCredentials(accounts.NewCredentials(accounts.SessionHeader, r.Token)), nil
+}
+
+// report prints the login times, and for each race how many miners had
+// work and a cancellation and how long after the first of them
+func report() {
+	stats.Lock()
+	defer stats.Unlock()
+	if len(stats.logins) > 0 {
+		p50, p99, max := percentiles(stats.logins)
+		fmt.Printf("login       p50 %-10v p99 %-10v max %v\n", p50, p99, max)
+	}
+	var races []*race
+	for _, r := range stats.races {
+		races = append(races, r)
+	}
+	sort.Slice(races, func(i, j int) bool { return races[i].job < races[j].job })
+	for _, r := range races {
+		fmt.Printf("job %x: work to %d miners, %s\n", r.job, len(r.work), spread(r.work))
+		fmt.Printf("job %x: cancel to %d miners, %s\n", r.job, len(r.cancels), spread(r.cancels))
+	}
+	for what, n := range stats.failures {
+		fmt.Printf("%d failed %s\n", n, what)
+	}
+}
+
+// spread describes how long after the first of times the rest came
+func spread(times []time.Time) string {
+	if len(times) == 0 {
+		return "none"
+	}
+	first := times[0]
+	for _, t := range times {
+		if t.Before(first) {
+			first = t
+		}
+	}
+	after := make([]time.Duration, len(times))
+	for i, t := range times {
+		after[i] = t.Sub(first)
+	}
+	p50, p99, max := percentiles(after)
+	return fmt.Sprintf("after the first p50 %v p99 %v max %v", p50, p99, max)
+}
+
+func percentiles(ds []time.Duration) (p50, p99, max time.Duration) {
+	sorted := append([]time.Duration{}, ds...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	at := func(p float64) time.Duration {
+		return sorted[int(p*float64(len(sorted)-1))].Truncate(time.Microsecond)
+	}
+	return at(0.5), at(0.99), sorted[len(sorted)-1].Truncate(time.Microsecond)
+}
+
+// transport is plaintext unless -tls or -ca is given
+func transport() grpc.DialOption {
+	if !*useTLS && *caFile == "" {
+		return grpc.WithInsecure()
+	}
+	config, err := certs.ClientConfig(*caFile, "", "")
+	if err != nil {
+		log.Fatalf("failed to load CA bundle: %v", err)
+	}
+	return grpc.WithTransportCredentials(credentials.NewTLS(config))
+}
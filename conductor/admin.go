@@ -0,0 +1,116 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"time"
+
+	"coin/accounts"
+	"coin/certs"
+	cpb "coin/service"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Admin ==================================================
+
+// Operators watch the conductor and force new templates with coinctl over
+// the ConductorAdmin service on -admin. Its calls carry -akey as coin-admin
+// metadata
+
+var (
+	adminAddr   = flag.String("admin", "", "address to serve the admin service on, eg localhost:50050, none if empty")
+	adminKey    = flag.String("akey", "", "admin key, coinctl must present it")
+	adminCert   = flag.String("admincert", "", "certificate file of the admin service, plaintext if empty")
+	adminTLSKey = flag.String("adminkey", "", "private key file of -admincert")
+)
+
+// newTemplate ends the round in progress for a new template, see NewTemplate
+var newTemplate = make(chan struct{})
+
+// admin implements cpb.ConductorAdminServer
+type admin struct{}
+
+// serveAdmin serves the admin service on *adminAddr, if it is set
+func serveAdmin() {
+	if *adminAddr == "" {
+		return
+	}
+	if *adminKey == "" {
+		log.Fatalf("%s\n", "The admin service needs a key. Use -akey switch")
+	}
+	lis, err := net.Listen("tcp", *adminAddr)
+	if err != nil {
+		log.Fatalf("failed to listen for admin: %v", err)
+	}
+	opts := []grpc.ServerOption{grpc.UnaryInterceptor(adminAuth)}
+	if *adminCert != "" {
+		config, err := certs.ServerConfig(*adminCert, *adminTLSKey, "", false)
+		if err != nil {
+			log.Fatalf("failed to load admin certificate: %v", err)
+		}
+		opts = append(opts, grpc.Creds(credentials.NewTLS(config)))
+	}
+	g := grpc.NewServer(opts...)
+	cpb.RegisterConductorAdminServer(g, new(admin))
+	fmt.Printf("admin on %s\n", lis.Addr())
+	go g.Serve(lis)
+}
+
+// adminAuth refuses calls without the admin key
+func adminAuth(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if keys := md.Get(accounts.AdminHeader); len(keys) == 0 || !accounts.SameKey(keys[0], *adminKey) {
+		return nil, status.Error(codes.Unauthenticated, "Bad admin key")
+	}
+	return handler(ctx, req)
+}
+
+// Servers lists the servers dialed : implements cpb.ConductorAdminServer
+func (a *admin) Servers(ctx context.Context, in *cpb.ServersRequest) (*cpb.ServersReply, error) {
+	reply := &cpb.ServersReply{}
+	serverConn.Lock()
+	defer serverConn.Unlock()
+	for _, c := range dialedServers {
+		st := "down"
+		switch serverConn.status[c] {
+		case 1:
+			st = "issued"
+		case 2:
+			st = "up"
+		}
+		reply.Servers = append(reply.Servers, &cpb.ServerStatus{Address: serverAddr[c], Status: st})
+	}
+	return reply, nil
+}
+
+// Round reports the round in progress : implements cpb.ConductorAdminServer
+func (a *admin) Round(ctx context.Context, in *cpb.RoundRequest) (*cpb.RoundReply, error) {
+	round.Lock()
+	defer round.Unlock()
+	reply := &cpb.RoundReply{Epoch: epoch, Height: round.height, Bits: round.bits, Prev: round.prevhash}
+	if !round.start.IsZero() { // rounds follow one another, the first once blocks are issued
+		reply.Started, reply.Racing = round.start.Unix(), true
+	}
+	return reply, nil
+}
+
+// NewTemplate ends the round as the network would : implements cpb.ConductorAdminServer
+func (a *admin) NewTemplate(ctx context.Context, in *cpb.TemplateRequest) (*cpb.TemplateReply, error) {
+	select {
+	case newTemplate <- struct{}{}: // the external search takes it within a second
+		fmt.Println("admin: new template")
+		return &cpb.TemplateReply{Ok: true}, nil
+	case <-time.After(5 * time.Second):
+		return &cpb.TemplateReply{Ok: false}, nil // between rounds, the next has a new template anyway
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
@@ -10,18 +10,46 @@ import (
 	"sync"
 	"time"
 
+	"coin/accounts"
+	"coin/certs"
+	"coin/ledger"
+	"coin/metrics"
 	cpb "coin/service"
 
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 )
 
 var (
 	debug         = flag.Bool("d", false, "debug mode")
 	servers       = flag.String("s", "", "Servers - list url_1:i_1,url_2:i_2, i_j=0,.. port")
 	timeOut       = flag.Int("o", 14, "timeout for EXTERNAL")
+	metricsAddr   = flag.String("metrics", "", "address for the /metrics endpoint, eg :9090")
+	ledgerFile    = flag.String("ledger", "rounds.log", "round history file, appended to")
+	query         = flag.String("history", "", "print rounds from the ledger and exit - all, last:N, height:H, server:S, miner:M")
+	export        = flag.String("export", "", "export the -history rounds (default all) as json or csv and exit")
+	payTo         = flag.String("pubkey", "0225c141d69b74adac8ab984a8eb9fee42c4ce79cf6cb2be166b1ddc0356b37086", "public key the coinbase pays, hex")
+	difficulty    = flag.Uint("bits", 0x19015f53, "template difficulty bits, eg 0x207fffff for an easy test target")
+	grace         = flag.Duration("grace", 10*time.Second, "deadline for notifying servers on SIGINT/SIGTERM")
+	leaseFile     = flag.String("lease", "", "lease file shared with standby conductors, enables leader election")
+	leaseTTL      = flag.Duration("ttl", 15*time.Second, "lease duration, a standby takes over this long after the leader dies")
+	holder        = flag.String("id", "", "name of this conductor in the lease (default host-pid)")
+	condKey       = flag.String("ckey", "", "conductor key, as given to the servers with -ckey")
+	useTLS        = flag.Bool("tls", false, "connect to the servers with TLS, implied by -ca")
+	caFile        = flag.String("ca", "", "CA bundle the servers' certificates must be signed by (default system roots)")
+	tlsCert       = flag.String("tlscert", "", "client certificate file, for servers run with -cmtls")
+	tlsKey        = flag.String("tlskey", "", "private key file of -tlscert")
 	numServers    int // count of expected servers
 	dialedServers []cpb.CoinClient
+	serverAddr    map[cpb.CoinClient]string // dialed address, used to label metrics
+)
+
+var (
+	roundsTotal = metrics.NewCounter("coin_conductor_rounds_total", "Rounds started by issuing blocks.")
+	winsTotal   = metrics.NewCounter("coin_conductor_wins_total", "Declared wins by source.", "source")
+	rpcErrors   = metrics.NewCounter("coin_conductor_rpc_errors_total", "Failed RPCs by server.", "server")
+	badClaims   = metrics.NewCounter("coin_conductor_rejected_claims_total", "Wins discarded by proof-of-work checks, by server.", "server")
 )
 
 type server struct {
@@ -32,13 +60,16 @@ type server struct {
 // Bitcoin stuff =========================================
 
 // newBlock packages the block information that becomes 'work' for each run
-func newBlock() (upper, lower, bheader, merkle []byte, blockheight, bits uint32) { // TODO - this data NOT fixed
+func newBlock() (upper, lower, bheader, merkle []byte, blockheight, bits uint32, fees uint64) { // TODO - this data NOT fixed
+	if upstream.pool != nil { // the pool's coinbase, see upstreamCoinbase
+		bheader, merkle, blockheight, bits = upstreamBlock()
+		return nil, nil, bheader, merkle, blockheight, bits, 0
+	}
 	blockHeight := uint32(433789) // should come from unix time
 	blockFees := 8756123          // satoshis
-	bits = 0x19015f53             // difficulty
-	pubkey := "0225c141d69b74adac8ab984a8eb9fee42c4ce79cf6cb2be166b1ddc0356b37086"
+	bits = uint32(*difficulty)    // difficulty
 	// conductor generates this ...
-	upper, lower, err := coin.CoinbaseTemplates(blockHeight, blockFees, pubkey)
+	upper, lower, err := coin.CoinbaseTemplates(blockHeight, blockFees, *payTo)
 	if err != nil {
 		log.Fatalf("failed to generate coinbase: %v", err)
 	}
@@ -47,7 +78,7 @@ func newBlock() (upper, lower, bheader, merkle []byte, blockheight, bits uint32)
 	// fetch the  skeleton mr
 	merkle = merkleRoot()
 	// sends upper, lower , blockHeight --> server
-	return upper, lower, bheader, merkle, blockHeight, bits
+	return upper, lower, bheader, merkle, blockHeight, bits, uint64(blockFees)
 }
 
 // blockHeader supplies the 80 byte bh template
@@ -201,6 +232,14 @@ func getResult(c cpb.CoinClient, lateWin chan struct{}) {
 	if skipServer(c, "could not request result", err) {
 		return
 	}
+	if res.Winner != nil && res.Winner.Identity == "EXTERNAL" { // an echo of a win we announced, never a claim
+		return
+	}
+	if err := checkClaim(res.Winner); err != nil { // keep racing
+		log.Printf("rejected win from %s: %v", serverAddr[c], err)
+		badClaims.Inc(serverAddr[c])
+		return
+	}
 	// try to pass to theWinner, fail when we are too late
 	select {
 	case <-lateWin: // ignore this win - this is closed
@@ -229,9 +268,9 @@ func condResult(lateWin chan struct{}) {
 	}
 }
 
-// canonical name of server at c
+// canonical name of server at c - the address we dialed
 func serverName(c cpb.CoinClient) string {
-	return fmt.Sprintf("%v", c)
+	return serverAddr[c]
 }
 
 // based on product2 (jan 10)
@@ -246,19 +285,24 @@ func issueBlocks(cancelChan chan struct{}) {
 	go condResult(lateWin) // this is how the conductor wins
 
 	// the block ....
-	u, l, blk, m, h, bts := newBlock() // next block
-
-	for _, c := range dialedServers { // RANGE DIALED
-		go func(c cpb.CoinClient, lateWin chan struct{}) {
-			_, err := c.IssueBlock(context.Background(), // HL
-				&cpb.IssueBlockRequest{
-					Upper:       u,
-					Lower:       l,
-					Block:       blk,
-					Merkle:      m,
-					Blockheight: h,
-					Bits:        bts,
-					Server:      serverName(c)})
+	u, l, blk, m, h, bts, fees := newBlock() // next block
+	roundsTotal.Inc()
+	startRound(h, blk, bts)
+
+	for i, c := range dialedServers { // RANGE DIALED
+		go func(i int, c cpb.CoinClient, lateWin chan struct{}) {
+			req := &cpb.IssueBlockRequest{
+				Upper:       u,
+				Lower:       l,
+				Block:       blk,
+				Merkle:      m,
+				Blockheight: h,
+				Bits:        bts,
+				Server:      serverName(c),
+				Epoch:       epoch,
+				Fees:        fees}
+			upstreamCoinbase(req, i)
+			_, err := c.IssueBlock(context.Background(), req) // HL
 			if skipServer(c, "could not issue block", err) {
 				blockSendDone <- struct{}{}
 				return
@@ -288,7 +332,7 @@ func issueBlocks(cancelChan chan struct{}) {
 			blockSendDone <- struct{}{}
 			go getResult(c, lateWin)
 			go getCancel(c, cancelChan) // register the conductor for cancellation
-		}(c, lateWin)
+		}(i, c, lateWin)
 	} // END  RANGE DIALED
 
 	// wait for all blockSendDone to return
@@ -322,24 +366,45 @@ var serverConn lockMap
 
 func main() {
 	flag.Parse()
+	if *query != "" || *export != "" {
+		showHistory(*ledgerFile, *query, *export)
+	}
 	myServers := checkMandatoryF()
 	numServers = len(myServers)
+	if *leaseFile != "" {
+		becomeLeader(*leaseFile) // blocks while we are a standby
+	}
 	serverConn.status = make(map[cpb.CoinClient]int)
+	serverAddr = make(map[cpb.CoinClient]string)
+	metrics.Serve(*metricsAddr)
+	var err error
+	history, err = ledger.Open(*ledgerFile)
+	if err != nil {
+		log.Fatalf("failed to open ledger: %v", err)
+	}
+	defer history.Close()
 
-	// dial them
+	// dial them, presenting our key on every call
+	key := accounts.NewCredentials(accounts.ConductorHeader, *condKey)
+	tlsOption := transport()
 	for index := 0; index < numServers; index++ {
 		addr := fmt.Sprintf("%s:%d", myServers[index].host, 50051+myServers[index].port)
-		conn, err := grpc.Dial(addr, grpc.WithInsecure()) // HL
+		conn, err := grpc.Dial(addr, tlsOption, grpc.WithPerRPCCredentials(key)) // HL
 		if err != nil {
 			log.Fatalf("fail to dial: %v", err)
 		}
 		defer conn.Close()
 		c := cpb.NewCoinClient(conn) // note that we do not login!
 		dialedServers = append(dialedServers, c)
+		serverAddr[c] = addr
 	}
 
+	connectUpstream()
+	serveAdmin()
+
 	// initialise
-	theEnd := make(chan struct{}) // required because we use go routines ... exit (never)
+	theEnd := make(chan struct{}) // required because we use go routines ... exit on signal
+	go shutdownOnSignal(theEnd)
 
 	startSearch := make(chan struct{})   // for firirng off external
 	stopSearching := make(chan struct{}) // stop external
@@ -369,6 +434,16 @@ func main() {
 				select {
 				case <-stopSearching: // the winner channel is filled elsewhere
 					carryOn = false
+				case <-upstream.newTip: // the pool has moved on to the next block
+					if carryOn {
+						localWin <- struct{}{}
+						carryOn = false
+					}
+				case <-newTemplate: // an operator's, see admin.go
+					if carryOn {
+						localWin <- struct{}{}
+						carryOn = false
+					}
 				default: // continue
 				}
 			}
@@ -392,6 +467,8 @@ func main() {
 				win = winStruct{str, res.Server} // a miner wins
 				stopSearching <- struct{}{}      // data on to external search
 			}
+			winsTotal.Inc(win.source)
+			recordRound(res)
 			for _, c := range dialedServers {
 				// if isAsleep(c) {
 				if isDead(c) {
@@ -422,6 +499,11 @@ func main() {
 			}
 			// announce
 			fmt.Println("---------------\nWinner: ", winner, "\n---------------")
+			select {
+			case <-quit: // shutting down, no more blocks
+				return
+			default:
+			}
 
 			// awaken by issuing new blocks
 			issueBlocks(replies)
@@ -481,6 +563,7 @@ func setStatus(c cpb.CoinClient, lev int) {
 func skipServer(c cpb.CoinClient, message string, err error) bool {
 	if err != nil {
 		log.Printf("SF: "+message+": %v", err)
+		rpcErrors.Inc(serverAddr[c])
 		if !isDead(c) {
 			setStatus(c, 0)
 		}
@@ -495,10 +578,29 @@ func debugF(format string, args ...interface{}) {
 	}
 }
 
+// transport is plaintext unless -tls or -ca is given, with a client
+// certificate for mutual TLS if -tlscert is
+func transport() grpc.DialOption {
+	if !*useTLS && *caFile == "" && *tlsCert == "" {
+		return grpc.WithInsecure()
+	}
+	config, err := certs.ClientConfig(*caFile, *tlsCert, *tlsKey)
+	if err != nil {
+		log.Fatalf("failed to load TLS files: %v", err)
+	}
+	return grpc.WithTransportCredentials(credentials.NewTLS(config))
+}
+
 func checkMandatoryF() []server {
 	if *servers == "" {
 		log.Fatalf("%s\n", "Conductor must set servers. Use -s switch")
 	}
+	if *condKey == "" {
+		log.Fatalf("%s\n", "Conductor must have the servers' conductor key. Use -ckey switch")
+	}
+	if *upstreamAddr != "" && *worker == "" {
+		log.Fatalf("%s\n", "Conductor must name its worker at the upstream pool. Use -worker switch")
+	}
 	var servList []server
 	cl := strings.Split(*servers, ",")
 	for _, v := range cl {
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"coin/lease"
+)
+
+// Leader election ========================================
+
+var (
+	election *lease.File // nil when running without -lease
+	epoch    uint64      // our term as leader, sent with every block issued
+)
+
+// holderName identifies this conductor in the lease
+func holderName() string {
+	if *holder != "" {
+		return *holder
+	}
+	host, _ := os.Hostname()
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}
+
+// becomeLeader waits as a standby until the lease at path is ours, then keeps
+// it renewed. A conductor that loses the lease exits: its servers already
+// refuse its blocks, so a supervisor can restart it as a standby
+func becomeLeader(path string) {
+	election = lease.New(path)
+	me := holderName()
+	standby := false
+	for {
+		l, ok, err := election.Acquire(me, *leaseTTL, time.Now())
+		switch {
+		case err != nil:
+			log.Printf("lease: %v", err)
+		case ok:
+			epoch = l.Epoch
+			fmt.Printf("LEADER: %s, epoch %d\n", me, epoch)
+			go renewLease(me)
+			return
+		case !standby:
+			fmt.Printf("STANDBY: leader is %s, epoch %d\n", l.Holder, l.Epoch)
+			standby = true
+		}
+		<-time.After(*leaseTTL / 3)
+	}
+}
+
+// renewLease holds on to the lease until it is lost or cannot be renewed in time
+func renewLease(me string) {
+	renewed := time.Now()
+	for {
+		<-time.After(*leaseTTL / 3)
+		l, ok, err := election.Acquire(me, *leaseTTL, time.Now())
+		switch {
+		case err != nil && time.Since(renewed) < *leaseTTL:
+			log.Printf("lease: could not renew: %v", err)
+		case err != nil:
+			log.Fatalf("lease: expired, could not renew: %v", err)
+		case !ok || l.Epoch != epoch:
+			log.Fatalf("lease: lost to %s, epoch %d", l.Holder, l.Epoch)
+		default:
+			renewed = time.Now()
+		}
+	}
+}
+
+// releaseLease hands over to a standby at once, rather than when the lease expires
+func releaseLease() {
+	if election == nil {
+		return
+	}
+	if err := election.Release(holderName()); err != nil {
+		log.Printf("lease: could not release: %v", err)
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"coin"
+	"coin/ledger"
+	"encoding/binary"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	cpb "coin/service"
+)
+
+// Round history ==========================================
+
+// lockRound describes the round in progress, set when blocks are issued
+type lockRound struct {
+	sync.Mutex
+	height   uint32          // template block height
+	prevhash string          // template previous block hash
+	prev     []byte          // ... as it appears in the header
+	bits     uint32          // template difficulty, the target of a win
+	nbits    uint32          // ... as it appears in the header, the pool's network bits upstream
+	start    time.Time       // when the blocks were issued
+	job      string          // the upstream pool's job, see roundJob
+	seen     map[string]bool // block hashes claimed this round
+}
+
+var (
+	round   lockRound
+	history *ledger.Ledger // the round history, opened in main
+)
+
+// startRound notes the template being raced for
+func startRound(height uint32, blk []byte, bits uint32) {
+	job := roundJob()
+	round.Lock()
+	round.height = height
+	round.prev = blk[4:36]
+	round.prevhash = fmt.Sprintf("%x", coin.Reverse(round.prev))
+	round.bits = bits
+	round.nbits = binary.LittleEndian.Uint32(blk[72:76])
+	round.start = time.Now()
+	round.job = job
+	round.seen = make(map[string]bool)
+	round.Unlock()
+}
+
+// recordRound appends the declared winner res to the ledger
+func recordRound(res *cpb.GetResultReply) {
+	round.Lock()
+	rec := ledger.Record{
+		Height:   round.height,
+		PrevHash: round.prevhash,
+		Start:    round.start,
+		End:      time.Now(),
+		Server:   "EXTERNAL",
+	}
+	job := round.job
+	round.Unlock()
+	if rec.Start.IsZero() { // the warm up round before any block was issued
+		return
+	}
+	if res.Winner.Identity != "EXTERNAL" {
+		rec.Server = res.Server
+		rec.Miner = res.Winner.Identity
+		rec.BlockHash = winHash(res.Winner)
+		rec.Submit = submitBlock(res.Server, job, res.Winner)
+	}
+	rec, err := history.Append(rec)
+	if err != nil {
+		log.Printf("could not record round: %v", err)
+		return
+	}
+	debugF("recorded round %d\n", rec.Round)
+}
+
+// winHash is the hash of the header claimed by win, empty if it is not a header
+func winHash(win *cpb.Win) string {
+	header, err := claimHeader(win)
+	if err != nil {
+		return ""
+	}
+	hash, err := header.Hash()
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%x", hash)
+}
+
+// submitBlock hands a block found on server, in the round on the pool's
+// job, to the upstream pool and reports the outcome. Without a pool it is
+// left unsubmitted, reported empty: the conductor runs no node to submit it
+// to the network
+func submitBlock(server, job string, win *cpb.Win) string {
+	if *upstreamAddr != "" {
+		return submitUpstream(server, job, win)
+	}
+	return ""
+}
+
+// showHistory prints the rounds selected by query, in format if one is given, then exits
+func showHistory(path, query, format string) {
+	records, err := ledger.Read(path)
+	if err != nil && !os.IsNotExist(err) {
+		log.Fatalf("failed to read ledger: %v", err)
+	}
+	records, err = ledger.Filter(records, query)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	if format != "" {
+		if err := ledger.Export(os.Stdout, records, format); err != nil {
+			log.Fatalf("failed to export ledger: %v", err)
+		}
+		os.Exit(0)
+	}
+	for _, r := range records {
+		winner := r.Server
+		if r.Miner != "" {
+			winner = fmt.Sprintf("%s:%s %s", r.Server, r.Miner, r.BlockHash)
+		}
+		fmt.Printf("%5d  %d  %s - %s  %s\n", r.Round, r.Height,
+			r.Start.Format("2006-01-02 15:04:05"), r.End.Format("15:04:05"), winner)
+	}
+	os.Exit(0)
+}
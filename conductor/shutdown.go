@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	cpb "coin/service"
+
+	"golang.org/x/net/context"
+)
+
+var quit = make(chan struct{}) // closed on shutdown, no more blocks are issued
+
+// shutdownOnSignal waits for SIGINT or SIGTERM, stops the issue of blocks and
+// ends the round on every live server so that their miners are cancelled,
+// then releases the lease to any standby. theEnd is closed when the servers have answered or -grace has passed
+func shutdownOnSignal(theEnd chan struct{}) {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	sig := <-sigs
+	fmt.Printf("%v: shutting down (at most %v) ...\n", sig, *grace)
+	close(quit)
+
+	ctx, cancel := context.WithTimeout(context.Background(), *grace)
+	defer cancel()
+	var wg sync.WaitGroup
+	for _, c := range dialedServers {
+		if isDead(c) {
+			continue
+		}
+		wg.Add(1)
+		go func(c cpb.CoinClient) {
+			defer wg.Done()
+			win := &cpb.Win{Block: []byte{}, Nonce: 99, Identity: "EXTERNAL"} // as for any external win
+			if _, err := c.Announce(ctx, &cpb.AnnounceRequest{Win: win}); err != nil {
+				log.Printf("could not notify %s: %v", serverAddr[c], err)
+			}
+		}(c)
+	}
+	notified := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(notified)
+	}()
+	select {
+	case <-notified:
+	case <-ctx.Done():
+		log.Printf("grace period over")
+	}
+	releaseLease()
+	close(theEnd)
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"encoding/binary"
+	"flag"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"coin"
+	"coin/metrics"
+	cpb "coin/service"
+	"coin/stratum"
+)
+
+// Upstream pool ==========================================
+
+// With -upstream the conductor mines for a Stratum v1 pool rather than the
+// network, our miners one large worker of the pool. The pool's jobs become
+// the templates issued to the servers, a win is a share that meets the
+// pool's difficulty and goes back to the pool with mining.submit, and a
+// clean job ends the round as a block found elsewhere would. Each server
+// works in its own part of the pool's extranonce2: its first byte is the
+// server's index, the rest is left to the server's miners
+
+var (
+	upstreamAddr = flag.String("upstream", "", "Stratum v1 pool to mine for instead of the network, host:port")
+	worker       = flag.String("worker", "", "worker name at the -upstream pool")
+	workerPass   = flag.String("wpass", "x", "password of -worker")
+)
+
+var upstreamShares = metrics.NewCounter("coin_conductor_upstream_shares_total", "Wins forwarded to the upstream pool by result.", "result")
+
+// lockUpstream is the pool and its jobs
+type lockUpstream struct {
+	sync.Mutex
+	pool   *stratum.Client
+	job    *stratum.Job  // the latest from the pool
+	round  *stratum.Job  // the job of the round in progress
+	time   uint32        // header time of the round, rolled forward each round
+	newTip chan struct{} // a clean job ends the round
+}
+
+var upstream = lockUpstream{newTip: make(chan struct{}, 1)}
+
+// connectUpstream logs in to the -upstream pool, if there is one, and waits
+// for its first job
+func connectUpstream() {
+	if *upstreamAddr == "" {
+		return
+	}
+	pool, err := stratum.Dial(*upstreamAddr, "coin-conductor")
+	if err != nil {
+		log.Fatalf("failed to dial the upstream pool: %v", err)
+	}
+	if pool.Extranonce2Size < 2 {
+		log.Fatalf("the upstream pool leaves %d bytes of extranonce2, at least 2 are needed", pool.Extranonce2Size)
+	}
+	if numServers > 256 {
+		log.Fatalf("%d servers, an upstream pool can take 256", numServers)
+	}
+	if err := pool.Authorize(*worker, *workerPass); err != nil {
+		log.Fatalf("the upstream pool refused worker %s: %v", *worker, err)
+	}
+	select {
+	case j := <-pool.Jobs():
+		upstream.job = j
+	case <-time.After(time.Minute):
+		log.Fatalf("no job from the upstream pool")
+	}
+	upstream.pool = pool
+	fmt.Printf("mining for %s as %s\n", *upstreamAddr, *worker)
+	go followUpstream(pool)
+}
+
+// followUpstream keeps the pool's latest job. Losing the pool is fatal, as
+// for a leader, a standby or a restart takes over
+func followUpstream(pool *stratum.Client) {
+	for j := range pool.Jobs() {
+		upstream.Lock()
+		upstream.job = j
+		upstream.Unlock()
+		debugF("upstream job %s, clean %v, difficulty %v\n", j.ID, j.Clean, j.Difficulty)
+		if j.Clean {
+			select {
+			case upstream.newTip <- struct{}{}:
+			default:
+			}
+		}
+	}
+	select {
+	case <-quit:
+	default:
+		log.Fatalf("lost the upstream pool")
+	}
+}
+
+// upstreamBlock makes the pool's latest job the template of the next round:
+// its header, with the time rolled past the last round's so that no round
+// repeats the work of another, its merkle branch as the skeleton, its height
+// and the bits of the pool's share target
+func upstreamBlock() (bheader, merkle []byte, blockheight, bits uint32) {
+	upstream.Lock()
+	defer upstream.Unlock()
+	select { // the round is on the latest job already
+	case <-upstream.newTip:
+	default:
+	}
+	j := upstream.job
+	t := uint32(time.Now().Unix())
+	if t < j.Time {
+		t = j.Time
+	}
+	if t <= upstream.time {
+		t = upstream.time + 1
+	}
+	upstream.time = t
+	upstream.round = j
+
+	bheader = make([]byte, 80)
+	binary.LittleEndian.PutUint32(bheader[0:], j.Version)
+	copy(bheader[4:36], j.Prev)
+	binary.LittleEndian.PutUint32(bheader[68:], t)
+	binary.LittleEndian.PutUint32(bheader[72:], j.Bits)
+	for _, b := range j.Branch {
+		merkle = append(merkle, b...)
+	}
+	blockheight, err := j.Height()
+	if err != nil {
+		debugF("upstream job %s: %v\n", j.ID, err)
+	}
+	return bheader, merkle, blockheight, coin.Target2Bits(stratum.Target(j.Difficulty))
+}
+
+// upstreamCoinbase gives server i its part of the round's coinbase, if the
+// round is for the pool
+func upstreamCoinbase(req *cpb.IssueBlockRequest, i int) {
+	upstream.Lock()
+	defer upstream.Unlock()
+	if upstream.pool == nil {
+		return
+	}
+	j, pool := upstream.round, upstream.pool
+	req.Coinb1 = append(append(append([]byte{}, j.Coinb1...), pool.Extranonce1...), byte(i))
+	req.Coinb2 = j.Coinb2
+	req.Extranonce = uint32(pool.Extranonce2Size - 1)
+}
+
+// roundJob is the id of the pool's job the round being started is on, see
+// upstreamBlock, empty without a pool
+func roundJob() string {
+	upstream.Lock()
+	defer upstream.Unlock()
+	if upstream.round == nil {
+		return ""
+	}
+	return upstream.round.ID
+}
+
+// submitUpstream forwards win, found on server in the round on the pool's
+// job, to the pool as a share
+func submitUpstream(server, job string, win *cpb.Win) string {
+	i := -1
+	for n, c := range dialedServers {
+		if serverName(c) == server {
+			i = n
+		}
+	}
+	upstream.Lock()
+	pool := upstream.pool
+	upstream.Unlock()
+	if pool == nil {
+		return "not submitted: no upstream pool"
+	}
+	if job == "" {
+		return "not submitted: the round has no upstream job"
+	}
+	header, err := claimHeader(win)
+	if err != nil {
+		return "not submitted: " + err.Error()
+	}
+	if i < 0 || len(win.Extranonce) != pool.Extranonce2Size-1 {
+		return fmt.Sprintf("not submitted: no extranonce of %s on %s", win.Identity, server)
+	}
+	sub := stratum.Submit{
+		Worker:      *worker,
+		JobID:       job,
+		Extranonce2: append([]byte{byte(i)}, win.Extranonce...),
+		Time:        binary.LittleEndian.Uint32(header[68:72]),
+		Nonce:       win.Nonce,
+	}
+	if err := pool.Submit(sub); err != nil {
+		upstreamShares.Inc("rejected")
+		return "rejected by the pool: " + err.Error()
+	}
+	upstreamShares.Inc("accepted")
+	return "accepted by the pool"
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"coin"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	cpb "coin/service"
+)
+
+// Proof of work ==========================================
+
+// claimHeader rebuilds the full 80 byte header claimed by win
+func claimHeader(win *cpb.Win) (coin.Block, error) {
+	if win == nil {
+		return nil, errors.New("empty claim")
+	}
+	if len(win.Block) != 80 {
+		return nil, fmt.Errorf("claim carries %d bytes, not a blockheader", len(win.Block))
+	}
+	header := make(coin.Block, 80) // never write into the message
+	copy(header, win.Block)
+	header.PutNonce(win.Nonce)
+	return header, nil
+}
+
+// checkClaim verifies that win solves the round in progress: right previous
+// block, right difficulty and a hash within target. Each block hash is only
+// accepted once per round so that simultaneous reports of the same block collapse
+func checkClaim(win *cpb.Win) error {
+	header, err := claimHeader(win)
+	if err != nil {
+		return err
+	}
+	hash, err := header.Hash()
+	if err != nil {
+		return err
+	}
+	round.Lock()
+	defer round.Unlock()
+	if !bytes.Equal(header[4:36], round.prev) {
+		return errors.New("wrong previous block")
+	}
+	if bits := binary.LittleEndian.Uint32(header[72:76]); bits != round.nbits {
+		return fmt.Errorf("wrong bits %x, round has %x", bits, round.nbits)
+	}
+	key := fmt.Sprintf("%x", hash)
+	if round.seen[key] {
+		return errors.New("duplicate of block " + key)
+	}
+	if !coin.MeetsTarget(hash, coin.Bits2Target(round.bits)) {
+		return errors.New("block " + key + " does not meet target")
+	}
+	round.seen[key] = true // only a block that passed, a bad claim does not shut out a good one
+	return nil
+}
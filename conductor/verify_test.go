@@ -0,0 +1,109 @@
+package main
+
+import (
+	"coin"
+	"encoding/binary"
+	"testing"
+
+	cpb "coin/service"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+)
+
+// testRound starts a round on the conductor's template with header bits
+// 0x207fffff, a win needing a hash within target bits, and returns the
+// template
+func testRound(bits uint32) []byte {
+	blk := blockHeader(0x207fffff)
+	startRound(433789, blk, bits)
+	return blk
+}
+
+// solve returns a claim on header whose hash meets target bits
+func solve(t *testing.T, header []byte, bits uint32) *cpb.Win {
+	target := coin.Bits2Target(bits)
+	win := &cpb.Win{Block: header, Identity: "pi"}
+	for ; win.Nonce < 1<<16; win.Nonce++ {
+		h, _ := claimHeader(win)
+		if hash, err := h.Hash(); err == nil && coin.MeetsTarget(hash, target) {
+			return win
+		}
+	}
+	t.Fatalf("no nonce meets %08x", bits)
+	return nil
+}
+
+func TestCheckClaim(t *testing.T) {
+	blk := testRound(0x207fffff)
+	win := solve(t, blk, 0x207fffff)
+	if err := checkClaim(win); err != nil {
+		t.Fatalf("a block meeting the target: %v", err)
+	}
+	if err := checkClaim(win); err == nil {
+		t.Error("expected a second report of the block to be refused")
+	}
+
+	prev := append([]byte{}, blk...)
+	prev[4] ^= 0xff
+	if err := checkClaim(solve(t, prev, 0x207fffff)); err == nil {
+		t.Error("expected a block on the wrong previous block to be refused")
+	}
+	bits := append([]byte{}, blk...)
+	binary.LittleEndian.PutUint32(bits[72:], 0x1d00ffff)
+	if err := checkClaim(solve(t, bits, 0x207fffff)); err == nil {
+		t.Error("expected a block with the wrong bits to be refused")
+	}
+
+	testRound(0x03000001) // no hash meets it
+	win = solve(t, blk, 0x207fffff)
+	if err := checkClaim(win); err == nil {
+		t.Error("expected a block over the target to be refused")
+	}
+	if len(round.seen) != 0 {
+		t.Errorf("a refused block was recorded as seen: %v", round.seen)
+	}
+}
+
+// scriptedServer is a server whose GetResult reports res
+type scriptedServer struct {
+	cpb.CoinClient // nothing else is called
+	res            *cpb.GetResultReply
+}
+
+func (s scriptedServer) GetResult(ctx context.Context, in *cpb.GetResultRequest, opts ...grpc.CallOption) (*cpb.GetResultReply, error) {
+	return s.res, nil
+}
+
+func TestGetResultKeepsRacing(t *testing.T) {
+	theWinner = make(chan *cpb.GetResultReply, 1)
+	localWin = make(chan struct{}, 1)
+	blk := testRound(0x207fffff)
+	bad := solve(t, blk, 0x207fffff)
+	bad.Block = blk[:79]
+	lateWin := make(chan struct{})
+	getResult(scriptedServer{res: &cpb.GetResultReply{Winner: bad, Server: "a"}}, lateWin)
+	select {
+	case <-lateWin:
+		t.Fatal("an invalid claim ended the round")
+	case res := <-theWinner:
+		t.Fatalf("an invalid claim won: %v", res)
+	default:
+	}
+
+	good := solve(t, blk, 0x207fffff)
+	getResult(scriptedServer{res: &cpb.GetResultReply{Winner: good, Server: "b"}}, lateWin)
+	select {
+	case res := <-theWinner:
+		if res.Server != "b" {
+			t.Errorf("winner on %s, expected b", res.Server)
+		}
+	default:
+		t.Fatal("a valid claim did not win")
+	}
+	select {
+	case <-lateWin:
+	default:
+		t.Error("the round is still open after a win")
+	}
+}
@@ -0,0 +1,158 @@
+// Package extranonce divides the extranonce of a coinbase among the sessions
+// of a server. Each session holds a prefix of the extranonce that no other
+// live session holds and rolls the bytes after it, so no two miners hash the
+// same coinbase, and the extranonce of a solution tells whose it is. A prefix
+// given up is the last to be given out again, so late work from a session
+// that has ended is not taken for another's.
+package extranonce
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Errors returned by an Allocator
+var (
+	ErrExhausted = errors.New("Extranonce partitions exhausted")
+	ErrHeld      = errors.New("Extranonce partition already held")
+)
+
+// Partition is a session's part of the extranonce space: every extranonce
+// that starts with the Len bytes of Prefix, big endian
+type Partition struct {
+	Prefix uint64
+	Len    int
+}
+
+// Bytes is the prefix
+func (p Partition) Bytes() []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, p.Prefix)
+	return b[8-p.Len:]
+}
+
+// Fill makes extranonce the first of the partition, the prefix then zeros
+func (p Partition) Fill(extranonce []byte) error {
+	if len(extranonce) < p.Len {
+		return fmt.Errorf("extranonce of %d bytes has no room for a %d byte prefix", len(extranonce), p.Len)
+	}
+	copy(extranonce, p.Bytes())
+	for i := p.Len; i < len(extranonce); i++ {
+		extranonce[i] = 0
+	}
+	return nil
+}
+
+// Contains reports whether extranonce is in the partition
+func (p Partition) Contains(extranonce []byte) bool {
+	return len(extranonce) >= p.Len && prefixOf(extranonce, p.Len) == p.Prefix
+}
+
+// prefixOf is the first n bytes of extranonce, big endian
+func prefixOf(extranonce []byte, n int) uint64 {
+	var prefix uint64
+	for _, b := range extranonce[:n] {
+		prefix = prefix<<8 | uint64(b)
+	}
+	return prefix
+}
+
+func (p Partition) String() string {
+	return fmt.Sprintf("%x..", p.Bytes())
+}
+
+// Allocator hands out the prefixes of one length. Prefix 0 is never given
+// out, it is left for work outside any session, such as the conductor's
+type Allocator struct {
+	sync.Mutex
+	size   int
+	next   uint64   // the lowest never given out
+	free   []uint64 // given up, the longest ago first
+	owners map[uint64]string
+}
+
+// New hands out prefixes of size bytes, at most 7
+func New(size int) *Allocator {
+	return &Allocator{size: size, next: 1, owners: make(map[uint64]string)}
+}
+
+// Assign gives owner a partition no other holds
+func (a *Allocator) Assign(owner string) (Partition, error) {
+	a.Lock()
+	defer a.Unlock()
+	var prefix uint64
+	for held := true; held; _, held = a.owners[prefix] { // claimed, see Claim
+		switch {
+		case a.next < 1<<(8*uint(a.size)):
+			prefix = a.next
+			a.next++
+		case len(a.free) > 0:
+			prefix = a.free[0]
+			a.free = a.free[1:]
+		default:
+			return Partition{}, ErrExhausted
+		}
+	}
+	a.owners[prefix] = owner
+	return Partition{prefix, a.size}, nil
+}
+
+// Claim gives owner p, which it held before, eg in an earlier run of the server
+func (a *Allocator) Claim(p Partition, owner string) error {
+	a.Lock()
+	defer a.Unlock()
+	if p.Len != a.size || p.Prefix == 0 || p.Prefix >= 1<<(8*uint(a.size)) {
+		return fmt.Errorf("no partition %s among those of %d bytes", p, a.size)
+	}
+	if _, ok := a.owners[p.Prefix]; ok {
+		return ErrHeld
+	}
+	for i, prefix := range a.free {
+		if prefix == p.Prefix {
+			a.free = append(a.free[:i], a.free[i+1:]...)
+			break
+		}
+	}
+	a.owners[p.Prefix] = owner
+	return nil
+}
+
+// Rename records that p is now held by owner, eg once its session has logged in
+func (a *Allocator) Rename(p Partition, owner string) {
+	a.Lock()
+	defer a.Unlock()
+	if _, ok := a.owners[p.Prefix]; ok {
+		a.owners[p.Prefix] = owner
+	}
+}
+
+// Release gives up p
+func (a *Allocator) Release(p Partition) {
+	a.Lock()
+	defer a.Unlock()
+	if _, ok := a.owners[p.Prefix]; !ok || p.Len != a.size {
+		return
+	}
+	delete(a.owners, p.Prefix)
+	a.free = append(a.free, p.Prefix)
+}
+
+// Owner returns the holder of the partition extranonce is in
+func (a *Allocator) Owner(extranonce []byte) (string, bool) {
+	if len(extranonce) < a.size {
+		return "", false
+	}
+	a.Lock()
+	defer a.Unlock()
+	owner, ok := a.owners[prefixOf(extranonce, a.size)]
+	return owner, ok
+}
+
+// Held is the number of partitions held
+func (a *Allocator) Held() int {
+	a.Lock()
+	defer a.Unlock()
+	return len(a.owners)
+}
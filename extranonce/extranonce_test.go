@@ -0,0 +1,110 @@
+package extranonce
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+func TestPartitions(t *testing.T) {
+	a := New(2)
+	rnd := rand.New(rand.NewSource(1))
+	live := make(map[string]Partition)
+
+	// thousands of sessions come and go
+	for i := 0; i < 8000; i++ {
+		owner := fmt.Sprintf("miner%d", i)
+		p, err := a.Assign(owner)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.Prefix == 0 {
+			t.Fatal("prefix 0 is never given out")
+		}
+		live[owner] = p
+		if rnd.Intn(3) == 0 { // a third leave again
+			for gone, q := range live {
+				a.Release(q)
+				delete(live, gone)
+				break
+			}
+		}
+	}
+	if a.Held() != len(live) {
+		t.Fatalf("%d partitions held, %d sessions live", a.Held(), len(live))
+	}
+
+	// no extranonce a session rolls is in another's partition, and each
+	// leads back to its session
+	prefixes := make(map[uint64]string)
+	for owner, p := range live {
+		if other, ok := prefixes[p.Prefix]; ok {
+			t.Fatalf("%s and %s share %s", owner, other, p)
+		}
+		prefixes[p.Prefix] = owner
+	}
+	for owner, p := range live {
+		en := make([]byte, 4)
+		if err := p.Fill(en); err != nil {
+			t.Fatal(err)
+		}
+		en[2], en[3] = byte(rnd.Intn(256)), byte(rnd.Intn(256))
+		if !p.Contains(en) {
+			t.Fatalf("%s: %x is not in its own partition %s", owner, en, p)
+		}
+		if got, ok := a.Owner(en); !ok || got != owner {
+			t.Fatalf("%x: expected owner %s, got %q", en, owner, got)
+		}
+		for other, q := range live {
+			if other != owner && q.Contains(en) {
+				t.Fatalf("%x of %s is also in %s's partition %s", en, owner, other, q)
+			}
+		}
+	}
+
+	// prefixes given up are given out again oldest first, once there are no new ones
+	small := New(1)
+	var held []Partition
+	for {
+		p, err := small.Assign("x")
+		if err == ErrExhausted {
+			break
+		}
+		held = append(held, p)
+	}
+	if len(held) != 255 {
+		t.Fatalf("expected 255 one byte prefixes, got %d", len(held))
+	}
+	small.Release(held[7])
+	small.Release(held[3])
+	small.Release(held[3]) // once only
+	if p, _ := small.Assign("y"); p != held[7] {
+		t.Errorf("expected %s again, got %s", held[7], p)
+	}
+	if p, _ := small.Assign("z"); p != held[3] {
+		t.Errorf("expected %s again, got %s", held[3], p)
+	}
+	if _, err := small.Assign("w"); err != ErrExhausted {
+		t.Errorf("expected ErrExhausted, got %v", err)
+	}
+
+	// partitions held in an earlier run are claimed back, and not given out again
+	restarted := New(2)
+	if err := restarted.Claim(Partition{2, 2}, "back"); err != nil {
+		t.Fatal(err)
+	}
+	if err := restarted.Claim(Partition{2, 2}, "again"); err != ErrHeld {
+		t.Errorf("expected ErrHeld, got %v", err)
+	}
+	if err := restarted.Claim(Partition{2, 1}, "short"); err == nil {
+		t.Error("expected a one byte partition to be refused")
+	}
+	for _, want := range []uint64{1, 3} {
+		if p, _ := restarted.Assign("new"); p.Prefix != want {
+			t.Errorf("expected prefix %d, got %s", want, p)
+		}
+	}
+	if err := held[0].Fill(nil); err == nil {
+		t.Error("expected no room for the prefix")
+	}
+}
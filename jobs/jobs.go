@@ -0,0 +1,148 @@
+// Package jobs keeps a window of the most recent jobs, the templates a server
+// has handed to its miners, and judges whether work on one may still be
+// submitted. Work on an older job is late but valid while the job builds on
+// the current tip, the previous block of the newest job, and no block has
+// been found on it; otherwise it is stale.
+package jobs
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrUnknown is returned for jobs never issued or no longer in the window
+var ErrUnknown = errors.New("Unknown job, too old or never issued")
+
+// StaleError refuses work on a job that can no longer make a block
+type StaleError struct {
+	ID     uint64
+	Reason string
+}
+
+func (e StaleError) Error() string {
+	return fmt.Sprintf("Stale job %d: %s", e.ID, e.Reason)
+}
+
+// Job is one template issued to the miners
+type Job struct {
+	ID     uint64
+	Issued time.Time
+	Prev   []byte      // previous block hash, as it appears in the header
+	Data   interface{} // the template, to check work on the job against
+	found  bool        // a block was found on Prev while this job was current
+	seen   map[string]bool
+}
+
+// Window holds the last size jobs, newest last
+type Window struct {
+	sync.Mutex
+	size int
+	next uint64
+	jobs []*Job
+}
+
+// NewWindow numbers its jobs from first, which should not repeat the ids of
+// an earlier run: the start time in unix seconds will do
+func NewWindow(size int, first uint64) *Window {
+	if size < 1 {
+		size = 1
+	}
+	return &Window{size: size, next: first}
+}
+
+// Add issues a job building on prev, dropping the oldest if the window is full
+func (w *Window) Add(prev []byte, data interface{}, now time.Time) *Job {
+	w.Lock()
+	defer w.Unlock()
+	j := &Job{ID: w.next, Issued: now, Prev: append([]byte(nil), prev...), Data: data, seen: make(map[string]bool)}
+	w.next++
+	w.jobs = append(w.jobs, j)
+	if len(w.jobs) > w.size {
+		w.jobs = w.jobs[len(w.jobs)-w.size:]
+	}
+	return j
+}
+
+// Resume puts back job id, issued by an earlier run, as the newest. Jobs
+// added after it are numbered on from it, if they would not be already
+func (w *Window) Resume(id uint64, prev []byte, data interface{}, issued time.Time) *Job {
+	w.Lock()
+	defer w.Unlock()
+	j := &Job{ID: id, Issued: issued, Prev: append([]byte(nil), prev...), Data: data, seen: make(map[string]bool)}
+	if w.next <= id {
+		w.next = id + 1
+	}
+	w.jobs = append(w.jobs, j)
+	if len(w.jobs) > w.size {
+		w.jobs = w.jobs[len(w.jobs)-w.size:]
+	}
+	return j
+}
+
+// Current is the newest job, nil before the first
+func (w *Window) Current() *Job {
+	w.Lock()
+	defer w.Unlock()
+	if len(w.jobs) == 0 {
+		return nil
+	}
+	return w.jobs[len(w.jobs)-1]
+}
+
+// Find returns job id if work on it may still be submitted
+func (w *Window) Find(id uint64) (*Job, error) {
+	w.Lock()
+	defer w.Unlock()
+	j := w.find(id)
+	if j == nil {
+		return nil, ErrUnknown
+	}
+	if j.found {
+		return nil, StaleError{id, "its block has been found"}
+	}
+	if tip := w.jobs[len(w.jobs)-1].Prev; !bytes.Equal(j.Prev, tip) {
+		return nil, StaleError{id, fmt.Sprintf("builds on %x, superseded", j.Prev)}
+	}
+	return j, nil
+}
+
+func (w *Window) find(id uint64) *Job {
+	for _, j := range w.jobs {
+		if j.ID == id {
+			return j
+		}
+	}
+	return nil
+}
+
+// Solved makes job id and every job before it on the same tip stale, a block
+// has been found on it. Later jobs are for the next block, even if a template
+// repeats the previous block hash
+func (w *Window) Solved(id uint64) {
+	w.Lock()
+	defer w.Unlock()
+	solved := w.find(id)
+	if solved == nil {
+		return
+	}
+	for _, j := range w.jobs {
+		if j.ID <= id && bytes.Equal(j.Prev, solved.Prev) {
+			j.found = true
+		}
+	}
+}
+
+// Once reports whether key, eg a share's hash, is new to job id and records it
+func (w *Window) Once(id uint64, key string) bool {
+	w.Lock()
+	defer w.Unlock()
+	j := w.find(id)
+	if j == nil || j.seen[key] {
+		return false
+	}
+	j.seen[key] = true
+	return true
+}
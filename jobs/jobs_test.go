@@ -0,0 +1,66 @@
+package jobs
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWindow(t *testing.T) {
+	now := time.Unix(0x57f00000, 0)
+	tip, next := []byte{1, 2, 3}, []byte{4, 5, 6}
+	w := NewWindow(3, 100)
+	if w.Current() != nil {
+		t.Fatal("expected no current job in an empty window")
+	}
+	a := w.Add(tip, "a", now)
+	b := w.Add(tip, "b", now.Add(time.Minute)) // a new template for the same tip
+	if a.ID != 100 || b.ID != 101 || w.Current() != b {
+		t.Fatalf("expected jobs 100 and 101, b current, got %d, %d", a.ID, b.ID)
+	}
+	// late but valid
+	if j, err := w.Find(a.ID); err != nil || j.Data != "a" {
+		t.Errorf("job a: %v %v", j, err)
+	}
+	if w.Once(a.ID, "x") != true || w.Once(a.ID, "x") != false || w.Once(b.ID, "x") != true {
+		t.Error("Once: each key once per job")
+	}
+
+	// a block found on the tip makes both stale, a repeat of the tip is not
+	w.Solved(b.ID)
+	c := w.Add(tip, "c", now.Add(2*time.Minute))
+	for _, id := range []uint64{a.ID, b.ID} {
+		if _, err := w.Find(id); err == nil {
+			t.Errorf("job %d: expected it to be stale", id)
+		} else if _, ok := err.(StaleError); !ok {
+			t.Errorf("job %d: expected a StaleError, got %v", id, err)
+		}
+	}
+	if _, err := w.Find(c.ID); err != nil {
+		t.Errorf("job c: %v", err)
+	}
+
+	// a new tip makes c stale, and the window drops a
+	d := w.Add(next, "d", now.Add(3*time.Minute))
+	if _, err := w.Find(c.ID); err == nil {
+		t.Error("job c: expected it to be stale on the new tip")
+	}
+	if _, err := w.Find(d.ID); err != nil {
+		t.Errorf("job d: %v", err)
+	}
+	if _, err := w.Find(a.ID); err != ErrUnknown {
+		t.Errorf("job a: expected ErrUnknown once out of the window, got %v", err)
+	}
+	if w.Once(a.ID, "y") {
+		t.Error("Once: expected false for a job out of the window")
+	}
+
+	// a restarted server takes up the job it was racing for
+	restarted := NewWindow(3, 50)
+	r := restarted.Resume(d.ID, next, "d", d.Issued)
+	if j, err := restarted.Find(d.ID); err != nil || j != r || j.Data != "d" {
+		t.Errorf("resumed job d: %v %v", j, err)
+	}
+	if e := restarted.Add(next, "e", now.Add(4*time.Minute)); e.ID != d.ID+1 {
+		t.Errorf("expected job %d after the resumed one, got %d", d.ID+1, e.ID)
+	}
+}
@@ -0,0 +1,105 @@
+// Package lease elects a single active conductor among several. The lease
+// lives in a file that every conductor can reach, guarded by flock, and
+// each change of holder increases its epoch so that servers can tell the
+// current leader from one that has been replaced.
+package lease
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"syscall"
+	"time"
+)
+
+// Lease is the content of the lease file
+type Lease struct {
+	Holder  string    `json:"holder"`
+	Epoch   uint64    `json:"epoch"`
+	Expires time.Time `json:"expires"`
+}
+
+// File is a lease stored at a path shared by the contenders
+type File struct {
+	path string
+}
+
+// New returns the lease stored at path, the file is created on first use
+func New(path string) *File {
+	return &File{path}
+}
+
+// Acquire takes or renews the lease for holder until now+ttl. A renewal
+// keeps the epoch, a takeover - only possible once the lease has expired -
+// increments it. It returns the lease as it now stands and whether holder has it
+func (f *File) Acquire(holder string, ttl time.Duration, now time.Time) (Lease, bool, error) {
+	var got bool
+	l, err := f.update(func(l *Lease) bool {
+		switch {
+		case l.Holder == holder && now.Before(l.Expires): // renew
+		case now.Before(l.Expires): // somebody else's
+			return false
+		default: // free or expired
+			l.Holder = holder
+			l.Epoch++
+		}
+		l.Expires = now.Add(ttl)
+		got = true
+		return true
+	})
+	return l, got, err
+}
+
+// Release gives up the lease if holder has it, letting a standby take over at once
+func (f *File) Release(holder string) error {
+	_, err := f.update(func(l *Lease) bool {
+		if l.Holder != holder {
+			return false
+		}
+		l.Expires = time.Time{}
+		return true
+	})
+	return err
+}
+
+// Read returns the current lease without changing it
+func (f *File) Read() (Lease, error) {
+	return f.update(func(l *Lease) bool { return false })
+}
+
+// update applies change to the lease under an exclusive lock and writes it back if change returns true
+func (f *File) update(change func(l *Lease) bool) (Lease, error) {
+	var l Lease
+	file, err := os.OpenFile(f.path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return l, err
+	}
+	defer file.Close()
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX); err != nil {
+		return l, err
+	}
+	defer syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return l, err
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &l); err != nil {
+			return l, err
+		}
+	}
+	if !change(&l) {
+		return l, nil
+	}
+	data, err = json.Marshal(l)
+	if err != nil {
+		return l, err
+	}
+	if err := file.Truncate(0); err != nil {
+		return l, err
+	}
+	if _, err := file.WriteAt(data, 0); err != nil {
+		return l, err
+	}
+	return l, file.Sync()
+}
@@ -0,0 +1,46 @@
+package lease
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAcquire(t *testing.T) {
+	f := New(filepath.Join(t.TempDir(), "conductor.lease"))
+	ttl := 10 * time.Second
+	now := time.Date(2016, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	l, ok, err := f.Acquire("a", ttl, now)
+	if err != nil || !ok || l.Epoch != 1 {
+		t.Fatalf("a should lead epoch 1: %+v %v %v", l, ok, err)
+	}
+	// b is a standby while a renews
+	if l, ok, _ = f.Acquire("b", ttl, now.Add(5*time.Second)); ok || l.Holder != "a" {
+		t.Errorf("b should not get a live lease: %+v", l)
+	}
+	if l, ok, _ = f.Acquire("a", ttl, now.Add(6*time.Second)); !ok || l.Epoch != 1 {
+		t.Errorf("renewal should keep epoch 1: %+v", l)
+	}
+	// a dies, b takes over after expiry
+	if _, ok, _ = f.Acquire("b", ttl, now.Add(15*time.Second)); ok {
+		t.Error("b took the lease before it expired")
+	}
+	if l, ok, _ = f.Acquire("b", ttl, now.Add(17*time.Second)); !ok || l.Epoch != 2 || l.Holder != "b" {
+		t.Errorf("b should lead epoch 2: %+v", l)
+	}
+	// a comes back and must not renew
+	if _, ok, _ = f.Acquire("a", ttl, now.Add(18*time.Second)); ok {
+		t.Error("a renewed a lease it had lost")
+	}
+	// b releases, a takes over at once
+	if err := f.Release("b"); err != nil {
+		t.Fatal(err)
+	}
+	if l, ok, _ = f.Acquire("a", ttl, now.Add(19*time.Second)); !ok || l.Epoch != 3 {
+		t.Errorf("a should lead epoch 3 after release: %+v", l)
+	}
+	if l, err = f.Read(); err != nil || l.Holder != "a" {
+		t.Errorf("read %+v %v", l, err)
+	}
+}
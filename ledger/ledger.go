@@ -0,0 +1,206 @@
+// Package ledger keeps the conductor's round history - an append-only file
+// with one JSON record per line. It is the audit trail for found blocks and
+// the basis for donation receipts, so records are never rewritten.
+package ledger
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Record is one round as seen by the conductor
+type Record struct {
+	Round     uint64    `json:"round"`     // sequence number in this ledger, from 1
+	Height    uint32    `json:"height"`    // template block height
+	PrevHash  string    `json:"prevhash"`  // template previous block hash (hex)
+	Start     time.Time `json:"start"`     // blocks issued
+	End       time.Time `json:"end"`       // winner declared
+	Server    string    `json:"server"`    // winning server, EXTERNAL if nobody in the pool won
+	Miner     string    `json:"miner"`     // winning miner as reported by the server
+	BlockHash string    `json:"blockhash"` // hash of the winning header (hex), empty for EXTERNAL
+	Submit    string    `json:"submit"`    // result of submitting the block, empty if it was not submitted
+}
+
+// Ledger is an open round history
+type Ledger struct {
+	sync.Mutex
+	f    *os.File
+	last uint64 // round number of the most recent record
+}
+
+// Open opens (creating if need be) the ledger at path for appending. A torn
+// final record, left by a crash mid-write, was never acknowledged and is cut off
+func Open(path string) (*Ledger, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+	records, end, err := scan(f, path)
+	if err == nil {
+		err = f.Truncate(end)
+	}
+	if err == nil {
+		_, err = f.Seek(end, io.SeekStart)
+	}
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	l := &Ledger{f: f}
+	if n := len(records); n > 0 {
+		l.last = records[n-1].Round
+	}
+	return l, nil
+}
+
+// Append numbers r, writes it and syncs it to disk before returning it
+func (l *Ledger) Append(r Record) (Record, error) {
+	l.Lock()
+	defer l.Unlock()
+	r.Round = l.last + 1
+	line, err := json.Marshal(r)
+	if err != nil {
+		return r, err
+	}
+	if _, err := l.f.Write(append(line, '\n')); err != nil {
+		return r, err
+	}
+	if err := l.f.Sync(); err != nil {
+		return r, err
+	}
+	l.last = r.Round
+	return r, nil
+}
+
+// Close closes the underlying file
+func (l *Ledger) Close() error {
+	return l.f.Close()
+}
+
+// Read returns every record in the ledger at path
+func Read(path string) ([]Record, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	records, _, err := scan(f, path)
+	return records, err
+}
+
+// scan decodes the records in r and returns the offset just past the last
+// complete one. A torn final line is ignored, a corrupt line elsewhere is an error
+func scan(r io.Reader, path string) ([]Record, int64, error) {
+	var (
+		records []Record
+		end     int64
+	)
+	reader := bufio.NewReader(r)
+	for n := 1; ; n++ {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF { // no newline: empty, or the torn tail
+			return records, end, nil
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		if text := strings.TrimSpace(string(line)); text != "" {
+			var rec Record
+			if err := json.Unmarshal([]byte(text), &rec); err != nil {
+				return nil, 0, fmt.Errorf("%s line %d: %v", path, n, err)
+			}
+			records = append(records, rec)
+		}
+		end += int64(len(line))
+	}
+}
+
+// Filter selects records by query, one of
+//	all          every record
+//	last:N       the N most recent rounds
+//	height:H     rounds at template height H
+//	server:S     rounds won by server S
+//	miner:M      rounds won by miner M
+func Filter(records []Record, query string) ([]Record, error) {
+	if query == "" || query == "all" {
+		return records, nil
+	}
+	w := strings.SplitN(query, ":", 2)
+	if len(w) != 2 {
+		return nil, fmt.Errorf("bad query %q, expected key:value", query)
+	}
+	key, value := w[0], w[1]
+	switch key {
+	case "last":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("bad query %q, expected last:N", query)
+		}
+		if n > len(records) {
+			n = len(records)
+		}
+		return records[len(records)-n:], nil
+	case "height", "server", "miner":
+	default:
+		return nil, fmt.Errorf("bad query key %q", key)
+	}
+	var selected []Record
+	for _, r := range records {
+		match := false
+		switch key {
+		case "height":
+			match = strconv.FormatUint(uint64(r.Height), 10) == value
+		case "server":
+			match = r.Server == value
+		case "miner":
+			match = r.Miner == value
+		}
+		if match {
+			selected = append(selected, r)
+		}
+	}
+	return selected, nil
+}
+
+var header = []string{"round", "height", "prevhash", "start", "end", "server", "miner", "blockhash", "submit"}
+
+// Export writes records to w as "json" (an array) or "csv" (with a header row)
+func Export(w io.Writer, records []Record, format string) error {
+	switch format {
+	case "json":
+		if records == nil {
+			records = []Record{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(records)
+	case "csv":
+		cw := csv.NewWriter(w)
+		cw.Write(header)
+		for _, r := range records {
+			cw.Write([]string{
+				strconv.FormatUint(r.Round, 10),
+				strconv.FormatUint(uint64(r.Height), 10),
+				r.PrevHash,
+				r.Start.UTC().Format(time.RFC3339),
+				r.End.UTC().Format(time.RFC3339),
+				r.Server,
+				r.Miner,
+				r.BlockHash,
+				r.Submit,
+			})
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+	return errors.New("unknown export format " + format + ", use json or csv")
+}
@@ -0,0 +1,108 @@
+package ledger
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAppendReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rounds.log")
+	l, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2016, 9, 30, 12, 0, 0, 0, time.UTC)
+	for i, server := range []string{"EXTERNAL", "server0", "server1"} {
+		r, err := l.Append(Record{Height: 433789 + uint32(i), Server: server, Start: start, End: start.Add(time.Minute)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.Round != uint64(i+1) {
+			t.Errorf("expected round %d, got %d", i+1, r.Round)
+		}
+	}
+	l.Close()
+
+	// simulate a crash mid-write
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"round":4,"height":43`)
+	f.Close()
+
+	l, err = Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := l.Append(Record{Height: 433792, Server: "server0", Miner: "0:abc"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Close()
+	if r.Round != 4 {
+		t.Errorf("expected round 4 after reopening, got %d", r.Round)
+	}
+	records, err := Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 4 || records[3].Miner != "0:abc" {
+		t.Errorf("unexpected records after torn write: %+v", records)
+	}
+}
+
+func TestFilterExport(t *testing.T) {
+	records := []Record{
+		{Round: 1, Height: 10, Server: "EXTERNAL"},
+		{Round: 2, Height: 11, Server: "server0", Miner: "0:abc", BlockHash: "00ff"},
+		{Round: 3, Height: 11, Server: "server1", Miner: "1:def"},
+	}
+	tests := []struct {
+		query  string
+		rounds []uint64
+	}{
+		{"all", []uint64{1, 2, 3}},
+		{"last:2", []uint64{2, 3}},
+		{"last:9", []uint64{1, 2, 3}},
+		{"height:11", []uint64{2, 3}},
+		{"server:server0", []uint64{2}},
+		{"miner:1:def", []uint64{3}},
+	}
+	for _, test := range tests {
+		got, err := Filter(records, test.query)
+		if err != nil {
+			t.Errorf("%s: %v", test.query, err)
+			continue
+		}
+		if len(got) != len(test.rounds) {
+			t.Errorf("%s: expected %d records, got %d", test.query, len(test.rounds), len(got))
+			continue
+		}
+		for i, r := range got {
+			if r.Round != test.rounds[i] {
+				t.Errorf("%s: expected round %d, got %d", test.query, test.rounds[i], r.Round)
+			}
+		}
+	}
+	if _, err := Filter(records, "colour:blue"); err == nil {
+		t.Error("expected an error for an unknown query key")
+	}
+
+	var buffer bytes.Buffer
+	if err := Export(&buffer, records[1:2], "csv"); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[1], "2,11,,") || !strings.Contains(lines[1], "0:abc,00ff") {
+		t.Errorf("unexpected csv:\n%s", buffer.String())
+	}
+	buffer.Reset()
+	if err := Export(&buffer, nil, "json"); err != nil || strings.TrimSpace(buffer.String()) != "[]" {
+		t.Errorf("unexpected json export of no records: %q %v", buffer.String(), err)
+	}
+}
@@ -0,0 +1,185 @@
+// Package metrics is a small Prometheus text-format encoder used by the
+// conductor, the servers and the clients to expose a /metrics endpoint
+// without pulling in the full Prometheus client library.
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// metric is the shared state of a counter or gauge family - one value per label set
+type metric struct {
+	sync.Mutex
+	name   string
+	help   string
+	kind   string             // "counter" or "gauge"
+	labels []string           // label names
+	values map[string]float64 // keyed by the encoded label values
+}
+
+var registry struct {
+	sync.Mutex
+	metrics []*metric
+}
+
+func register(name, help, kind string, labels []string) *metric {
+	m := &metric{name: name, help: help, kind: kind, labels: labels, values: make(map[string]float64)}
+	registry.Lock()
+	registry.metrics = append(registry.metrics, m)
+	registry.Unlock()
+	if len(labels) == 0 {
+		m.values[""] = 0 // unlabelled metrics are reported from the start
+	}
+	return m
+}
+
+// key encodes label values lv as the {...} part of a sample line
+func (m *metric) key(lv []string) string {
+	if len(lv) != len(m.labels) {
+		log.Panicf("metric %s: expected %d label values, got %d", m.name, len(m.labels), len(lv))
+	}
+	if len(lv) == 0 {
+		return ""
+	}
+	pairs := make([]string, len(lv))
+	for i, v := range lv {
+		pairs[i] = fmt.Sprintf("%s=%q", m.labels[i], escape(v))
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func (m *metric) add(v float64, lv []string) {
+	k := m.key(lv)
+	m.Lock()
+	m.values[k] += v
+	m.Unlock()
+}
+
+func (m *metric) set(v float64, lv []string) {
+	k := m.key(lv)
+	m.Lock()
+	m.values[k] = v
+	m.Unlock()
+}
+
+func (m *metric) get(lv []string) float64 {
+	k := m.key(lv)
+	m.Lock()
+	defer m.Unlock()
+	return m.values[k]
+}
+
+// write emits the HELP, TYPE and sample lines of m, samples sorted by label set
+func (m *metric) write(w io.Writer) error {
+	m.Lock()
+	keys := make([]string, 0, len(m.values))
+	for k := range m.values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var buffer bytes.Buffer
+	fmt.Fprintf(&buffer, "# HELP %s %s\n", m.name, m.help)
+	fmt.Fprintf(&buffer, "# TYPE %s %s\n", m.name, m.kind)
+	for _, k := range keys {
+		fmt.Fprintf(&buffer, "%s%s %v\n", m.name, k, m.values[k])
+	}
+	m.Unlock()
+	_, err := w.Write(buffer.Bytes())
+	return err
+}
+
+// escape replaces the characters %q would otherwise render in Go rather than Prometheus syntax
+func escape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r < 0x20 || r == 0x7f {
+			continue // control characters have no place in a label
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// Counter is a monotonically increasing value, optionally split by labels
+type Counter struct{ m *metric }
+
+// NewCounter registers a counter named name with the given label names
+func NewCounter(name, help string, labels ...string) *Counter {
+	return &Counter{register(name, help, "counter", labels)}
+}
+
+// Inc adds one to the counter for label values lv
+func (c *Counter) Inc(lv ...string) { c.m.add(1, lv) }
+
+// Add adds v (which must not be negative) to the counter for label values lv
+func (c *Counter) Add(v float64, lv ...string) {
+	if v < 0 {
+		log.Panicf("counter %s cannot decrease", c.m.name)
+	}
+	c.m.add(v, lv)
+}
+
+// Value returns the current count for label values lv
+func (c *Counter) Value(lv ...string) float64 { return c.m.get(lv) }
+
+// Gauge is a value that can go up and down, optionally split by labels
+type Gauge struct{ m *metric }
+
+// NewGauge registers a gauge named name with the given label names
+func NewGauge(name, help string, labels ...string) *Gauge {
+	return &Gauge{register(name, help, "gauge", labels)}
+}
+
+// Set sets the gauge for label values lv
+func (g *Gauge) Set(v float64, lv ...string) { g.m.set(v, lv) }
+
+// Add changes the gauge by v (possibly negative) for label values lv
+func (g *Gauge) Add(v float64, lv ...string) { g.m.add(v, lv) }
+
+// Value returns the current gauge value for label values lv
+func (g *Gauge) Value(lv ...string) float64 { return g.m.get(lv) }
+
+// WriteText writes every registered metric to w in the Prometheus text format
+func WriteText(w io.Writer) error {
+	registry.Lock()
+	all := make([]*metric, len(registry.metrics))
+	copy(all, registry.metrics)
+	registry.Unlock()
+	sort.Slice(all, func(i, j int) bool { return all[i].name < all[j].name })
+	for _, m := range all {
+		if err := m.write(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Handler serves the registered metrics
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		WriteText(w)
+	})
+}
+
+// Serve exposes /metrics on addr in the background, does nothing when addr is empty
+func Serve(addr string) {
+	if addr == "" {
+		return
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", Handler())
+	go func() {
+		log.Printf("metrics on %s/metrics", addr)
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			log.Printf("metrics server: %v", err)
+		}
+	}()
+}
@@ -0,0 +1,39 @@
+package metrics
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWriteText(t *testing.T) {
+	wins := NewCounter("test_wins_total", "wins by source", "source")
+	miners := NewGauge("test_miners", "miners logged in")
+	wins.Inc("EXTERNAL")
+	wins.Add(2, `server "0"`)
+	miners.Set(3)
+	miners.Add(-1)
+
+	var buffer bytes.Buffer
+	if err := WriteText(&buffer); err != nil {
+		t.Fatal(err)
+	}
+	got := buffer.String()
+	expected := []string{
+		"# HELP test_miners miners logged in\n# TYPE test_miners gauge\ntest_miners 2\n",
+		"# TYPE test_wins_total counter\n",
+		"test_wins_total{source=\"EXTERNAL\"} 1\n",
+		"test_wins_total{source=\"server \\\"0\\\"\"} 2\n",
+	}
+	for _, e := range expected {
+		if !strings.Contains(got, e) {
+			t.Errorf("missing %q in\n%s", e, got)
+		}
+	}
+	if strings.Index(got, "test_miners") > strings.Index(got, "test_wins_total") {
+		t.Errorf("metrics not sorted by name:\n%s", got)
+	}
+	if v := wins.Value("EXTERNAL"); v != 1 {
+		t.Errorf("expected 1 external win, got %v", v)
+	}
+}
@@ -0,0 +1,166 @@
+// Package minerid hands out the 3 byte miner ids written into each coinbase.
+// An id belongs to a user's device for good: it is kept on disk, so that a
+// found block can be traced back through its coinbase to whoever mined it.
+package minerid
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Max is the largest id, the coinbase has 24 bits for it. 0 is never
+// issued, it stands for the conductor
+const Max = 1<<24 - 1
+
+// Errors returned by Assign
+var (
+	ErrExhausted = errors.New("Miner ids exhausted")
+	ErrDevices   = errors.New("Too many devices for this user")
+)
+
+// Owner is the user and device an id was issued to
+type Owner struct {
+	User   uint32 `json:"user"`
+	Device string `json:"device"`
+}
+
+// reserve is how many ids Assign sets aside on disk at a time. New devices
+// are saved by Flush, those lost to a crash are never issued again
+const reserve = 256
+
+// Registry holds the ids issued so far
+type Registry struct {
+	sync.Mutex
+	path    string
+	max     uint32
+	perUser int               // devices a user may have ids for, 0 for any number
+	devices map[uint32]int    // by user
+	next    uint32            // the next id to issue
+	Next    uint32            `json:"next"` // the first id not set aside
+	IDs     map[string]uint32 `json:"ids"`  // by key
+	owners  map[uint32]Owner  // by id
+	dirty   bool              // ids issued since the last save
+}
+
+func key(user uint32, device string) string {
+	return fmt.Sprintf("%d/%s", user, device)
+}
+
+// Open loads the registry kept at path, empty if there is none yet. Ids run
+// from 1 to max, normally Max, and each user may have perUser of them, any
+// number if 0, so that one user cannot use them all up
+func Open(path string, max uint32, perUser int) (*Registry, error) {
+	r := &Registry{path: path, max: max, perUser: perUser, Next: 1, IDs: make(map[string]uint32)}
+	data, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, r); err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+	}
+	if r.IDs == nil {
+		r.IDs = make(map[string]uint32)
+	}
+	r.owners = make(map[uint32]Owner)
+	r.devices = make(map[uint32]int)
+	for k, id := range r.IDs {
+		var o Owner
+		if _, err := fmt.Sscanf(k, "%d/", &o.User); err != nil {
+			return nil, fmt.Errorf("%s: bad key %q", path, k)
+		}
+		o.Device = k[len(fmt.Sprint(o.User))+1:]
+		r.owners[id] = o
+		r.devices[o.User]++
+	}
+	r.next = r.Next
+	return r, nil
+}
+
+// Assign returns the id of user's device, issuing a new one the first time.
+// It is saved with the next Flush, only setting ids aside waits for the disk
+func (r *Registry) Assign(user uint32, device string) (uint32, error) {
+	r.Lock()
+	defer r.Unlock()
+	k := key(user, device)
+	if id, ok := r.IDs[k]; ok {
+		return id, nil
+	}
+	if r.perUser > 0 && r.devices[user] >= r.perUser {
+		return 0, ErrDevices
+	}
+	if r.next > r.max || r.next == 0 {
+		return 0, ErrExhausted
+	}
+	if r.next >= r.Next {
+		aside := r.Next
+		r.Next = r.next + reserve
+		if r.Next > r.max || r.Next < r.next {
+			r.Next = r.max + 1
+		}
+		if err := r.save(); err != nil { // an id not set aside on disk could be issued twice
+			r.Next = aside
+			return 0, err
+		}
+	}
+	id := r.next
+	r.next++
+	r.IDs[k] = id
+	r.owners[id] = Owner{user, device}
+	r.devices[user]++
+	r.dirty = true
+	return id, nil
+}
+
+// Flush saves the ids issued since it was last called
+func (r *Registry) Flush() error {
+	r.Lock()
+	defer r.Unlock()
+	if !r.dirty {
+		return nil
+	}
+	return r.save()
+}
+
+// Lookup returns the owner of id
+func (r *Registry) Lookup(id uint32) (Owner, bool) {
+	r.Lock()
+	defer r.Unlock()
+	o, ok := r.owners[id]
+	return o, ok
+}
+
+// save replaces the file whole so that a crash leaves the previous copy,
+// r must be locked
+func (r *Registry) save() error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(r.path), filepath.Base(r.path)+".")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), r.path); err != nil {
+		return err
+	}
+	r.dirty = false
+	return nil
+}
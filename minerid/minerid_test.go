@@ -0,0 +1,102 @@
+package minerid
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestAssign(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "minerids.json")
+	r, err := Open(path, 3, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, _ := r.Assign(1, "pi-kitchen")
+	b, _ := r.Assign(1, "desktop")
+	c, _ := r.Assign(2, "pi-kitchen")
+	if a != 1 || b != 2 || c != 3 {
+		t.Errorf("expected ids 1 2 3, got %d %d %d", a, b, c)
+	}
+	if again, _ := r.Assign(1, "pi-kitchen"); again != a {
+		t.Errorf("a device keeps its id, expected %d got %d", a, again)
+	}
+	if _, err := r.Assign(3, "laptop"); err != ErrExhausted {
+		t.Errorf("expected ErrExhausted, got %v", err)
+	}
+
+	// ids survive a restart and lead back to their owners
+	if err := r.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	r, err = Open(path, 3, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, _ := r.Assign(1, "desktop"); id != b {
+		t.Errorf("expected %d after reopening, got %d", b, id)
+	}
+	if o, ok := r.Lookup(c); !ok || o.User != 2 || o.Device != "pi-kitchen" {
+		t.Errorf("unexpected owner of %d: %+v %v", c, o, ok)
+	}
+	if _, ok := r.Lookup(0); ok {
+		t.Error("0 is never issued")
+	}
+	if _, err := r.Assign(1, "a/b"); err != ErrExhausted {
+		t.Errorf("expected ErrExhausted after reopening, got %v", err)
+	}
+}
+
+func TestAssignUnflushed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "minerids.json")
+	r, err := Open(path, Max, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, _ := r.Assign(1, "pi-kitchen")
+	if err := r.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	b, _ := r.Assign(1, "desktop") // lost to a crash
+
+	r, err = Open(path, Max, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, _ := r.Assign(1, "pi-kitchen"); id != a {
+		t.Errorf("a flushed device keeps its id, expected %d got %d", a, id)
+	}
+	if id, _ := r.Assign(2, "laptop"); id <= b {
+		t.Errorf("id %d issued again after a crash, expected more than %d", id, b)
+	}
+}
+
+func TestAssignPerUser(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "minerids.json")
+	r, err := Open(path, Max, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, _ := r.Assign(1, "pi-kitchen")
+	r.Assign(1, "desktop")
+	if _, err := r.Assign(1, "laptop"); err != ErrDevices {
+		t.Errorf("expected ErrDevices, got %v", err)
+	}
+	if id, err := r.Assign(1, "pi-kitchen"); err != nil || id != a {
+		t.Errorf("a device over the cap keeps its id, expected %d got %d %v", a, id, err)
+	}
+	if _, err := r.Assign(2, "laptop"); err != nil {
+		t.Errorf("another user: %v", err)
+	}
+
+	// the count survives a restart
+	if err := r.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	r, err = Open(path, Max, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Assign(1, "laptop"); err != ErrDevices {
+		t.Errorf("expected ErrDevices after reopening, got %v", err)
+	}
+}
@@ -0,0 +1,169 @@
+// Package rewards attributes the value of a found block to the users whose
+// shares found it. The proceeds may go to charity, but the statements show
+// each volunteer's contribution and are the basis for payouts or for
+// sponsors who match donations per share.
+package rewards
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"time"
+
+	"coin/shares"
+)
+
+// The attribution methods
+const (
+	PPLNS        = "pplns" // the last N shares, by difficulty, however old
+	Proportional = "prop"  // every share since the previous block found
+)
+
+// Work sums the difficulty of the shares paid for by method, per user. For
+// PPLNS n is the window in units of difficulty: the newest shares are taken
+// until it is full, the one that straddles its edge counting in part. For
+// proportional the shares are those after since
+func Work(entries []shares.Entry, method string, n float64, since time.Time) (map[uint32]float64, error) {
+	work := make(map[uint32]float64)
+	switch method {
+	case PPLNS:
+		if n <= 0 {
+			return nil, errors.New("PPLNS needs a window greater than 0")
+		}
+		for i := len(entries) - 1; i >= 0 && n > 0; i-- {
+			d := math.Min(entries[i].Difficulty, n)
+			work[entries[i].User] += d
+			n -= d
+		}
+	case Proportional:
+		for _, e := range entries {
+			if e.Time.After(since) {
+				work[e.User] += e.Difficulty
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unknown reward method %q, use %s or %s", method, PPLNS, Proportional)
+	}
+	return work, nil
+}
+
+// Outside counts the oldest entries that no block after since can pay for
+// by method, those Work would not take however many shares follow. The
+// share log may drop them once a block found at since is attributed
+func Outside(entries []shares.Entry, method string, n float64, since time.Time) int {
+	switch method {
+	case PPLNS:
+		i := len(entries) - 1
+		for ; i >= 0 && n > 0; i-- {
+			n -= entries[i].Difficulty
+		}
+		return i + 1
+	case Proportional:
+		i := 0
+		for i < len(entries) && !entries[i].Time.After(since) {
+			i++
+		}
+		return i
+	}
+	return 0
+}
+
+// Allocation is one user's part of a block
+type Allocation struct {
+	User   uint32  `json:"user"`
+	Work   float64 `json:"work"`   // difficulty of the shares counted
+	Amount int64   `json:"amount"` // satoshis
+}
+
+// Statement records the attribution of a found block
+type Statement struct {
+	Height uint32       `json:"height"`
+	Found  time.Time    `json:"found"`
+	Miner  string       `json:"miner"`  // who found it
+	ID     uint32       `json:"id"`     // ... and the miner id in its coinbase
+	Method string       `json:"method"` // pplns or prop
+	Value  int64        `json:"value"`  // subsidy plus fees, satoshis
+	Fee    int64        `json:"fee"`    // kept by the pool, with the rounding remainder
+	Users  []Allocation `json:"users"`  // by user id
+}
+
+// Allocate shares value less a fee of feeRate (0.01 is 1%) in proportion to
+// work. Amounts are rounded down, the remainder goes with the fee, so that
+// the allocations and the fee add up to value exactly
+func Allocate(work map[uint32]float64, value int64, feeRate float64) (fee int64, users []Allocation) {
+	fee = int64(float64(value) * feeRate)
+	pot := value - fee
+	var total float64
+	for _, w := range work {
+		total += w
+	}
+	if total <= 0 { // nobody to pay
+		return value, nil
+	}
+	paid := int64(0)
+	for user, w := range work {
+		a := Allocation{User: user, Work: w, Amount: int64(float64(pot) * w / total)}
+		paid += a.Amount
+		users = append(users, a)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].User < users[j].User })
+	return value - paid, users
+}
+
+// Print writes the statement for people to read
+func (s Statement) Print(w io.Writer) {
+	fmt.Fprintf(w, "block %d found %s by %s (id %d), %s\n", s.Height, s.Found.Format("2006-01-02 15:04:05"), s.Miner, s.ID, s.Method)
+	fmt.Fprintf(w, "  value %d, pool fee %d\n", s.Value, s.Fee)
+	for _, a := range s.Users {
+		fmt.Fprintf(w, "  user %-6d work %-12.6g %d\n", a.User, a.Work, a.Amount)
+	}
+}
+
+// Append adds s to the statements file at path, one JSON statement per line
+func Append(path string, s Statement) error {
+	line, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Last returns the most recent statement in the file at path, ok is false if there is none
+func Last(path string) (s Statement, ok bool, err error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return s, false, nil
+	}
+	if err != nil {
+		return s, false, err
+	}
+	defer f.Close()
+	dec := json.NewDecoder(f)
+	for {
+		var next Statement
+		err := dec.Decode(&next)
+		if err == io.EOF || err == io.ErrUnexpectedEOF { // the end, or a torn final line
+			return s, ok, nil
+		}
+		if err != nil {
+			return s, ok, err
+		}
+		s, ok = next, true
+	}
+}
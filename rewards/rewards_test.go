@@ -0,0 +1,128 @@
+package rewards
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"coin/shares"
+)
+
+var start = time.Date(2016, 10, 1, 12, 0, 0, 0, time.UTC)
+
+func entries() []shares.Entry {
+	var list []shares.Entry
+	add := func(user uint32, diff float64) {
+		list = append(list, shares.Entry{Time: start.Add(time.Duration(len(list)) * time.Minute), User: user, Difficulty: diff})
+	}
+	add(1, 1)
+	add(1, 1)
+	add(2, 2) // found at minute 2
+	add(3, 1)
+	add(2, 1)
+	add(1, 2)
+	return list
+}
+
+func TestWork(t *testing.T) {
+	tests := []struct {
+		method string
+		n      float64
+		since  time.Time
+		work   map[uint32]float64
+	}{
+		{PPLNS, 3, time.Time{}, map[uint32]float64{1: 2, 2: 1}},
+		{PPLNS, 4.5, time.Time{}, map[uint32]float64{1: 2, 2: 1.5, 3: 1}},
+		{PPLNS, 100, time.Time{}, map[uint32]float64{1: 4, 2: 3, 3: 1}},
+		{Proportional, 0, start.Add(2 * time.Minute), map[uint32]float64{1: 2, 2: 1, 3: 1}},
+	}
+	for _, test := range tests {
+		work, err := Work(entries(), test.method, test.n, test.since)
+		if err != nil {
+			t.Errorf("%s %v: %v", test.method, test.n, err)
+			continue
+		}
+		if len(work) != len(test.work) {
+			t.Errorf("%s %v\nExp: %v\nGot: %v\n", test.method, test.n, test.work, work)
+			continue
+		}
+		for user, w := range test.work {
+			if work[user] != w {
+				t.Errorf("%s %v\nExp: %v\nGot: %v\n", test.method, test.n, test.work, work)
+				break
+			}
+		}
+	}
+	if _, err := Work(entries(), "pps", 1, start); err == nil {
+		t.Error("expected an error for an unknown method")
+	}
+	if _, err := Work(entries(), PPLNS, 0, start); err == nil {
+		t.Error("expected an error for an empty PPLNS window")
+	}
+}
+
+func TestOutside(t *testing.T) {
+	tests := []struct {
+		method string
+		n      float64
+		since  time.Time
+		exp    int
+	}{
+		{PPLNS, 3, time.Time{}, 4},
+		{PPLNS, 4.5, time.Time{}, 2}, // the straddling share counts in part
+		{PPLNS, 100, time.Time{}, 0},
+		{Proportional, 0, start.Add(2 * time.Minute), 3},
+		{Proportional, 0, time.Time{}, 0},
+	}
+	for _, test := range tests {
+		if got := Outside(entries(), test.method, test.n, test.since); got != test.exp {
+			t.Errorf("%s %v %v: expected %d outside, got %d", test.method, test.n, test.since, test.exp, got)
+		}
+	}
+}
+
+func TestAllocate(t *testing.T) {
+	value := int64(1250000000 + 8756123)
+	fee, users := Allocate(map[uint32]float64{1: 1, 2: 1, 3: 1}, value, 0.02)
+	sum := fee
+	for _, a := range users {
+		sum += a.Amount
+	}
+	if sum != value {
+		t.Errorf("allocations and fee should add to %d, got %d", value, sum)
+	}
+	if len(users) != 3 || users[0].User != 1 || users[0].Amount != users[2].Amount {
+		t.Errorf("unexpected allocations: %+v", users)
+	}
+	if fee < int64(float64(value)*0.02) || fee > int64(float64(value)*0.02)+3 {
+		t.Errorf("fee %d should be 2%% plus the rounding remainder", fee)
+	}
+	if fee, users = Allocate(nil, value, 0.02); fee != value || users != nil {
+		t.Errorf("with no work the pool keeps everything, got fee %d %+v", fee, users)
+	}
+}
+
+func TestStatements(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "statements.log")
+	if _, ok, err := Last(path); ok || err != nil {
+		t.Fatalf("no statements yet, got %v %v", ok, err)
+	}
+	for i, h := range []uint32{433789, 433790} {
+		fee, users := Allocate(map[uint32]float64{1: 3, 2: 1}, 1000, 0.1)
+		s := Statement{Height: h, Found: start.Add(time.Duration(i) * time.Hour), Miner: "0:abc", Method: PPLNS, Value: 1000, Fee: fee, Users: users}
+		if err := Append(path, s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s, ok, err := Last(path)
+	if err != nil || !ok || s.Height != 433790 || len(s.Users) != 2 || s.Users[0].Amount != 675 {
+		t.Errorf("unexpected last statement: %+v %v %v", s, ok, err)
+	}
+	var buffer bytes.Buffer
+	s.Print(&buffer)
+	if !strings.Contains(buffer.String(), "pool fee 100") || !strings.Contains(buffer.String(), "675") {
+		t.Errorf("unexpected statement:\n%s", buffer.String())
+	}
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"coin"
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"coin/accounts"
+	"coin/bans"
+	cpb "coin/service"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Admin ==================================================
+
+// Operators manage the server with coinctl over the Admin service. Its
+// calls carry -akey as coin-admin metadata, without one the service is off
+
+var adminKey = flag.String("akey", "", "admin key, coinctl must present it, no admin service if empty")
+
+const adminPrefix = "/cpb.Admin/"
+
+// admin implements cpb.AdminServer
+type admin struct{}
+
+// banKey checks key names a user, user:ID, or an address, ip:ADDR. A miner
+// logged in, miner:NAME, stands for its user
+func banKey(key string) (string, error) {
+	switch {
+	case strings.HasPrefix(key, "user:"):
+		return key, nil
+	case strings.HasPrefix(key, "ip:"):
+		return bans.IP(strings.TrimPrefix(key, "ip:")), nil
+	case strings.HasPrefix(key, "miner:"):
+		name := strings.TrimPrefix(key, "miner:")
+		users.RLock()
+		user, ok := users.loggedIn[name]
+		users.RUnlock()
+		if !ok || name == "EXTERNAL" {
+			return "", status.Errorf(codes.NotFound, "no miner %s logged in", name)
+		}
+		return bans.User(user), nil
+	}
+	return "", status.Errorf(codes.InvalidArgument, "%q is neither user:ID, ip:ADDR nor miner:NAME", key)
+}
+
+// Ban bans a user or an address : implements cpb.AdminServer
+func (a *admin) Ban(ctx context.Context, in *cpb.BanRequest) (*cpb.BanReply, error) {
+	key, err := banKey(in.Key)
+	if err != nil {
+		return nil, err
+	}
+	reason := in.Reason
+	if reason == "" {
+		reason = "by an operator"
+	}
+	b := banned.Ban(key, reason, time.Duration(in.Seconds)*time.Second, time.Now())
+	bansTotal.Inc("admin")
+	fmt.Printf("BANNED: %s until %s, %s\n", b.Key, b.Until.Format(time.Stamp), b.Reason)
+	return &cpb.BanReply{Ok: true, Until: b.Until.Unix()}, nil
+}
+
+// Unban lifts a ban : implements cpb.AdminServer
+func (a *admin) Unban(ctx context.Context, in *cpb.BanRequest) (*cpb.BanReply, error) {
+	key, err := banKey(in.Key)
+	if err != nil {
+		return nil, err
+	}
+	ok := banned.Unban(key, time.Now())
+	if ok {
+		fmt.Printf("UNBANNED: %s\n", key)
+	}
+	return &cpb.BanReply{Ok: ok}, nil
+}
+
+// Bans lists the bans in force : implements cpb.AdminServer
+func (a *admin) Bans(ctx context.Context, in *cpb.BansRequest) (*cpb.BansReply, error) {
+	reply := &cpb.BansReply{}
+	for _, b := range banned.List(time.Now()) {
+		reply.Bans = append(reply.Bans, &cpb.Ban{Key: b.Key, Until: b.Until.Unix(), Reason: b.Reason})
+	}
+	return reply, nil
+}
+
+// Miners lists the miners logged in : implements cpb.AdminServer
+func (a *admin) Miners(ctx context.Context, in *cpb.MinersRequest) (*cpb.MinersReply, error) {
+	reply := &cpb.MinersReply{}
+	users.RLock()
+	for name, user := range users.loggedIn {
+		if name == "EXTERNAL" || (in.User != 0 && user != in.User) {
+			continue
+		}
+		m := &cpb.Miner{Name: name, User: user, Id: users.minerIDs[name]}
+		if p, ok := users.partitions[name]; ok {
+			m.Extranonce = p.Bytes()
+		}
+		if t, ok := users.seen[name]; ok {
+			m.Seen = atomic.LoadInt64(t) / int64(time.Second)
+		}
+		reply.Miners = append(reply.Miners, m)
+	}
+	users.RUnlock()
+	for _, m := range reply.Miners {
+		m.Tally = tallyReply(book.Miner(m.Id))
+	}
+	sort.Slice(reply.Miners, func(i, j int) bool { return reply.Miners[i].Name < reply.Miners[j].Name })
+	return reply, nil
+}
+
+// Round reports the job and the race : implements cpb.AdminServer
+func (a *admin) Round(ctx context.Context, in *cpb.RoundRequest) (*cpb.RoundReply, error) {
+	r := snapshot(time.Now()) // as it would be saved
+	reply := &cpb.RoundReply{Server: r.Server, Epoch: r.Epoch, Racing: r.Racing}
+	if j := r.Job; j != nil {
+		reply.Job, reply.Height, reply.Bits, reply.Started = j.ID, j.Height, j.Bits, j.Issued.Unix()
+		reply.Prev = fmt.Sprintf("%x", coin.Reverse(j.Block[4:36]))
+	}
+	users.RLock()
+	reply.Miners = uint32(users.count)
+	users.RUnlock()
+	return reply, nil
+}
+
+// Users lists the users, without their keys : implements cpb.AdminServer
+func (a *admin) Users(ctx context.Context, in *cpb.UsersRequest) (*cpb.UsersReply, error) {
+	list, err := store.List()
+	if err != nil {
+		return nil, err
+	}
+	reply := &cpb.UsersReply{}
+	for _, u := range list {
+		u.Key = ""
+		reply.Users = append(reply.Users, userReply(u))
+	}
+	return reply, nil
+}
+
+// AddUser adds a user : implements cpb.AdminServer
+func (a *admin) AddUser(ctx context.Context, in *cpb.UserRequest) (*cpb.UserReply, error) {
+	u, err := store.Create(in.Id, in.Key)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Printf("USER ADDED: %d\n", u.ID)
+	return &cpb.UserReply{User: userReply(u)}, nil
+}
+
+// DisableUser stops a user logging in and logs out its miners : implements cpb.AdminServer
+func (a *admin) DisableUser(ctx context.Context, in *cpb.UserRequest) (*cpb.UserReply, error) {
+	if err := store.Disable(in.Id); err != nil {
+		return nil, err
+	}
+	users.Lock()
+	n := 0
+	for name, user := range users.loggedIn {
+		if user == in.Id && name != "EXTERNAL" {
+			dismiss(name)
+			n++
+		}
+	}
+	users.Unlock()
+	fmt.Printf("USER DISABLED: %d, %d miners logged out\n", in.Id, n)
+	return &cpb.UserReply{User: &cpb.User{Id: in.Id, Disabled: true}}, nil
+}
+
+// EnableUser lets a user log in again : implements cpb.AdminServer
+func (a *admin) EnableUser(ctx context.Context, in *cpb.UserRequest) (*cpb.UserReply, error) {
+	if err := store.Enable(in.Id); err != nil {
+		return nil, err
+	}
+	fmt.Printf("USER ENABLED: %d\n", in.Id)
+	return &cpb.UserReply{User: &cpb.User{Id: in.Id}}, nil
+}
+
+// RotateKey gives a user a new key, its miners keep their sessions : implements cpb.AdminServer
+func (a *admin) RotateKey(ctx context.Context, in *cpb.UserRequest) (*cpb.UserReply, error) {
+	u, err := store.Rotate(in.Id)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Printf("KEY ROTATED: user %d\n", u.ID)
+	return &cpb.UserReply{User: userReply(u)}, nil
+}
+
+func userReply(u accounts.User) *cpb.User {
+	return &cpb.User{Id: u.ID, Key: u.Key, Disabled: u.Disabled, Created: u.Created.Unix(), Rotated: u.Rotated.Unix()}
+}
+
+// Drain stops the server once the reply is out : implements cpb.AdminServer
+func (a *admin) Drain(ctx context.Context, in *cpb.DrainRequest) (*cpb.DrainReply, error) {
+	select {
+	case drainNow <- "admin":
+	default: // draining already
+	}
+	return &cpb.DrainReply{Ok: true}, nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	cpb "coin/service"
+
+	"golang.org/x/net/context"
+)
+
+// Liveness ===============================================
+
+// A miner is alive while it is heard from: any call it makes, any message on
+// its stream, or a Heartbeat while it long-polls. One not heard from for
+// -alive is dropped as DEAD, however slow it is to come back for work, so
+// races need not wait for anyone. Stratum miners are dropped when their
+// connection closes instead
+
+var aliveFor = flag.Duration("alive", time.Minute, "a miner not heard from for this long is dropped as DEAD")
+
+// seen records that miner name was heard from, if it logged in with Login
+func seen(name string) {
+	users.RLock()
+	defer users.RUnlock()
+	if t, ok := users.seen[name]; ok {
+		atomic.StoreInt64(t, time.Now().UnixNano())
+	}
+}
+
+// Heartbeat keeps a miner alive between calls : implements cpb.CoinServer
+func (s *server) Heartbeat(ctx context.Context, in *cpb.HeartbeatRequest) (*cpb.HeartbeatReply, error) {
+	users.RLock()
+	_, ok := users.seen[in.Name]
+	users.RUnlock()
+	if !ok {
+		return nil, errNotLoggedIn
+	}
+	return &cpb.HeartbeatReply{Grace: int64(*aliveFor / time.Second)}, nil
+}
+
+// reapDead drops the miners not heard from for -alive, until shutdown
+func reapDead() {
+	tick := time.NewTicker(*aliveFor / 4)
+	defer tick.Stop()
+	for {
+		select {
+		case now := <-tick.C:
+			users.Lock()
+			for name, t := range users.seen {
+				if last := time.Unix(0, atomic.LoadInt64(t)); now.Sub(last) > *aliveFor {
+					fmt.Printf("DEAD: %s, not heard from since %s\n", name, last.Format(time.Stamp))
+					dismiss(name)
+					deadMiners.Inc()
+				}
+			}
+			users.Unlock()
+		case <-quit:
+			return
+		}
+	}
+}
+
+// entered enters miner name in the race now on, or about to start, and
+// returns the channel closed when it is over: GetCancel waits on it, so a
+// miner that comes back for work late in a race is not held over the next
+func entered(name string) <-chan struct{} {
+	ended := stop.Stopped()
+	users.Lock()
+	users.racing[name] = ended
+	users.Unlock()
+	return ended
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"coin/accounts"
+	"coin/bans"
+	"coin/metrics"
+	cpb "coin/service"
+	"strings"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+var sessions *accounts.Sessions // issued at Login
+
+var authFailures = metrics.NewCounter("coin_server_auth_failures_total", "Calls refused by the interceptors.", "reason")
+
+// anyone may call the open methods, only the conductor the conductor methods
+var (
+	openMethods      = map[string]bool{"/cpb.Coin/Login": true, "/cpb.Coin/Challenge": true}
+	conductorMethods = map[string]bool{"/cpb.Coin/IssueBlock": true, "/cpb.Coin/GetResult": true}
+)
+
+type callerKey struct{}
+
+// callerOf is the login authenticated for the call with ctx, EXTERNAL for the
+// conductor. Streams need it, they name no miner when they are opened
+func callerOf(ctx context.Context) string {
+	caller, _ := ctx.Value(callerKey{}).(string)
+	return caller
+}
+
+// authenticate checks the credentials sent with a call to method and returns
+// who is calling, EXTERNAL for the conductor, ADMIN for an operator or the
+// login of a miner's session
+func authenticate(ctx context.Context, method string) (string, error) {
+	if openMethods[method] {
+		return "", nil
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	if strings.HasPrefix(method, adminPrefix) {
+		if keys := md.Get(accounts.AdminHeader); len(keys) == 0 || !accounts.SameKey(keys[0], *adminKey) {
+			authFailures.Inc("admin")
+			return "", status.Error(codes.Unauthenticated, "Bad admin key")
+		}
+		return "ADMIN", nil
+	}
+	if keys := md.Get(accounts.ConductorHeader); len(keys) > 0 {
+		if !accounts.SameKey(keys[0], *condKey) {
+			authFailures.Inc("conductor")
+			return "", status.Error(codes.Unauthenticated, "Bad conductor key")
+		}
+		if !conductorCert(ctx) {
+			authFailures.Inc("conductor")
+			return "", status.Error(codes.Unauthenticated, "Conductor certificate required")
+		}
+		return "EXTERNAL", nil
+	}
+	if conductorMethods[method] {
+		authFailures.Inc("conductor")
+		return "", status.Error(codes.PermissionDenied, "Only the conductor may call "+method)
+	}
+	tokens := md.Get(accounts.SessionHeader)
+	if len(tokens) == 0 {
+		authFailures.Inc("session")
+		return "", status.Error(codes.Unauthenticated, accounts.ErrNoSession.Error())
+	}
+	ss, err := sessions.Check(tokens[0], time.Now())
+	if err != nil {
+		authFailures.Inc("session")
+		return "", status.Error(codes.Unauthenticated, err.Error())
+	}
+	return ss.Name, nil
+}
+
+// claimed is the miner a request acts for, if it names one
+func claimed(req interface{}) (string, bool) {
+	switch in := req.(type) {
+	case *cpb.GetWorkRequest:
+		return in.Name, true
+	case *cpb.GetCancelRequest:
+		return in.Name, true
+	case *cpb.LogoutRequest:
+		return in.Name, true
+	case *cpb.SubmitShareRequest:
+		return in.Name, true
+	case *cpb.HeartbeatRequest:
+		return in.Name, true
+	case *cpb.AnnounceRequest:
+		if in.Win == nil {
+			return "", true
+		}
+		return in.Win.Identity, true
+	}
+	return "", false
+}
+
+// admitted authenticates a call to method and refuses it if the caller, or
+// its address, is banned. A failed authentication is scored against the address
+func admitted(ctx context.Context, method string) (string, error) {
+	caller, err := authenticate(ctx, method)
+	if err != nil {
+		debugF("%s: %v", method, err)
+		misbehaved(ctx, "", bans.Auth)
+		return "", err
+	}
+	if caller == "EXTERNAL" || caller == "ADMIN" {
+		return caller, nil
+	}
+	if err := refuseBanned(ctx, caller); err != nil {
+		return "", err
+	}
+	seen(caller)
+	return caller, nil
+}
+
+// unaryAuth authenticates every call, and stops a miner acting for another
+func unaryAuth(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	caller, err := admitted(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	if name, ok := claimed(req); ok && name != caller {
+		authFailures.Inc("identity")
+		misbehaved(ctx, caller, bans.Auth)
+		return nil, status.Errorf(codes.PermissionDenied, "%s may not act for %q", caller, name)
+	}
+	return handler(context.WithValue(ctx, callerKey{}, caller), req)
+}
+
+// authStream carries the caller in its context
+type authStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s authStream) Context() context.Context {
+	return s.ctx
+}
+
+// streamAuth authenticates a stream when it is opened
+func streamAuth(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	caller, err := admitted(ss.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	return handler(srv, authStream{ss, context.WithValue(ss.Context(), callerKey{}, caller)})
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"coin/bans"
+	"coin/metrics"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// Bans ===================================================
+
+// Invalid solutions, failed logins, floods of login challenges, duplicate
+// and stale shares are scored against the user of the miner and the address
+// the call came from. Either is banned for -bantime once its score reaches
+// -banscore, and a banned user or address may not log in or call for work
+// until the ban lapses or an operator lifts it. A failed login, or a
+// challenge asked for an unknown user or past the cap, scores the address
+// only, or anyone could lock a user out by guessing at its key
+
+var (
+	banScore = flag.Float64("banscore", 100, "misbehaviour score that bans a user or address, an invalid solution scores 25")
+	banTime  = flag.Duration("bantime", 10*time.Minute, "how long a ban lasts")
+)
+
+const scoreHalfLife = 10 * time.Minute // misbehaviour is forgiven this fast
+
+var (
+	offences  = metrics.NewCounter("coin_server_offences_total", "Misbehaviour scored, by offence.", "offence")
+	bansTotal = metrics.NewCounter("coin_server_bans_total", "Bans imposed, by the score or by an operator.", "source")
+)
+
+var banned *bans.Table // made in main
+
+// offenders are the keys misbehaviour with ctx is scored on: the user of
+// miner name, if it is logged in, and the caller's address
+func offenders(ctx context.Context, name string) []string {
+	var keys []string
+	if name != "" && name != "EXTERNAL" {
+		users.RLock()
+		user, ok := users.loggedIn[name]
+		users.RUnlock()
+		if ok {
+			keys = append(keys, bans.User(user))
+		}
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		keys = append(keys, bans.IP(p.Addr.String()))
+	}
+	return keys
+}
+
+// misbehaved scores offence o by miner name, "" for a caller not logged in
+func misbehaved(ctx context.Context, name string, o bans.Offence) {
+	offences.Inc(string(o))
+	for _, b := range banned.Offend(o, time.Now(), offenders(ctx, name)...) {
+		bansTotal.Inc("score")
+		fmt.Printf("BANNED: %s until %s, %s\n", b.Key, b.Until.Format(time.Stamp), b.Reason)
+	}
+}
+
+// refuseBanned refuses a call from miner name, or from its address, while
+// either is banned
+func refuseBanned(ctx context.Context, name string) error {
+	return refuse(banned.Banned(time.Now(), offenders(ctx, name)...))
+}
+
+// refuse is the error of ban b, if ok
+func refuse(b bans.Ban, ok bool) error {
+	if !ok {
+		return nil
+	}
+	return status.Errorf(codes.PermissionDenied, "Banned until %s: %s", b.Until.Format(time.RFC3339), b.Reason)
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"errors"
+
+	"coin/extranonce"
+)
+
+// Extranonces ============================================
+
+// Every miner, logged in or on Stratum, holds a partition of the coinbase
+// extranonce: a prefix of extranonce1Size bytes no other miner holds, which
+// is in the coinbase of all its work, and the bytes after it to roll. No two
+// miners search the same headers, and the extranonce of a share or a win
+// tells whose it is. The conductor's work keeps prefix 0. A Stratum
+// session's extranonce1 is its prefix
+
+var partitions *extranonce.Allocator // see setup
+
+var errPartition = errors.New("extranonce outside the miner's partition")
+
+// partition is the partition of miner name, if it holds one
+func partition(name string) (extranonce.Partition, bool) {
+	users.RLock()
+	defer users.RUnlock()
+	p, ok := users.partitions[name]
+	return p, ok
+}
+
+// partitioned writes the partition of miner name into the extranonce of cb,
+// its coinbase on data
+func partitioned(name string, cb []byte, data blockdata) ([]byte, error) {
+	p, ok := partition(name)
+	if !ok {
+		return cb, nil
+	}
+	start, end, err := extranonceAt(cb, data)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.Fill(cb[start:end]); err != nil {
+		return nil, err
+	}
+	return cb, nil
+}
+
+// inPartition checks that an extranonce miner name rolled is its own
+func inPartition(name string, en []byte) error {
+	if p, ok := partition(name); ok && !p.Contains(en) {
+		return errPartition
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Conductor epochs =======================================
+
+type lockEpoch struct {
+	sync.Mutex
+	epoch uint64 // highest conductor epoch seen, 0 without leader election
+}
+
+var leader lockEpoch
+
+// checkEpoch accepts blocks from the conductor holding epoch e or a later one.
+// It reports whether e belongs to a new leader
+func checkEpoch(e uint64) (accepted, changed bool) {
+	leader.Lock()
+	defer leader.Unlock()
+	switch {
+	case e < leader.epoch:
+		return false, false
+	case e > leader.epoch:
+		fmt.Printf("LEADER: conductor epoch %d replaces %d\n", e, leader.epoch)
+		leader.epoch = e
+		return true, true
+	}
+	return true, false
+}
+
+// abandonRace cancels a race left open by a conductor that has been replaced,
+// as Announce would, so that its miners come back for the new leader's work
+func abandonRace() {
+	if cancelRace() {
+		fmt.Println("abandoning race of previous conductor")
+	}
+}
+
+// cancelRace ends the race in progress without a winner, its work stays good
+// for the next race. It reports whether there was one
+func cancelRace() bool {
+	run.Lock()
+	defer run.Unlock()
+	if run.winnerFound {
+		return false
+	}
+	run.winnerFound = true
+	closeJob(false)
+	run.ch = make(chan struct{})
+	stop.Done()
+	return true
+}
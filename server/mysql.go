@@ -0,0 +1,8 @@
+//go:build mysql
+// +build mysql
+
+package main
+
+// links the MySQL driver for -users sql:mysql:user:password@tcp(host:3306)/coin,
+// accounts.Open adds parseTime=true to the dsn
+import _ "github.com/go-sql-driver/mysql"
@@ -0,0 +1,321 @@
+package main
+
+import (
+	"coin"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"coin/accounts"
+	"coin/certs"
+	cpb "coin/service"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// Proxy ==================================================
+
+// A server given -upstream is a proxy: rather than take blocks from a
+// conductor, it logs in to the upstream server as one miner, user -uuser
+// with key -ukey, and races its own miners for the work pushed to it on a
+// MineStream. The upstream's coinbase carries the proxy's partition; the
+// bytes after it are the proxy's to roll, and its miners each hold a
+// partition of them, so the extranonce tells their work apart here and
+// upstream alike. Shares meeting the upstream's target for the proxy are
+// relayed up, and so are wins. The 4 byte extranonce has room for one proxy
+// between the miners and a server with a conductor, and none for Stratum
+
+var (
+	upstreamAddr = flag.String("upstream", "", "server to mine for as one miner, host:port, in place of a conductor")
+	upstreamUser = flag.Uint("uuser", 0, "user id the proxy logs in upstream as")
+	upstreamKey  = flag.String("ukey", "", "key of -uuser upstream")
+	upstreamTLS  = flag.Bool("utls", false, "dial upstream with TLS, implied by -uca")
+	upstreamCA   = flag.String("uca", "", "CA bundle the upstream's certificate must be signed by (default system roots)")
+)
+
+// upstreamJob is the upstream's work a job of ours was made from
+type upstreamJob struct {
+	id     uint64 // the upstream's job id
+	prefix []byte // our partition upstream, before our miners' extranonce
+}
+
+var upstream struct {
+	sync.Mutex
+	name string                 // our login upstream
+	out  chan *cpb.MinerMessage // to the upstream's stream, nil while it is down
+	jobs map[uint64]upstreamJob // by our job id, the last -jobs
+}
+
+// upstreamShare is the upstream's share target for the proxy, atomic
+var upstreamShare uint32
+
+// proxying is the goroutines mining for the upstream, shutdown waits for
+// them to let go of it
+var proxying sync.WaitGroup
+
+// serveUpstream mines for the upstream server, if -upstream is set, logging
+// in again whenever the stream to it fails
+func serveUpstream() {
+	if *upstreamAddr == "" {
+		return
+	}
+	if *upstreamKey == "" {
+		log.Fatalf("%s", "Upstream key missing! use -ukey key")
+	}
+	if *stratumAddr != "" {
+		log.Fatalf("%s", "A proxy has no extranonce left for Stratum miners, drop -stratum")
+	}
+	upstream.jobs = make(map[uint64]upstreamJob)
+	serverID = *upstreamAddr
+	session := accounts.NewCredentials(accounts.SessionHeader, "") // the token arrives with Login
+	conn, err := grpc.Dial(*upstreamAddr, upstreamTransport(), grpc.WithPerRPCCredentials(session))
+	fatalF("failed to dial upstream", err)
+	c := cpb.NewCoinClient(conn)
+	proxying.Add(2)
+	go relayWins()
+	go func() {
+		defer proxying.Done()
+		defer conn.Close()
+		for {
+			err := upstreamLogin(c, session)
+			if err == nil {
+				err = mineUpstream(c)
+			}
+			select {
+			case <-quit:
+				return
+			default:
+			}
+			log.Printf("upstream %s: %v", *upstreamAddr, err)
+			if cancelRace() { // nothing to mine for until we are back
+				fmt.Println("race ended, upstream lost")
+			}
+			select {
+			case <-time.After(5 * time.Second):
+			case <-quit:
+				return
+			}
+		}
+	}()
+}
+
+// upstreamTransport is plaintext unless -utls or -uca is given
+func upstreamTransport() grpc.DialOption {
+	if !*upstreamTLS && *upstreamCA == "" {
+		return grpc.WithInsecure()
+	}
+	config, err := certs.ClientConfig(*upstreamCA, "", "")
+	fatalF("failed to load upstream CA bundle", err)
+	return grpc.WithTransportCredentials(credentials.NewTLS(config))
+}
+
+// upstreamLogin logs in upstream with a challenge, as a client would, and
+// keeps the session token for the calls that follow
+func upstreamLogin(c cpb.CoinClient, session *accounts.Credentials) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	user := uint32(*upstreamUser)
+	ch, err := c.Challenge(ctx, &cpb.ChallengeRequest{User: user})
+	if err != nil {
+		return err
+	}
+	name, err := coin.GenLogin(user, *upstreamKey, ch.Nonce)
+	if err != nil {
+		return err
+	}
+	host, _ := os.Hostname()
+	r, err := c.Login(ctx, &cpb.LoginRequest{Name: name, User: user, Time: ch.Nonce, Device: fmt.Sprintf("%s:%d", host, *index)})
+	if err != nil {
+		return err
+	}
+	session.Set(r.Token)
+	upstream.Lock()
+	upstream.name = name
+	upstream.Unlock()
+	fmt.Printf("UPSTREAM: %s as %s\n", *upstreamAddr, name)
+	return nil
+}
+
+// mineUpstream takes the upstream's work on a MineStream and relays to it
+// until the stream fails or we shut down
+func mineUpstream(c cpb.CoinClient) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream, err := c.MineStream(ctx)
+	if err != nil {
+		return err
+	}
+	out := make(chan *cpb.MinerMessage, 64)
+	upstream.Lock()
+	upstream.out = out
+	upstream.Unlock()
+	defer func() {
+		upstream.Lock()
+		upstream.out = nil
+		upstream.Unlock()
+	}()
+	proxying.Add(1)
+	go func() { // the only sender, with a heartbeat while there is nothing to say
+		defer proxying.Done()
+		tick := time.NewTicker(*aliveFor / 4)
+		defer tick.Stop()
+		for {
+			var m *cpb.MinerMessage
+			select {
+			case m = <-out:
+			case <-tick.C:
+				m = &cpb.MinerMessage{Kind: "heartbeat"}
+			case <-quit:
+				cancel()
+				return
+			case <-ctx.Done():
+				return
+			}
+			if stream.Send(m) != nil {
+				return // Recv below fails too
+			}
+		}
+	}()
+	for {
+		m, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+		switch m.Kind {
+		case "work":
+			upstreamWork(m.Work)
+		case "cancel":
+			cancelRace()
+		case "share":
+			debugF("upstream share %s %s\n", m.Share.Result, m.Share.Reason)
+		case "win":
+			if m.Win.Ok {
+				fmt.Println("UPSTREAM: win accepted")
+			} else {
+				fmt.Printf("UPSTREAM: win refused: %s\n", m.Win.Reason)
+			}
+		case "difficulty":
+			atomic.StoreUint32(&upstreamShare, m.Bits)
+		}
+	}
+}
+
+// upstreamWork starts a race on work from upstream, as IssueBlock would on a
+// block from the conductor. Our miners' partitions follow ours in its
+// coinbase
+func upstreamWork(w *cpb.Work) {
+	if len(w.Block) != 80 {
+		log.Printf("upstream work of %d bytes is not a blockheader", len(w.Block))
+		return
+	}
+	start, end, err := coin.Transaction(w.Coinbase).Extranonce()
+	if err != nil {
+		log.Printf("upstream coinbase: %v", err)
+		return
+	}
+	at := start + len(w.Extranonce)
+	if end-at < extranonce1Size {
+		log.Printf("upstream leaves %d bytes of extranonce, our miners need %d", end-at, extranonce1Size)
+		return
+	}
+	endResumed()
+	cancelRace() // in case its cancellation was lost with the stream
+	select {     // in case we are holding previous work, discard it
+	case <-blockchan:
+	default:
+	}
+	data := blockdata{blk: w.Block, merk: w.Skel, bits: w.Bits, coinb1: w.Coinbase[:at], coinb2: w.Coinbase[end:], enlen: end - at}
+	j := recent.Add(w.Block[4:36], data, time.Now())
+	data.job, data.issued = j.ID, j.Issued
+	upstream.Lock()
+	upstream.jobs[j.ID] = upstreamJob{w.Job, w.Extranonce}
+	for id := range upstream.jobs {
+		if id+uint64(*jobWindow) <= j.ID {
+			delete(upstream.jobs, id)
+		}
+	}
+	upstream.Unlock()
+	atomic.StoreUint32(&upstreamShare, w.Share)
+	fmt.Printf("UPSTREAM: work %d as job %d\n", w.Job, j.ID)
+	blockchan <- data
+}
+
+// relayUp queues m for the upstream, dropping it while the stream is down
+func relayUp(m *cpb.MinerMessage) bool {
+	upstream.Lock()
+	out := upstream.out
+	upstream.Unlock()
+	if out == nil {
+		return false
+	}
+	select {
+	case out <- m:
+		return true
+	default: // the upstream is not keeping up
+		return false
+	}
+}
+
+// upstreamWin is win, a verified win of our miner on one of our jobs, as the
+// upstream knows the work: its job, our login, and the whole extranonce
+func upstreamWin(win cpb.Win) (*cpb.Win, bool) {
+	upstream.Lock()
+	defer upstream.Unlock()
+	up, ok := upstream.jobs[win.Job]
+	if !ok {
+		return nil, false
+	}
+	extranonce := append(append([]byte{}, up.prefix...), win.Extranonce...)
+	return &cpb.Win{Block: win.Block, Nonce: win.Nonce, Identity: upstream.name, Job: up.id, Extranonce: extranonce}, true
+}
+
+// relayShare sends an accepted share of miner name upstream if it meets the
+// upstream's target for us
+func relayShare(name string, header coin.Block, extranonce []byte, id uint64) {
+	if *upstreamAddr == "" {
+		return
+	}
+	hash, err := header.Hash()
+	if err != nil || !coin.MeetsTarget(hash, coin.Bits2Target(atomic.LoadUint32(&upstreamShare))) {
+		return
+	}
+	win := &cpb.Win{Block: header, Identity: name, Job: id, Extranonce: extranonce}
+	claimExtranonce(win)
+	up, ok := upstreamWin(*win)
+	if !ok {
+		return
+	}
+	if !relayUp(&cpb.MinerMessage{Kind: "share", Block: header, Job: up.Job, Extranonce: up.Extranonce}) {
+		debugF("share from %s not relayed, upstream down\n", name)
+	}
+}
+
+// relayWins takes the results GetResult would give the conductor and sends
+// the wins of our miners upstream
+func relayWins() {
+	defer proxying.Done()
+	for {
+		var win cpb.Win
+		select {
+		case win = <-resultchan:
+		case <-quit:
+			return
+		}
+		up, ok := upstreamWin(win)
+		if !ok {
+			fmt.Printf("win from %s on job %d has no upstream work\n", win.Identity, win.Job)
+			continue
+		}
+		if !relayUp(&cpb.MinerMessage{Kind: "win", Win: up}) {
+			fmt.Printf("win from %s lost, upstream down\n", win.Identity)
+			continue
+		}
+		fmt.Printf("UPSTREAM: win from %s relayed on job %d\n", win.Identity, up.Job)
+	}
+}
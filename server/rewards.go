@@ -0,0 +1,100 @@
+package main
+
+import (
+	"coin"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"coin/rewards"
+	"coin/shares"
+)
+
+// Rewards ================================================
+
+type lockFound struct {
+	sync.Mutex
+	last time.Time // when the previous block was found, proportional rounds start here
+}
+
+var (
+	shareLog    *shares.Log
+	found       lockFound
+	attributing sync.WaitGroup // statements being written, the drain waits for them
+)
+
+// openRewards checks the reward settings and opens the share log
+func openRewards() {
+	if _, err := rewards.Work(nil, *method, *window, time.Time{}); err != nil {
+		log.Fatalf("bad reward settings: %v", err)
+	}
+	if *poolFee < 0 || *poolFee > 1 {
+		log.Fatalf("pool fee %v is not a fraction", *poolFee)
+	}
+	var err error
+	shareLog, err = shares.OpenLog(*shareFile)
+	fatalF("failed to open share log", err)
+	last, ok, err := rewards.Last(*statements)
+	fatalF("failed to read statements", err)
+	if ok {
+		found.last = last.Found
+	}
+}
+
+// shareDifficulty is the weight of a share meeting share target bits
+func shareDifficulty(bits uint32) float64 {
+	return coin.Difficulty(coin.Bits2Target(bits))
+}
+
+// logShare records an accepted share from miner name, id, at share target bits, for the rewards
+func logShare(name string, id, user uint32, bits uint32) {
+	e := shares.Entry{Time: time.Now(), Miner: name, ID: id, User: user, Difficulty: shareDifficulty(bits)}
+	if err := shareLog.Append(e); err != nil {
+		log.Printf("could not log share: %v", err)
+	}
+}
+
+// attribute shares out the block found by miner name and appends the
+// statement. Announce adds it to attributing
+func attribute(name string) {
+	defer attributing.Done()
+	block.Lock()
+	data := block.data
+	block.Unlock()
+	found.Lock()
+	defer found.Unlock()
+	entries, err := shares.ReadLog(*shareFile)
+	if err != nil {
+		log.Printf("could not attribute block: %v", err)
+		return
+	}
+	n := *window * shareDifficulty(uint32(*shareBits))
+	work, err := rewards.Work(entries, *method, n, found.last)
+	if err != nil {
+		log.Printf("could not attribute block: %v", err)
+		return
+	}
+	st := rewards.Statement{
+		Height: data.height,
+		Found:  time.Now(),
+		Miner:  fmt.Sprintf("%d:%s", *index, name),
+		ID:     uint32(minerID(name)),
+		Method: *method,
+		Value:  int64(coin.BlockValue(data.height, int(data.fees))),
+	}
+	st.Fee, st.Users = rewards.Allocate(work, st.Value, *poolFee)
+	if err := rewards.Append(*statements, st); err != nil {
+		log.Printf("could not write statement: %v", err)
+		return
+	}
+	found.last = st.Found
+	if err := shareLog.Prune(rewards.Outside(entries, *method, n, st.Found)); err != nil {
+		log.Printf("could not prune share log: %v", err)
+	}
+	st.Print(os.Stdout)
+	if o, ok := ids.Lookup(st.ID); ok {
+		fmt.Printf("  miner id %d is user %d device %q\n", st.ID, o.User, o.Device)
+	}
+}
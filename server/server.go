@@ -2,7 +2,15 @@ package main
 
 import (
 	"coin"
+	"coin/accounts"
+	"coin/bans"
+	"coin/extranonce"
+	"coin/jobs"
+	"coin/metrics"
+	"coin/minerid"
 	cpb "coin/service"
+	"coin/shares"
+	"coin/vardiff"
 	"errors"
 	"flag"
 	"fmt"
@@ -13,23 +21,54 @@ import (
 
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 const (
-	allowedTime          = 1  // 2  // "number of seconds before miner declared NOT alive"
 	allowedConductorTime = 20 // number of seconds for the conductor
 )
 
 var (
-	index     = flag.Int("index", -1, "RPC port is 50051+index") // must be at least 0
-	numMiners = flag.Int("miners", 3, "number of miners")        // DOESNT include the external one
-	debug     = flag.Bool("d", false, "debug mode")
+	index       = flag.Int("index", -1, "RPC port is 50051+index") // must be at least 0
+	numMiners   = flag.Int("miners", 0, "soft limit on miners, new logins are refused past it, 0 for none")
+	debug       = flag.Bool("d", false, "debug mode")
+	metricsAddr = flag.String("metrics", "", "address for the /metrics endpoint, eg :9091")
+	grace       = flag.Duration("grace", 10*time.Second, "deadline for draining RPCs on SIGINT/SIGTERM")
+	shareBits   = flag.Uint("share", 0x201fffff, "starting share target bits, the default is met by one hash in 8")
+	shareRate   = flag.Float64("spm", 4, "shares per minute each miner's share target is adjusted for")
+	tallyFile   = flag.String("tally", "shares.json", "share tallies, kept across rounds and restarts")
+	shareFile   = flag.String("sharelog", "shares.log", "log of accepted shares, the basis of rewards")
+	method      = flag.String("reward", "pplns", "reward attribution, pplns or prop")
+	window      = flag.Float64("window", 1000, "PPLNS window N, in shares at the -share target")
+	poolFee     = flag.Float64("fee", 0, "pool fee deducted from each block, 0.01 is 1%")
+	statements  = flag.String("statements", "statements.log", "per-user statements of found blocks, appended to")
+	idFile      = flag.String("ids", "minerids.json", "miner ids issued to each user's devices")
+	perUser     = flag.Int("devices", 100, "devices a user may have miner ids for, new ones are refused past it, 0 for any number")
+	userStore   = flag.String("users", "users.json", "user store, a JSON file servers may share or sql:driver:dsn, parseTime=true is added to a mysql dsn")
+	skew        = flag.Duration("skew", 2*time.Minute, "how far a login time may be from the server's clock")
+	sessionTTL  = flag.Duration("session", 30*time.Minute, "a miner's session ends when unused this long")
+	condKey     = flag.String("ckey", "", "conductor key, the conductor must present it") // no default, see main
+	jobWindow   = flag.Int("jobs", 4, "recent jobs whose work is accepted while they build on the current tip")
 )
 
+var (
+	minersIn    = metrics.NewGauge("coin_server_miners_logged_in", "Miners currently logged in.")
+	deadMiners  = metrics.NewCounter("coin_server_dead_miners_total", "Miners dropped as DEAD, not heard from for -alive.")
+	announces   = metrics.NewCounter("coin_server_announce_total", "Announced solutions by result.", "result")
+	sharesTotal = metrics.NewCounter("coin_server_shares_total", "Submitted shares by verdict.", "result")
+)
+
+// lockMap is read on every call and written on login, logout and as each
+// miner enters a race
 type lockMap struct {
-	sync.Mutex
-	countIN  int
-	loggedIn map[string]uint32
+	sync.RWMutex
+	count      int // miners logged in, the conductor aside
+	loggedIn   map[string]uint32
+	minerIDs   map[string]uint32               // by login, see ids
+	partitions map[string]extranonce.Partition // by login, see extranonce.go
+	seen       map[string]*int64               // unix nanoseconds last heard from, atomic, see alive.go
+	racing     map[string]<-chan struct{}      // the end of the race each miner has work for
 }
 
 type blockdata struct {
@@ -39,6 +78,12 @@ type blockdata struct {
 	blk    []byte // 80 byte block header partially filled
 	merk   []byte // merkle root skeleton - multiple of 32 bytes
 	bits   uint32 // for target computation
+	fees   uint64 // transaction fees, satoshis
+	job    uint64 // job id, see recent
+	issued time.Time
+	coinb1 []byte // upstream pool coinbase, if set, before the extranonce
+	coinb2 []byte // ... and after it
+	enlen  int    // extranonce bytes between them
 }
 
 type lockBlock struct {
@@ -52,23 +97,103 @@ type lockChan struct {
 	ch          chan struct{}
 }
 
+// raceStart is closed when the next race starts, or is closed already while
+// one is on. Announce and cancelRace replace it, read it under the lock
+func raceStart() <-chan struct{} {
+	run.Lock()
+	defer run.Unlock()
+	return run.ch
+}
+
+// raceStop releases the miners waiting in GetCancel when a race ends. Unlike
+// a WaitGroup it can be rearmed for the next race while they are still leaving
+type raceStop struct {
+	sync.Mutex
+	ch chan struct{} // closed when the race is over, nil before the first
+}
+
+func (s *raceStop) Add() {
+	s.Lock()
+	s.ch = make(chan struct{})
+	s.Unlock()
+}
+
+func (s *raceStop) Done() {
+	s.Lock()
+	safeclose(s.ch)
+	s.Unlock()
+}
+
+func (s *raceStop) Wait() {
+	s.Lock()
+	ch := s.ch
+	s.Unlock()
+	if ch != nil {
+		<-ch
+	}
+}
+
+// Stopped is closed when the race is over, for a select
+func (s *raceStop) Stopped() <-chan struct{} {
+	s.Lock()
+	defer s.Unlock()
+	if s.ch == nil {
+		ch := make(chan struct{})
+		close(ch)
+		return ch
+	}
+	return s.ch
+}
+
 var (
 	users      lockMap
 	block      lockBlock      // models the block information - basis of 'work'
 	run        lockChan       // channel that controls start of run
-	signIn     chan string    // for registering users in getwork
-	signOut    chan string    // for registering leaving users in getcancel
-	stop       sync.WaitGroup // control cancellation issue
+	stop       raceStop       // control cancellation issue
 	blockchan  chan blockdata // for incoming block
 	resultchan chan cpb.Win   // for the winner decision
 	serverID   string         // issued with block
+	quit       chan struct{}  // closed on shutdown
+	ids        *minerid.Registry
 )
 
-var mysql map[uint32]string
+var (
+	errShutdown   = errors.New("Server shutting down")
+	errStaleEpoch = errors.New("Stale conductor epoch, not the leader")
+	errCapacity   = status.Error(codes.ResourceExhausted, "Capacity reached!")
+	errChallenges = status.Error(codes.ResourceExhausted, "Too many login challenges outstanding")
+)
+
+var (
+	store accounts.UserStore // users and their keys
+	guard *accounts.Guard    // against replayed logins
+)
+
+// firstUsers are the users of the servers before the user store, with the
+// keys the client's cfile still carries. An empty store is seeded with them
+// on its first run, or no one could log in
+var firstUsers = map[uint32]string{1: "thekey", 2: "anotherthekey"}
+
+// seedUsers adds firstUsers to the store if it has no users yet. A server
+// sharing the store may be adding them too
+func seedUsers() {
+	list, err := store.List()
+	fatalF("failed to read user store", err)
+	if len(list) > 0 {
+		return
+	}
+	for id, key := range firstUsers {
+		if _, err := store.Create(id, key); err != nil && err != accounts.ErrExists {
+			fatalF("failed to seed user store", err)
+		}
+	}
+	log.Printf("user store %s was empty, added users 1 and 2 with their old keys, rotate them with coinctl users", *userStore)
+}
 
 func auth(login string, time string, userid uint32) (string, bool) {
-	key := mysql[userid]
-	if key == "" {
+	key, err := store.Key(userid)
+	if err != nil {
+		debugF("user %d: %v", userid, err)
 		return "", true
 	}
 	expected, err := coin.GenLogin(userid, key, time)
@@ -84,35 +209,118 @@ func auth(login string, time string, userid uint32) (string, bool) {
 
 // Login implements cpb.CoinServer
 func (s *server) Login(ctx context.Context, in *cpb.LoginRequest) (*cpb.LoginReply, error) { // HL
-	users.Lock()
-	defer users.Unlock()
-	nxtplus := users.countIN + 2
-	if nxtplus == *numMiners {
-		return nil, errors.New("Capacity reached!")
+	if err := refuse(banned.Banned(time.Now(), bans.User(in.User))); err != nil {
+		return nil, err
 	}
 	// authenticate user
+	if err := guard.Check(in.User, in.Time, time.Now()); err != nil {
+		return nil, err
+	}
 	login, nogood := auth(in.Name, in.Time, in.User)
 	if nogood {
+		misbehaved(ctx, "", bans.Auth) // the address only, see bans.go
+		return nil, errors.New("Authentication failure")
+	}
+	if err := guard.Use(in.User, login, in.Time, time.Now()); err != nil { // a replay
+		misbehaved(ctx, "", bans.Auth)
+		return nil, err
+	}
+	users.Lock()
+	defer users.Unlock()
+	if full() {
+		return nil, errCapacity
+	}
+	p, err := partitions.Assign(login)
+	if err != nil {
+		return nil, status.Error(codes.ResourceExhausted, err.Error())
+	}
+	id, err := ids.Assign(in.User, in.Device) // only for a login let in, saved with the round state
+	if err != nil {
+		partitions.Release(p)
+		return nil, err
+	}
+	admit(login, in.User, id, p)
+	now := time.Now().UnixNano()
+	users.seen[login] = &now
+	token, expires := sessions.Issue(login, in.User, time.Now())
+	return &cpb.LoginReply{Id: id, Token: token, Expires: expires.Unix()}, nil
+}
+
+// full reports whether the server is at its soft limit, users must be locked
+func full() bool {
+	return *numMiners > 0 && users.count >= *numMiners
+}
+
+// admit logs in miner login of user with its miner id and extranonce
+// partition, users must be locked
+func admit(login string, user, id uint32, p extranonce.Partition) {
+	if _, ok := users.loggedIn[login]; !ok {
+		users.count++
+	}
+	if old, ok := users.partitions[login]; ok && old != p { // logged in again
+		partitions.Release(old)
+	}
+	users.loggedIn[login] = user // HL
+	users.minerIDs[login] = id
+	users.partitions[login] = p
+	minersIn.Set(float64(users.count))
+	persist()
+}
+
+// dismiss logs out miner name, users must be locked
+func dismiss(name string) {
+	delete(users.loggedIn, name)
+	delete(users.minerIDs, name)
+	if p, ok := users.partitions[name]; ok {
+		partitions.Release(p)
+		delete(users.partitions, name)
+	}
+	delete(users.seen, name)
+	delete(users.racing, name)
+	diff.Forget(name)
+	sessions.End(name)
+	users.count--
+	minersIn.Set(float64(users.count))
+	persist()
+}
+
+// Challenge issues a login nonce to a user of the store : implements cpb.CoinServer
+func (s *server) Challenge(ctx context.Context, in *cpb.ChallengeRequest) (*cpb.ChallengeReply, error) {
+	if _, err := store.Key(in.User); err != nil {
+		debugF("challenge for user %d: %v", in.User, err)
+		misbehaved(ctx, "", bans.Auth) // the address only, as a failed login
 		return nil, errors.New("Authentication failure")
 	}
-	users.countIN++
-	users.loggedIn[login] = in.User    // HL
-	return &cpb.LoginReply{Id: 0}, nil // FIXME - we do not need to return any value, not used
+	nonce, flood := guard.Challenge(in.User, time.Now())
+	if flood {
+		misbehaved(ctx, "", bans.Flood)
+	}
+	if nonce == "" {
+		return nil, errChallenges
+	}
+	return &cpb.ChallengeReply{Nonce: nonce}, nil
 }
 
-// GetWork implements cpb.CoinServer, synchronises start of miners, hands out work
+// GetWork implements cpb.CoinServer, hands out work once a race is on. A
+// miner joining mid-round has its work at once
 func (s *server) GetWork(ctx context.Context, in *cpb.GetWorkRequest) (*cpb.GetWorkReply, error) {
 	debugF("Work request: %+v\n", in) // OMIT
-	signIn <- in.Name                 // HL
-	<-run.ch                          // HL
+	select {
+	case <-raceStart(): // HL
+	case <-quit:
+		return nil, errShutdown
+	}
+	entered(in.Name)
 	// customise work for this miner
 	work := setWork(in.Name)
 	return &cpb.GetWorkReply{Work: work}, nil
 }
 
-// this is needed because we no longer issue miner IDs - needed by steWork below
+// minerID is the id issued to the device logged in as name, needed by setWork below
 func minerID(name string) int {
-	return 1 // FIXME - this should be a unqiue ID assigned to miner for purposes of mining
+	users.RLock()
+	defer users.RUnlock()
+	return int(users.minerIDs[name])
 }
 
 func setWork(name string) *cpb.Work {
@@ -121,20 +329,57 @@ func setWork(name string) *cpb.Work {
 		return &cpb.Work{Coinbase: []byte{}, Block: []byte{}, Skel: []byte{}}
 	}
 	block.Lock()
-	minername := fmt.Sprintf("%d:%s", *index, name)
-	miner := minerID(name) // we return an ID attahed to this miner by name
-	upper := block.data.u
-	lower := block.data.l
-	blockHeight := block.data.height
-	partblock := block.data.blk
-	merkSkel := block.data.merk
-	bits := block.data.bits
+	data := block.data
+	block.Unlock()
 	// generate actual coinbase txn
-	coinbaseBytes, err := coin.GenCoinbase(upper, lower, blockHeight, miner, minername)
+	coinbaseBytes, err := minerCoinbase(name, data)
 	fatalF("failed to set block data", err)
-	block.Unlock()
 	// fmt.Printf("miner: %s\ncoinbase:\n%x\n", minername, coinbaseBytes)
-	return &cpb.Work{Coinbase: coinbaseBytes, Block: partblock, Skel: merkSkel, Bits: bits}
+	var prefix []byte
+	if p, ok := partition(name); ok {
+		prefix = p.Bytes()
+	}
+	return &cpb.Work{Coinbase: coinbaseBytes, Block: data.blk, Skel: data.merk, Bits: data.bits, Share: diff.Work(name, time.Now()),
+		Job: data.job, Issued: data.issued.Unix(), Prev: data.blk[4:36], Extranonce: prefix}
+}
+
+// minerCoinbase is the coinbase of the work for miner name on block data,
+// its extranonce the first of the miner's partition
+func minerCoinbase(name string, data blockdata) ([]byte, error) {
+	minername := fmt.Sprintf("%d:%s", *index, name)
+	miner := minerID(name) // we return an ID attahed to this miner by name
+	var cb []byte
+	var err error
+	if data.coinb1 != nil {
+		cb, err = upstreamCoinbase(data)
+	} else {
+		cb, err = coin.GenCoinbase(data.u, data.l, data.height, miner, minername)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return partitioned(name, cb, data)
+}
+
+// upstreamCoinbase is the upstream pool's coinbase with the extranonce left
+// to us zeroed. Unlike our own coinbase, it has no room for the miner's name
+// or id, the partition tells the miners apart
+func upstreamCoinbase(data blockdata) ([]byte, error) {
+	if data.enlen < 1 {
+		return nil, errors.New("no extranonce left for the miners")
+	}
+	cb := make([]byte, 0, len(data.coinb1)+data.enlen+len(data.coinb2))
+	cb = append(cb, data.coinb1...)
+	cb = append(cb, make([]byte, data.enlen)...)
+	return append(cb, data.coinb2...), nil
+}
+
+// extranonceAt locates the extranonce in cb, a miner's coinbase on data
+func extranonceAt(cb []byte, data blockdata) (start, end int, err error) {
+	if data.coinb1 != nil {
+		return len(data.coinb1), len(data.coinb1) + data.enlen, nil
+	}
+	return coin.Transaction(cb).Extranonce()
 }
 
 // Announce responds to a proposed solution : implements cpb.CoinServer
@@ -144,26 +389,60 @@ func (s *server) Announce(ctx context.Context, soln *cpb.AnnounceRequest) (*cpb.
 	defer run.Unlock()
 	if run.winnerFound { // reject all but the first
 		// fmt.Printf("PREV WINNER?\n")
-		return &cpb.AnnounceReply{Ok: false}, nil
+		announces.Inc("reject")
+		return &cpb.AnnounceReply{Ok: false, Reason: "Race over"}, nil
 	}
+	if soln.Win.Identity != "EXTERNAL" { // the conductor speaks for the network
+		if err := checkWin(soln.Win); err != nil {
+			if _, short := err.(shortWin); short { // a dice roll, as clients announced before proof of work
+				announces.Inc("short")
+			} else if isStale(err) {
+				announces.Inc("stale")
+				misbehaved(ctx, soln.Win.Identity, bans.Stale)
+			} else {
+				announces.Inc("invalid")
+				misbehaved(ctx, soln.Win.Identity, bans.Invalid)
+			}
+			fmt.Printf("refused win from %s: %v\n", soln.Win.Identity, err)
+			return &cpb.AnnounceReply{Ok: false, Reason: err.Error()}, nil
+		}
+		if soln.Win.Job != raceJob() {
+			fmt.Printf("late win on job %d\n", soln.Win.Job)
+			announces.Inc("late")
+		}
+		claimExtranonce(soln.Win)
+	}
+	announces.Inc("accept")
 	// we have a  winner
 	// fmt.Printf("NEW WINNER *** \n")
 
-	run.winnerFound = true  // HL
-	resultchan <- *soln.Win // HL
-
-	fmt.Println("starting signout numminers = ", *numMiners) // OMIT
-	WaitFor(signOut, "out")
-	run.ch = make(chan struct{}) // HL
-	stop.Done()                  // HL
+	run.winnerFound = true       // HL
+	run.ch = make(chan struct{}) // HL - GetWork waits for the next race
+	closeJob(true)
+	if soln.Win.Identity != "EXTERNAL" {
+		attributing.Add(1)
+		go attribute(soln.Win.Identity)
+	}
+	select {
+	case resultchan <- *soln.Win: // HL
+	case <-quit: // nobody to tell, just release the miners
+		stop.Done()
+		return nil, errShutdown
+	}
+	stop.Done() // HL
 	return &cpb.AnnounceReply{Ok: true}, nil
 }
 
 // GetCancel broadcasts a cancel instruction : implements cpb.CoinServer
 func (s *server) GetCancel(ctx context.Context, in *cpb.GetCancelRequest) (*cpb.GetCancelReply, error) {
 	// fmt.Println("CANCEL: ", in.Name)
-	signOut <- in.Name
-	stop.Wait()
+	users.RLock()
+	ended, ok := users.racing[in.Name]
+	users.RUnlock()
+	if !ok { // no work from this server yet
+		ended = stop.Stopped()
+	}
+	<-ended
 	return &cpb.GetCancelReply{Server: serverID}, nil
 }
 
@@ -172,56 +451,61 @@ type server struct{}
 
 // IssueBlock receives the new block from Conductor : implements cpb.CoinServer
 func (s *server) IssueBlock(ctx context.Context, in *cpb.IssueBlockRequest) (*cpb.IssueBlockReply, error) {
+	if len(in.Block) != 80 { // before the race is touched, a bad block leaves it be
+		return nil, errors.New("Block header must be 80 bytes")
+	}
+	accepted, newLeader := checkEpoch(in.Epoch)
+	if !accepted {
+		return nil, errStaleEpoch
+	}
+	endResumed()
+	if newLeader {
+		go abandonRace() // an Announce may hold the race until our GetResult
+	}
 	select { // in case we are holding previous block, discard it
 	case <-blockchan:
 	default:
 	}
-	blockchan <- blockdata{in.Lower, in.Upper, in.Blockheight, in.Block, in.Merkle, in.Bits}
+	// the upper template opens the coinbase, the lower closes it. They were
+	// once swapped, which left no coinbase input to find the extranonce by
+	data := blockdata{in.Upper, in.Lower, in.Blockheight, in.Block, in.Merkle, in.Bits, in.Fees, 0, time.Time{},
+		in.Coinb1, in.Coinb2, int(in.Extranonce)}
+	j := recent.Add(in.Block[4:36], data, time.Now())
+	data.job, data.issued = j.ID, j.Issued
+	blockchan <- data
 	serverID = in.Server
+	users.Lock()
 	users.loggedIn["EXTERNAL"] = 0 //1 // we login conductor here FIXME 0 is magic for external
+	users.Unlock()
 	// fmt.Printf("ISSUEBLOCK\n")
 	return &cpb.IssueBlockReply{Ok: true}, nil
 }
 
 // GetResult sends back win to Conductor : implements cpb.CoinServer
 func (s *server) GetResult(ctx context.Context, in *cpb.GetResultRequest) (*cpb.GetResultReply, error) {
-	result := <-resultchan // wait for a result
+	var result cpb.Win
+	select {
+	case result = <-resultchan: // wait for a result
+	case <-quit:
+		return nil, errShutdown
+	case <-ctx.Done(): // the conductor has gone, leave the result for its successor
+		return nil, ctx.Err()
+	}
 	//fmt.Printf("sendresult: %d, %v\n", *index, result) // OMIT
 	fmt.Printf("sendresult: %s\n", serverID) // OMIT
 	return &cpb.GetResultReply{Winner: &result, Server: serverID}, nil
 }
 
-// WaitFor allows for the loss of a miners
-func WaitFor(sign chan string, direction string) {
-	alive := make(map[string]bool) // HL
-	count := 1
-	// we need at least one!
-	alive[<-sign] = true
-	//... then the rest ...
-	stopWaiting := false
-	for i := 1; i < *numMiners; i++ {
-		select {
-		case <-time.After(allowedTime * time.Second):
-			stopWaiting = true //  exit, time is up
-		case c := <-sign:
-			alive[c] = true
-			count++
-		}
-		if stopWaiting { // the remaining miners are taking too long, abandon them
-			break
-		}
-	}
-	// done OMIT
-	if direction == "in" && count < *numMiners {
-		for name := range users.loggedIn {
-			if !alive[name] && name != "EXTERNAL" {
-				fmt.Printf("DEAD: %s\n", name)
-				delete(users.loggedIn, name)
-				users.countIN--
-			}
-		}
+// Logout removes a departing miner : implements cpb.CoinServer
+func (s *server) Logout(ctx context.Context, in *cpb.LogoutRequest) (*cpb.LogoutReply, error) {
+	users.Lock()
+	defer users.Unlock()
+	if _, ok := users.loggedIn[in.Name]; !ok || in.Name == "EXTERNAL" {
+		return &cpb.LogoutReply{Ok: false}, nil
 	}
-	fmt.Printf("miners %s = %d\n", direction, count)
+	dismiss(in.Name)
+	fmt.Printf("LOGOUT: %s\n", in.Name)
+	return &cpb.LogoutReply{Ok: true}, nil
 }
 
 // coinbase accepts data from work, result is tailored to miner
@@ -236,51 +520,110 @@ func WaitFor(sign chan string, direction string) {
 func main() {
 	flag.Parse() // HL
 
-	users.loggedIn = make(map[string]uint32)
-	users.countIN = -1
-	*numMiners++      // to include the Conductor (EXTERNAL)
 	if *index == -1 { // mandatory
 		log.Fatalf("%s", "Server port missing! use -index i, i=0,1, ...")
 	}
+	if *condKey == "" && *upstreamAddr == "" { // mandatory, unless a proxy
+		log.Fatalf("%s", "Conductor key missing! use -ckey key")
+	}
+	metrics.Serve(*metricsAddr)
 	port := fmt.Sprintf(":%d", 50051+*index) // HL
 	lis, err := net.Listen("tcp", port)
 	fatalF("failed to listen", err)
 
-	signIn = make(chan string, *numMiners)  // register incoming miners
-	signOut = make(chan string, *numMiners) // register miners receipt of cancel instructions
-	blockchan = make(chan blockdata, 1)     // transfer block data
-	run.ch = make(chan struct{})            // signal to start mining
-	resultchan = make(chan cpb.Win)         // transfer solution data
+	setup()
+	defer store.Close()
+	restoreState()
 
-	mysql = make(map[uint32]string)
-	mysql[1] = "thekey"
-	mysql[2] = "anotherthekey"
+	go runRaces()
+	go reapDead()
+	go keepState()
+	serveUpstream()
+	s := new(server)
+	serveStratum(s)
+	opts := append(transport(), grpc.UnaryInterceptor(unaryAuth), grpc.StreamInterceptor(streamAuth))
+	g := grpc.NewServer(opts...)
+	cpb.RegisterCoinServer(g, s)
+	cpb.RegisterAdminServer(g, new(admin))
+	drained := make(chan struct{})
+	go drainOnSignal(g, drained)
+	g.Serve(lis)
+	<-drained
+}
 
-	go func() {
+// setup readies the server's state from its flags and files, before a
+// round is restored or a miner served
+func setup() {
+	users.loggedIn = make(map[string]uint32)
+	users.minerIDs = make(map[string]uint32)
+	users.partitions = make(map[string]extranonce.Partition)
+	users.seen = make(map[string]*int64)
+	users.racing = make(map[string]<-chan struct{})
+	users.count = 0
+	partitions = extranonce.New(extranonce1Size)
+
+	blockchan = make(chan blockdata, 1) // transfer block data
+	run.ch = make(chan struct{})        // signal to start mining
+	run.winnerFound = true              // no race until the first block
+	resultchan = make(chan cpb.Win)     // transfer solution data
+	quit = make(chan struct{})          // closed on SIGINT/SIGTERM
+
+	var err error
+	book, err = shares.Load(*tallyFile)
+	fatalF("failed to load share tallies", err)
+	ids, err = minerid.Open(*idFile, minerid.Max, *perUser)
+	fatalF("failed to load miner ids", err)
+	openRewards()
+	diff = vardiff.New(*shareRate, uint32(*shareBits), 0x207fffff)
+	recent = jobs.NewWindow(*jobWindow, uint64(time.Now().Unix()))
+
+	store, err = accounts.Open(*userStore)
+	fatalF("failed to open user store", err)
+	seedUsers()
+	guard = accounts.NewGuard(*skew)
+	sessions = accounts.NewSessions(*sessionTTL)
+	banned = bans.New(*banScore, *banTime, scoreHalfLife)
+}
+
+// runRaces starts a race on each block issued, until shutdown
+func runRaces() {
+	for {
+		haveBlock := false
 		for {
-			haveBlock := false
-			for {
-				select {
-				case block.data = <-blockchan: // HL
-					haveBlock = true //break out of this loop
-				case <-time.After(allowedConductorTime * time.Second): // HL
+			select {
+			case data := <-blockchan: // HL
+				block.Lock()
+				block.data = data
+				block.Unlock()
+				haveBlock = true //break out of this loop
+			case <-time.After(allowedConductorTime * time.Second): // HL
+				if *upstreamAddr != "" {
+					fmt.Println("Need live upstream work!")
+				} else {
 					fmt.Println("Need a live conductor!")
 				}
-				if haveBlock {
-					break
-				}
+			case <-quit:
+				return
+			}
+			if haveBlock {
+				break
 			}
-			WaitFor(signIn, "in") // HL
-			fmt.Printf("\n--------------------\nNew race!\n")
-			run.winnerFound = false // HL
-			stop.Add(1)             // HL
-			safeclose(run.ch)       // HL
 		}
-	}()
-	s := new(server)
-	g := grpc.NewServer()
-	cpb.RegisterCoinServer(g, s)
-	g.Serve(lis)
+		// no waiting for the miners, they join the race as they come
+		run.Lock()
+		select {
+		case <-quit: // too late, we are draining
+			run.Unlock()
+			return
+		default:
+		}
+		fmt.Printf("\n--------------------\nNew race!\n")
+		run.winnerFound = false // HL
+		stop.Add()              // HL
+		safeclose(run.ch)       // HL
+		run.Unlock()
+		persist()
+	}
 }
 
 // utilities -----------------------------------------------------------------------------------
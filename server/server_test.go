@@ -0,0 +1,739 @@
+package main
+
+import (
+	"bytes"
+	"coin"
+	"flag"
+	"fmt"
+	"net"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"coin/accounts"
+	"coin/bans"
+	"coin/minerid"
+	cpb "coin/service"
+	"coin/state"
+	"coin/stratum"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var testUsers = map[uint32]string{1: "thekey", 2: "anotherthekey"}
+
+// testServer sets the server up as main does, its files in a temporary
+// directory and users 1 and 2 seeded in its store, and runs its races until
+// the test ends
+func testServer(t testing.TB) *server {
+	return startServer(t, t.TempDir(), "")
+}
+
+// stopServer stops the server startServer started last, as the test ending would
+var stopServer func()
+
+// startServer sets the server up as main does, its files in dir, restores
+// the round saved to stateFile if any, and runs its races until the test
+// ends or stopServer is called
+func startServer(t testing.TB, dir, stateFile string) *server {
+	for name, file := range map[string]string{"tally": "shares.json", "sharelog": "shares.log",
+		"statements": "statements.log", "ids": "minerids.json", "users": "users.json"} {
+		flag.Set(name, filepath.Join(dir, file))
+	}
+	flag.Set("state", stateFile)
+	flag.Set("index", "0")
+	flag.Set("ckey", "s3cret")
+	setup()
+	for id, key := range testUsers {
+		if k, err := store.Key(id); err != nil || k != key {
+			t.Fatalf("user %d not seeded: %q %v", id, k, err)
+		}
+	}
+	restoreState()
+	done := make(chan struct{})
+	go func() {
+		runRaces()
+		close(done)
+	}()
+	var once sync.Once
+	stopServer = func() {
+		once.Do(func() {
+			close(quit)
+			<-done
+			attributing.Wait()
+			proxying.Wait()
+			store.Close()
+		})
+	}
+	t.Cleanup(stopServer)
+	return new(server)
+}
+
+func TestSeedUsers(t *testing.T) {
+	testServer(t) // seeded on the first run
+	path := filepath.Join(t.TempDir(), "users.json")
+	other := accounts.OpenFile(path)
+	if _, err := other.Create(7, "sevenkey"); err != nil {
+		t.Fatal(err)
+	}
+	store.Close()
+	store = other
+	flag.Set("users", path)
+	seedUsers()
+	if list, _ := other.List(); len(list) != 1 {
+		t.Errorf("a store with users was seeded: %+v", list)
+	}
+}
+
+// login logs user in from device, returning the login
+func login(t testing.TB, s *server, user uint32, device string) string {
+	now := fmt.Sprintf("%x", uint32(time.Now().Unix()))
+	name, err := coin.GenLogin(user, testUsers[user], now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Login(context.Background(), &cpb.LoginRequest{Name: name, User: user, Time: now, Device: device}); err != nil {
+		t.Fatalf("login of user %d: %v", user, err)
+	}
+	return name
+}
+
+// challengeLogin logs user in from device with a challenge, as the client
+// does, returning the login
+func challengeLogin(t testing.TB, s *server, user uint32, device string) string {
+	ctx := context.Background()
+	ch, err := s.Challenge(ctx, &cpb.ChallengeRequest{User: user})
+	if err != nil {
+		t.Fatal(err)
+	}
+	name, err := coin.GenLogin(user, testUsers[user], ch.Nonce)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Login(ctx, &cpb.LoginRequest{Name: name, User: user, Time: ch.Nonce, Device: device}); err != nil {
+		t.Fatalf("login of user %d from %s: %v", user, device, err)
+	}
+	return name
+}
+
+// Challenges go only to users in the store, and a flood of them from one
+// address gets it banned
+func TestChallengeFlood(t *testing.T) {
+	s := testServer(t)
+	addr := serveGRPC(t, s)
+	conn, _ := dial(t, addr, accounts.SessionHeader, "")
+	c := cpb.NewCoinClient(conn)
+	ctx := context.Background()
+	if _, err := c.Challenge(ctx, &cpb.ChallengeRequest{User: 99}); err == nil {
+		t.Error("expected a challenge for an unknown user to be refused")
+	}
+	if got := banned.Score(bans.IP("127.0.0.1"), time.Now()); got < 19 {
+		t.Errorf("an unknown user's challenge scored %.1f", got)
+	}
+	banned.Unban(bans.IP("127.0.0.1"), time.Now())
+	for i := 0; i < 128; i++ { // as many as a user may have outstanding
+		if _, err := c.Challenge(ctx, &cpb.ChallengeRequest{User: 2}); err != nil {
+			t.Fatalf("challenge %d: %v", i, err)
+		}
+	}
+	if got := banned.Score(bans.IP("127.0.0.1"), time.Now()); got != 0 {
+		t.Errorf("challenges within the cap scored %.1f", got)
+	}
+	var err error
+	for i := 0; i < 100 && err == nil; i++ {
+		_, err = c.Challenge(ctx, &cpb.ChallengeRequest{User: 2})
+	}
+	if status.Code(err) != codes.PermissionDenied {
+		t.Errorf("expected a flood of challenges to be banned, got %v", err)
+	}
+}
+
+// testBlock is a block as the conductor issues it, on target bits
+func testBlock(t testing.TB, bits uint32) *cpb.IssueBlockRequest {
+	upper, lower, err := coin.CoinbaseTemplates(433789, 8756123, "0225c141d69b74adac8ab984a8eb9fee42c4ce79cf6cb2be166b1ddc0356b37086")
+	if err != nil {
+		t.Fatal(err)
+	}
+	header, err := coin.BlockHeader(2, "000000000000000117c80378b8da0e33559b5997f2ad55e2f7d18ec1975b9717", 0x53058b35, int(bits))
+	if err != nil {
+		t.Fatal(err)
+	}
+	skel, err := coin.Skeleton([]string{"91c5e9f288437262f218c60f986e8bc10fb35ab3b9f6de477ff0eb554da89dea"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &cpb.IssueBlockRequest{Upper: upper, Lower: lower, Block: header, Merkle: skel, Blockheight: 433789,
+		Bits: bits, Server: "localhost:50051", Fees: 8756123}
+}
+
+// issue issues block as the conductor would and waits for its race to start
+func issue(t testing.TB, s *server, in *cpb.IssueBlockRequest) {
+	run.Lock()
+	started := run.ch
+	run.Unlock()
+	if _, err := s.IssueBlock(context.Background(), in); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no race started")
+	}
+}
+
+// mine searches work as the client does, returning the first nonce whose
+// hash meets target bits, with the header in work.Block
+func mine(t testing.TB, work *cpb.Work, bits uint32) uint32 {
+	header := coin.Block(work.Block) // the client writes into the work too
+	root, err := coin.CoinbaseMerkle(work.Coinbase, work.Skel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header.AddMerkle(root)
+	target := coin.Bits2Target(bits)
+	for nonce := uint32(0); nonce < 1<<16; nonce++ {
+		header.PutNonce(nonce)
+		if hash, err := header.Hash(); err == nil && coin.MeetsTarget(hash, target) {
+			return nonce
+		}
+	}
+	t.Fatalf("no nonce meets %08x", bits)
+	return 0
+}
+
+func TestClientWin(t *testing.T) {
+	s := testServer(t)
+	name := login(t, s, 2, "pi")
+	issue(t, s, testBlock(t, 0x200fffff))
+	work := setWork(name)
+	nonce := mine(t, work, work.Bits)
+	win := &cpb.Win{Block: work.Block, Nonce: nonce, Identity: name, Job: work.Job}
+	if err := checkWin(win); err != nil {
+		t.Fatalf("client win refused: %v", err)
+	}
+	for miss := uint32(0); ; miss++ { // a nonce the client would not announce
+		header := coin.Block(append([]byte{}, work.Block...))
+		header.PutNonce(miss)
+		if hash, _ := header.Hash(); !coin.MeetsTarget(hash, coin.Bits2Target(work.Bits)) {
+			win.Nonce = miss
+			break
+		}
+	}
+	if err := checkWin(win); err == nil {
+		t.Fatal("expected a win short of the target to be refused")
+	}
+}
+
+func TestCoinbaseTemplates(t *testing.T) {
+	s := testServer(t)
+	name := login(t, s, 2, "pi")
+	in := testBlock(t, 0x207fffff)
+	issue(t, s, in)
+	cb := setWork(name).Coinbase
+	if !bytes.HasPrefix(cb, in.Upper) || !bytes.HasSuffix(cb, in.Lower) {
+		t.Fatalf("coinbase is not upper + data + lower: %x", cb)
+	}
+	start, end, err := coin.Transaction(cb).Extranonce()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, _ := partition(name)
+	if !p.Contains(cb[start:end]) {
+		t.Errorf("extranonce %x outside the miner's partition %v", cb[start:end], p)
+	}
+	id, err := coin.CoinbaseMinerID(cb)
+	if err != nil || id != users.minerIDs[name] {
+		t.Errorf("miner id %d, %v, expected %d", id, err, users.minerIDs[name])
+	}
+}
+
+// A malformed block, even from a new leader, leaves the race as it was
+func TestIssueBadBlock(t *testing.T) {
+	s := testServer(t)
+	in := testBlock(t, 0x1d00ffff)
+	issue(t, s, in)
+	job := raceJob()
+	bad := testBlock(t, 0x1d00ffff)
+	bad.Block, bad.Epoch = bad.Block[:79], 5
+	if _, err := s.IssueBlock(context.Background(), bad); err == nil {
+		t.Fatal("expected a 79 byte header to be refused")
+	}
+	time.Sleep(10 * time.Millisecond) // an abandonRace would be under way
+	run.Lock()
+	over := run.winnerFound
+	run.Unlock()
+	leader.Lock()
+	epoch := leader.epoch
+	leader.Unlock()
+	if over || epoch != 0 || raceJob() != job {
+		t.Errorf("race over %v, epoch %d, job %d after a bad block", over, epoch, raceJob())
+	}
+}
+
+// stratumShare rolls the nonce of job until its header meets the session's
+// difficulty, as mining software would
+func stratumShare(t testing.TB, c *stratum.Client, j *stratum.Job, en2 []byte, ntime uint32) stratum.Submit {
+	target := stratum.Target(j.Difficulty)
+	for nonce := uint32(0); nonce < 1<<16; nonce++ {
+		header, _, err := j.Header(c.Extranonce1, en2, ntime, nonce)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hash, _ := coin.Block(header).Hash(); coin.MeetsTarget(hash, target) {
+			return stratum.Submit{Worker: "2.rig", JobID: j.ID, Extranonce2: en2, Time: ntime, Nonce: nonce}
+		}
+	}
+	t.Fatal("no share found")
+	return stratum.Submit{}
+}
+
+func TestStratum(t *testing.T) {
+	s := testServer(t)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go newStratum(s).Serve(l)
+	issue(t, s, testBlock(t, 0x1d00ffff)) // no share is a block
+
+	c, err := stratum.Dial(l.Addr().String(), "test/1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if len(c.Extranonce1) != extranonce1Size || c.Extranonce2Size != extranonce2Size {
+		t.Fatalf("subscribed with extranonce1 %x and extranonce2 size %d", c.Extranonce1, c.Extranonce2Size)
+	}
+	if err := c.Authorize("2.rig", "thekey"); err == nil {
+		t.Fatal("expected a wrong key to be refused")
+	}
+	if err := c.Authorize("2.rig", testUsers[2]); err != nil {
+		t.Fatal(err)
+	}
+	var j *stratum.Job
+	select {
+	case j = <-c.Jobs():
+	case <-time.After(5 * time.Second):
+		t.Fatal("no job notified")
+	}
+	if j.Bits != 0x1d00ffff || !j.Clean {
+		t.Errorf("job %+v", j)
+	}
+
+	sub := stratumShare(t, c, j, []byte{0x0a, 0x0b}, j.Time)
+	if err := c.Submit(sub); err != nil {
+		t.Fatalf("share refused: %v", err)
+	}
+	if got := book.User(2).Accepted; got != 1 {
+		t.Errorf("expected 1 accepted share for user 2, got %d", got)
+	}
+	if err := c.Submit(sub); err == nil || err.(*stratum.Error).Code != 22 {
+		t.Errorf("duplicate: expected error 22, got %v", err)
+	}
+	if err := c.Submit(stratumShare(t, c, j, []byte{0x0a, 0x0b}, j.Time+90)); err != nil {
+		t.Errorf("ntime rolled 90s: %v", err)
+	}
+	if err := c.Submit(stratumShare(t, c, j, []byte{0x0a, 0x0b}, j.Time+7200)); err == nil {
+		t.Error("expected ntime rolled 2h to be refused")
+	}
+	sub.JobID = "2e"
+	if err := c.Submit(sub); err == nil || err.(*stratum.Error).Code != 21 {
+		t.Errorf("unknown job: expected error 21, got %v", err)
+	}
+}
+
+// A restarted server takes up the round it saved: its miners with their
+// sessions, ids and partitions, and the race with its work
+func TestRestoreState(t *testing.T) {
+	dir := t.TempDir()
+	saved := filepath.Join(dir, "state.json")
+	s := startServer(t, dir, saved)
+	name := login(t, s, 2, "pi")
+	issue(t, s, testBlock(t, 0x1d00ffff))
+	id, p := users.minerIDs[name], users.partitions[name]
+	token, _, _ := sessions.Token(name)
+	job, work := raceJob(), setWork(name)
+	saveState()
+	stopServer()
+
+	s = startServer(t, dir, saved)
+	if users.loggedIn[name] != 2 || users.minerIDs[name] != id || users.partitions[name] != p {
+		t.Fatalf("miner %s restored as user %d id %d partition %v", name, users.loggedIn[name], users.minerIDs[name], users.partitions[name])
+	}
+	if ss, err := sessions.Check(token, time.Now()); err != nil || ss.Name != name {
+		t.Errorf("session not restored: %+v %v", ss, err)
+	}
+	select {
+	case <-raceStart():
+	default:
+		t.Fatal("race not reopened")
+	}
+	if raceJob() != job {
+		t.Errorf("restored job %d, expected %d", raceJob(), job)
+	}
+	again := setWork(name)
+	if !bytes.Equal(again.Coinbase, work.Coinbase) || !bytes.Equal(again.Block, work.Block) || again.Job != job {
+		t.Fatal("the restored work differs from the saved")
+	}
+	header := coin.Block(again.Block)
+	header.PutNonce(mine(t, again, diff.Current(name)))
+	r, err := s.SubmitShare(context.Background(), &cpb.SubmitShareRequest{Name: name, Block: header, Job: again.Job})
+	if err != nil || !r.Ok {
+		t.Errorf("share on the restored work refused: %v %v", r, err)
+	}
+}
+
+// serveGRPC serves s and the admin service as main does, through the
+// interceptors, until the test ends, returning the address
+func serveGRPC(t testing.TB, s *server) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := grpc.NewServer(grpc.UnaryInterceptor(unaryAuth), grpc.StreamInterceptor(streamAuth))
+	cpb.RegisterCoinServer(g, s)
+	cpb.RegisterAdminServer(g, new(admin))
+	go g.Serve(l)
+	t.Cleanup(g.Stop)
+	return l.Addr().String()
+}
+
+// dial connects to addr, each call carrying header set to key, or a session
+// token once Set if header is the session header
+func dial(t testing.TB, addr, header, key string) (*grpc.ClientConn, *accounts.Credentials) {
+	creds := accounts.NewCredentials(header, key)
+	conn, err := grpc.Dial(addr, grpc.WithInsecure(), grpc.WithPerRPCCredentials(creds))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn, creds
+}
+
+// remoteLogin logs user in with key and a challenge, as the client does over
+// c, keeping the session token in session
+func remoteLogin(c cpb.CoinClient, session *accounts.Credentials, user uint32, key, device string) (string, error) {
+	ctx := context.Background()
+	ch, err := c.Challenge(ctx, &cpb.ChallengeRequest{User: user})
+	if err != nil {
+		return "", err
+	}
+	name, err := coin.GenLogin(user, key, ch.Nonce)
+	if err != nil {
+		return "", err
+	}
+	r, err := c.Login(ctx, &cpb.LoginRequest{Name: name, User: user, Time: ch.Nonce, Device: device})
+	if err != nil {
+		return "", err
+	}
+	session.Set(r.Token)
+	return name, nil
+}
+
+// An operator's Ban, RotateKey and Drain pass the interceptor with the admin
+// key, and take effect
+func TestAdmin(t *testing.T) {
+	s := testServer(t)
+	flag.Set("akey", "adm1n")
+	defer flag.Set("akey", "")
+	addr := serveGRPC(t, s)
+	ctx := context.Background()
+
+	conn, _ := dial(t, addr, accounts.AdminHeader, "wrong")
+	if _, err := cpb.NewAdminClient(conn).Bans(ctx, &cpb.BansRequest{}); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("bad admin key: expected Unauthenticated, got %v", err)
+	}
+	conn, _ = dial(t, addr, accounts.AdminHeader, "adm1n")
+	a := cpb.NewAdminClient(conn)
+	miner, session := dial(t, addr, accounts.SessionHeader, "")
+	c := cpb.NewCoinClient(miner)
+	name, err := remoteLogin(c, session, 2, testUsers[2], "pi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.GetTally(ctx, &cpb.GetTallyRequest{Name: name}); err != nil {
+		t.Fatalf("before the ban: %v", err)
+	}
+
+	if r, err := a.Ban(ctx, &cpb.BanRequest{Key: "miner:" + name, Seconds: 60}); err != nil || !r.Ok {
+		t.Fatalf("ban: %v %v", r, err)
+	}
+	if _, err := c.GetTally(ctx, &cpb.GetTallyRequest{Name: name}); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("a banned miner's call: expected PermissionDenied, got %v", err)
+	}
+	if _, err := remoteLogin(c, session, 2, testUsers[2], "pi"); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("a banned user's login: expected PermissionDenied, got %v", err)
+	}
+
+	r, err := a.RotateKey(ctx, &cpb.UserRequest{Id: 1})
+	if err != nil || r.User.Key == "" || r.User.Key == testUsers[1] {
+		t.Fatalf("rotate: %v %v", r, err)
+	}
+	if _, err := remoteLogin(c, session, 1, testUsers[1], "pi"); err == nil {
+		t.Error("expected the old key to be refused")
+	}
+	if _, err := remoteLogin(c, session, 1, r.User.Key, "pi"); err != nil {
+		t.Errorf("login with the new key: %v", err)
+	}
+
+	if r, err := a.Drain(ctx, &cpb.DrainRequest{}); err != nil || !r.Ok {
+		t.Fatalf("drain: %v %v", r, err)
+	}
+	select {
+	case why := <-drainNow:
+		if why != "admin" {
+			t.Errorf("drained by %q", why)
+		}
+	default:
+		t.Error("drain not asked for")
+	}
+}
+
+// fakeUpstream is an upstream server as a proxy sees it: it lets the proxy
+// in, pushes work down its MineStream and hands on what the proxy relays
+type fakeUpstream struct {
+	cpb.CoinServer // nothing else is called
+	work           *cpb.Work
+	relayed        chan *cpb.MinerMessage
+}
+
+func (u *fakeUpstream) Challenge(ctx context.Context, in *cpb.ChallengeRequest) (*cpb.ChallengeReply, error) {
+	return &cpb.ChallengeReply{Nonce: "0123456789abcdef"}, nil
+}
+
+func (u *fakeUpstream) Login(ctx context.Context, in *cpb.LoginRequest) (*cpb.LoginReply, error) {
+	return &cpb.LoginReply{Id: 9, Token: "t0ken"}, nil
+}
+
+func (u *fakeUpstream) MineStream(stream cpb.Coin_MineStreamServer) error {
+	if err := stream.Send(&cpb.ServerMessage{Kind: "work", Work: u.work}); err != nil {
+		return err
+	}
+	for {
+		m, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+		if m.Kind != "heartbeat" {
+			u.relayed <- m
+		}
+	}
+}
+
+// A proxy races its miners on the upstream's work and relays a share that
+// meets the upstream's target, as the upstream can check it
+func TestProxyRelaysShare(t *testing.T) {
+	in := testBlock(t, 0x1d00ffff) // no share is a win
+	cb, err := coin.GenCoinbase(in.Upper, in.Lower, in.Blockheight, 9, "0:proxy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start, end, err := coin.Transaction(cb).Extranonce()
+	if err != nil {
+		t.Fatal(err)
+	}
+	prefix := []byte{0x00, 0x05} // the proxy's partition upstream
+	copy(cb[start:], prefix)
+	up := &fakeUpstream{relayed: make(chan *cpb.MinerMessage, 16),
+		work: &cpb.Work{Coinbase: cb, Block: in.Block, Skel: in.Merkle, Bits: in.Bits, Share: 0x207fffff, Job: 41, Extranonce: prefix}}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := grpc.NewServer()
+	cpb.RegisterCoinServer(g, up)
+	go g.Serve(l)
+	t.Cleanup(g.Stop)
+	t.Cleanup(func() { flag.Set("upstream", "") }) // once the proxy has stopped
+
+	s := testServer(t)
+	flag.Set("upstream", l.Addr().String())
+	flag.Set("uuser", "3")
+	flag.Set("ukey", "k3y")
+	started := raceStart()
+	serveUpstream()
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no race on the upstream's work")
+	}
+
+	name := login(t, s, 2, "pi")
+	work := setWork(name)
+	header := coin.Block(work.Block)
+	header.PutNonce(mine(t, work, diff.Current(name)))
+	r, err := s.SubmitShare(context.Background(), &cpb.SubmitShareRequest{Name: name, Block: header, Job: work.Job})
+	if err != nil || !r.Ok {
+		t.Fatalf("share refused: %v %v", r, err)
+	}
+	var m *cpb.MinerMessage
+	select {
+	case m = <-up.relayed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no share relayed")
+	}
+	if m.Kind != "share" || m.Job != 41 || !bytes.HasPrefix(m.Extranonce, prefix) || len(m.Extranonce) != end-start {
+		t.Fatalf("relayed %s on job %d with extranonce %x", m.Kind, m.Job, m.Extranonce)
+	}
+	relayed := append(append(append([]byte{}, cb[:start]...), m.Extranonce...), cb[end:]...)
+	root, err := coin.CoinbaseMerkle(relayed, in.Merkle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(coin.Block(m.Block)[36:68], root) {
+		t.Error("the relayed share is not on the upstream's coinbase with its extranonce")
+	}
+}
+
+// conduct takes the results of the races as the conductor's GetResult would,
+// Announce holds on until one is taken
+func conduct(t testing.TB) {
+	results, done := resultchan, quit
+	go func() {
+		for {
+			select {
+			case <-results:
+			case <-done:
+				return
+			}
+		}
+	}()
+}
+
+// A client mining as it should, its shares late or short now and then, is
+// never banned however many rounds it plays
+func TestHonestMinerNotBanned(t *testing.T) {
+	s := testServer(t)
+	conduct(t)
+	ctx := context.Background()
+	name := login(t, s, 2, "pi")
+	for round := 0; round < 3*int(*banScore/25); round++ {
+		issue(t, s, testBlock(t, 0x200fffff))
+		work := setWork(name)
+		header := coin.Block(work.Block)
+		root, err := coin.CoinbaseMerkle(work.Coinbase, work.Skel)
+		if err != nil {
+			t.Fatal(err)
+		}
+		header.AddMerkle(root)
+		target := coin.Bits2Target(work.Bits)
+		shorted := false // a client on dice alone announced a nonce short of the target
+		for nonce := uint32(0); ; nonce++ {
+			header.PutNonce(nonce)
+			hash, _ := header.Hash()
+			if coin.MeetsTarget(hash, coin.Bits2Target(diff.Current(name))) {
+				if _, err := s.SubmitShare(ctx, &cpb.SubmitShareRequest{Name: name, Block: header, Job: work.Job}); err != nil {
+					t.Fatalf("round %d: %v", round, err)
+				}
+			}
+			if !coin.MeetsTarget(hash, target) {
+				if !shorted {
+					r, _ := s.Announce(ctx, &cpb.AnnounceRequest{Win: &cpb.Win{Block: header, Nonce: nonce, Identity: name, Job: work.Job}})
+					if r == nil || r.Ok {
+						t.Fatalf("round %d: expected a win short of the target to be refused", round)
+					}
+					shorted = true
+				}
+				continue
+			}
+			r, err := s.Announce(ctx, &cpb.AnnounceRequest{Win: &cpb.Win{Block: work.Block, Nonce: nonce, Identity: name, Job: work.Job}})
+			if err != nil || !r.Ok {
+				t.Fatalf("round %d: win refused %v %v", round, r, err)
+			}
+			break
+		}
+		late := make(coin.Block, 80) // a share found as the race ended
+		copy(late, header)
+		s.SubmitShare(ctx, &cpb.SubmitShareRequest{Name: name, Block: late, Job: work.Job})
+	}
+	if b, ok := banned.Banned(time.Now(), bans.User(2)); ok {
+		t.Fatalf("honest miner banned: %+v", b)
+	}
+}
+
+// manyDevices lifts the cap on each user's devices until the test ends
+func manyDevices(t testing.TB) {
+	saved := *perUser
+	*perUser = 0
+	t.Cleanup(func() { *perUser = saved })
+}
+
+// Thousands of sessions are admitted without a save each, their miner ids
+// and the round state all saved once at the end
+func TestAdmitMany(t *testing.T) {
+	manyDevices(t)
+	s := testServer(t)
+	flag.Set("state", filepath.Join(t.TempDir(), "state.json"))
+	defer flag.Set("state", "")
+	const n = 5000
+	logins := make(map[string]uint32, n)
+	start := time.Now()
+	for i := 0; i < n; i++ {
+		name := challengeLogin(t, s, uint32(1+i%2), fmt.Sprintf("rig-%d", i))
+		logins[name] = users.minerIDs[name]
+	}
+	t.Logf("%d logins in %v", n, time.Since(start))
+	if users.count != n {
+		t.Fatalf("expected %d miners in, got %d", n, users.count)
+	}
+	saveState() // as shutdown does
+
+	r, err := state.Load(*stateFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Miners) != n {
+		t.Fatalf("expected %d miners saved, got %d", n, len(r.Miners))
+	}
+	reg, err := minerid.Open(*idFile, minerid.Max, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range r.Miners {
+		if m.ID != logins[m.Name] {
+			t.Fatalf("miner %s saved with id %d, issued %d", m.Name, m.ID, logins[m.Name])
+		}
+		if o, ok := reg.Lookup(m.ID); !ok || o.User != m.User {
+			t.Fatalf("miner id %d of %s not saved: %+v %v", m.ID, m.Name, o, ok)
+		}
+	}
+}
+
+func TestDeviceCap(t *testing.T) {
+	s := testServer(t)
+	ctx := context.Background()
+	for i := 0; i < *perUser; i++ {
+		challengeLogin(t, s, 2, fmt.Sprintf("rig-%d", i))
+	}
+	ch, _ := s.Challenge(ctx, &cpb.ChallengeRequest{User: 2})
+	name, _ := coin.GenLogin(2, testUsers[2], ch.Nonce)
+	if _, err := s.Login(ctx, &cpb.LoginRequest{Name: name, User: 2, Time: ch.Nonce, Device: "one-more"}); err != minerid.ErrDevices {
+		t.Errorf("expected a device past the cap to be refused, got %v", err)
+	}
+	challengeLogin(t, s, 2, "rig-0") // a known device still logs in
+	challengeLogin(t, s, 1, "rig-0")
+
+	flag.Set("miners", fmt.Sprint(users.count)) // full, a login refused takes no id
+	defer flag.Set("miners", "0")
+	ch, _ = s.Challenge(ctx, &cpb.ChallengeRequest{User: 1})
+	name, _ = coin.GenLogin(1, testUsers[1], ch.Nonce)
+	if _, err := s.Login(ctx, &cpb.LoginRequest{Name: name, User: 1, Time: ch.Nonce, Device: "refused"}); err != errCapacity {
+		t.Fatalf("expected capacity reached, got %v", err)
+	}
+	if _, ok := ids.IDs["1/refused"]; ok {
+		t.Error("the refused login took a miner id")
+	}
+}
+
+func BenchmarkLogin(b *testing.B) {
+	manyDevices(b)
+	s := testServer(b)
+	for i := 0; i < b.N; i++ {
+		challengeLogin(b, s, 2, fmt.Sprintf("rig-%d", i))
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"coin"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"coin/bans"
+	"coin/jobs"
+	cpb "coin/service"
+	"coin/shares"
+	"coin/vardiff"
+
+	"golang.org/x/net/context"
+)
+
+// Shares =================================================
+
+var (
+	recent *jobs.Window   // the jobs work may be submitted on
+	book   *shares.Book   // tallies, loaded in main and saved as each race ends
+	diff   *vardiff.Table // each miner's share target
+)
+
+var (
+	errNotLoggedIn = errors.New("Not logged in")
+	errLowShare    = errors.New("share does not meet its target")
+)
+
+// raceJob is the job being raced for
+func raceJob() uint64 {
+	block.Lock()
+	defer block.Unlock()
+	return block.data.job
+}
+
+// closeJob ends the race and saves the tallies, and the round state soon
+// after. If its block was found, work on its job and on older jobs for the
+// same block becomes stale
+func closeJob(found bool) {
+	if found {
+		recent.Solved(raceJob())
+	}
+	saveTally()
+	persist()
+}
+
+func saveTally() {
+	if err := book.Save(*tallyFile); err != nil {
+		log.Printf("could not save share tallies: %v", err)
+	}
+}
+
+// checkShare judges header submitted by miner name on job id, with the
+// extranonce it rolled if any, against the share target bits. The error of a
+// stale or invalid share says why
+func checkShare(name string, header coin.Block, extranonce []byte, id uint64, bits uint32) (shares.Result, error) {
+	j, err := recent.Find(id)
+	if err != nil {
+		return shares.Stale, err
+	}
+	hash, err := checkWork(name, header, j.Data.(blockdata), extranonce)
+	if err != nil {
+		return shares.Invalid, err
+	}
+	if !coin.MeetsTarget(hash, coin.Bits2Target(bits)) {
+		return shares.Invalid, errLowShare
+	}
+	if !recent.Once(id, fmt.Sprintf("%x", hash)) {
+		return shares.Duplicate, nil
+	}
+	return shares.Accepted, nil
+}
+
+// SubmitShare validates and counts a share : implements cpb.CoinServer
+func (s *server) SubmitShare(ctx context.Context, in *cpb.SubmitShareRequest) (*cpb.SubmitShareReply, error) {
+	users.RLock()
+	user, ok := users.loggedIn[in.Name]
+	id := users.minerIDs[in.Name]
+	users.RUnlock()
+	if !ok || in.Name == "EXTERNAL" {
+		return nil, errNotLoggedIn
+	}
+	if err := refuseBanned(ctx, in.Name); err != nil { // streams and stratum sessions outlive a ban
+		return nil, err
+	}
+	bits := diff.Current(in.Name)
+	r, why := checkShare(in.Name, coin.Block(in.Block), in.Extranonce, in.Job, bits)
+	book.Add(id, user, r)
+	if o, ok := shareOffences[r]; ok {
+		if why == errLowShare { // work at the old target, just after vardiff raised it
+			o = bans.Stale
+		}
+		misbehaved(ctx, in.Name, o)
+	}
+	if r == shares.Accepted {
+		diff.Share(in.Name, time.Now())
+		logShare(in.Name, id, user, bits)
+		relayShare(in.Name, coin.Block(in.Block), in.Extranonce, in.Job)
+	}
+	sharesTotal.Inc(string(r))
+	debugF("share from %s: %s\n", in.Name, r)
+	reply := &cpb.SubmitShareReply{Ok: r == shares.Accepted, Result: string(r)}
+	if why != nil {
+		reply.Reason = why.Error()
+	}
+	return reply, nil
+}
+
+// GetTally reports the share counts of a miner and its user, or of a user : implements cpb.CoinServer
+func (s *server) GetTally(ctx context.Context, in *cpb.GetTallyRequest) (*cpb.GetTallyReply, error) {
+	reply := &cpb.GetTallyReply{}
+	user := in.User
+	if in.Name != "" {
+		users.RLock()
+		id, ok := users.loggedIn[in.Name]
+		miner := users.minerIDs[in.Name]
+		users.RUnlock()
+		if ok {
+			user = id
+		}
+		reply.Miner = tallyReply(book.Miner(miner))
+	}
+	reply.User = tallyReply(book.User(user))
+	return reply, nil
+}
+
+// shareOffences scores each verdict but Accepted
+var shareOffences = map[shares.Result]bans.Offence{shares.Invalid: bans.Invalid, shares.Duplicate: bans.Duplicate, shares.Stale: bans.Stale}
+
+func tallyReply(t shares.Tally) *cpb.Tally {
+	return &cpb.Tally{Accepted: t.Accepted, Stale: t.Stale, Duplicate: t.Duplicate, Invalid: t.Invalid}
+}
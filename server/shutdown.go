@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// drainNow asks for a drain as a signal would, see admin.go
+var drainNow = make(chan string, 1)
+
+// drainOnSignal waits for SIGINT, SIGTERM or drainNow then cancels the
+// miners, releases every blocked RPC and stops g, forcibly if the -grace
+// deadline passes
+func drainOnSignal(g *grpc.Server, drained chan struct{}) {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	var why interface{}
+	select {
+	case why = <-sigs:
+	case why = <-drainNow:
+	}
+	fmt.Printf("%v: draining (at most %v) ...\n", why, *grace)
+	deadline := time.After(*grace)
+
+	close(quit) // no new races, blocked RPCs return
+	run.Lock()
+	if !run.winnerFound {
+		run.winnerFound = true
+		closeJob(false)
+		stop.Done() // HL
+	}
+	run.Unlock()
+
+	stopped := make(chan struct{})
+	go func() {
+		g.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		fmt.Println("drained")
+	case <-deadline:
+		fmt.Println("grace period over, stopping")
+		g.Stop()
+	}
+	attributing.Wait()
+	proxying.Wait()
+	saveTally() // shares judged since the race ended
+	saveState() // miners may resume their sessions after a restart
+	close(drained)
+}
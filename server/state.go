@@ -0,0 +1,195 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"coin/accounts"
+	"coin/state"
+)
+
+// Round state ============================================
+
+// The round is saved to -state as it changes, the changes of -stateflush
+// together: the job being raced for, the miners logged in with their
+// sessions, miner ids and partitions, and the conductor's epoch, with the
+// share tallies saved alongside and the new miner ids before it. A server
+// restarted after a crash restores it before serving and reopens the race if
+// one was on, so its miners carry on with their sessions and their work.
+// Stratum miners lose their connections and are not kept
+
+var (
+	stateFile  = flag.String("state", "state.json", "round state, saved as it changes and restored on restart, none if empty")
+	stateEvery = flag.Duration("statesave", 10*time.Second, "interval the share tallies and round state are saved at, besides each change")
+	stateFlush = flag.Duration("stateflush", time.Second, "how long changes to the round state gather before they are saved together")
+)
+
+var (
+	stateChanged = make(chan struct{}, 1)
+	saving       sync.Mutex // one save at a time, the latest last
+)
+
+// resumed is 1 while a race restored from the round state is open. The
+// conductor lost track of it when we went down, its next block ends it
+var resumed int32
+
+// persist has the round state saved soon, without waiting for it
+func persist() {
+	select {
+	case stateChanged <- struct{}{}:
+	default: // a save is due already
+	}
+}
+
+// keepState saves the round state -stateflush after it changes, and the
+// tallies with it every -statesave, until shutdown saves them a last time
+func keepState() {
+	tick := time.NewTicker(*stateEvery)
+	defer tick.Stop()
+	var due <-chan time.Time // a save, once changes have gathered
+	for {
+		select {
+		case <-stateChanged:
+			if due == nil {
+				due = time.After(*stateFlush)
+			}
+			continue
+		case <-due:
+		case <-tick.C:
+			saveTally()
+		case <-quit:
+			return
+		}
+		due = nil
+		saveState()
+	}
+}
+
+// saveState saves the miner ids issued since the last save, then the round
+// state, which names them
+func saveState() {
+	if err := ids.Flush(); err != nil {
+		log.Printf("could not save miner ids: %v", err)
+	}
+	if *stateFile == "" {
+		return
+	}
+	saving.Lock()
+	defer saving.Unlock()
+	if err := snapshot(time.Now()).Save(*stateFile); err != nil {
+		log.Printf("could not save round state: %v", err)
+	}
+}
+
+// snapshot is the round as it stands. Miners without a session, the
+// conductor and Stratum miners, are left out
+func snapshot(now time.Time) *state.Round {
+	r := &state.Round{Saved: now, Server: serverID}
+	leader.Lock()
+	r.Epoch = leader.epoch
+	leader.Unlock()
+	block.Lock()
+	data := block.data
+	block.Unlock()
+	if data.blk != nil {
+		r.Job = &state.Job{ID: data.job, Issued: data.issued, Upper: data.u, Lower: data.l, Height: data.height,
+			Block: data.blk, Merkle: data.merk, Bits: data.bits, Fees: data.fees,
+			Coinb1: data.coinb1, Coinb2: data.coinb2, Extranonce: data.enlen}
+		select {
+		case <-stop.Stopped():
+		default:
+			r.Racing = true
+		}
+	}
+	users.RLock()
+	defer users.RUnlock()
+	for name, user := range users.loggedIn {
+		token, expires, ok := sessions.Token(name)
+		if !ok {
+			continue
+		}
+		r.Miners = append(r.Miners, state.Miner{Name: name, User: user, ID: users.minerIDs[name],
+			Partition: users.partitions[name], Token: token, Expires: expires})
+	}
+	return r
+}
+
+// restoreState takes up the round saved by an earlier run, if any, before
+// we serve. Miners have -alive to come back before they are dropped
+func restoreState() {
+	if *stateFile == "" {
+		return
+	}
+	r, err := state.Load(*stateFile)
+	fatalF("failed to load round state", err)
+	if r.Saved.IsZero() {
+		return
+	}
+	now := time.Now()
+	serverID = r.Server
+	leader.epoch = r.Epoch
+	users.Lock()
+	for _, m := range r.Miners {
+		if now.After(m.Expires) {
+			continue
+		}
+		if err := partitions.Claim(m.Partition, m.Name); err != nil {
+			log.Printf("could not restore miner %s: %v", m.Name, err)
+			continue
+		}
+		admit(m.Name, m.User, m.ID, m.Partition)
+		seen := now.UnixNano()
+		users.seen[m.Name] = &seen
+		sessions.Resume(m.Token, accounts.Session{Name: m.Name, User: m.User, Expires: m.Expires})
+	}
+	restored := users.count
+	users.Unlock()
+	fmt.Printf("RESTORED: %d miners, saved %s\n", restored, r.Saved.Format(time.Stamp))
+	if r.Job == nil {
+		return
+	}
+	j := r.Job
+	data := blockdata{j.Upper, j.Lower, j.Height, j.Block, j.Merkle, j.Bits, j.Fees, 0, time.Time{},
+		j.Coinb1, j.Coinb2, j.Extranonce}
+	recent.Resume(j.ID, j.Block[4:36], data, j.Issued)
+	data.job, data.issued = j.ID, j.Issued
+	block.Lock()
+	block.data = data
+	block.Unlock()
+	if !r.Racing {
+		return
+	}
+	fmt.Printf("RESTORED: race for job %d\n", j.ID)
+	atomic.StoreInt32(&resumed, 1)
+	run.Lock()
+	run.winnerFound = false
+	stop.Add()
+	safeclose(run.ch)
+	run.Unlock()
+}
+
+// endResumed ends a race restored from the round state, when the conductor
+// issues its next block. A win held in it is too late, the conductor has
+// moved on, and must not pass for a win in the next race
+func endResumed() {
+	if !atomic.CompareAndSwapInt32(&resumed, 1, 0) {
+		return
+	}
+	ended := make(chan struct{})
+	go func() {
+		abandonRace()
+		close(ended)
+	}()
+	for {
+		select {
+		case w := <-resultchan: // an Announce holding the race
+			fmt.Printf("win from %s in the restored race came too late\n", w.Identity)
+		case <-ended:
+			return
+		}
+	}
+}
@@ -0,0 +1,297 @@
+package main
+
+import (
+	"coin"
+	"encoding/binary"
+	"flag"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"coin/accounts"
+	"coin/bans"
+	"coin/extranonce"
+	cpb "coin/service"
+	"coin/shares"
+	"coin/stratum"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// Stratum ================================================
+
+// Standard mining software speaks Stratum v1. Its miners log in with
+// mining.authorize as USER[.DEVICE] and their user's key as the password,
+// and race as the miner USER[.DEVICE]#SESSION. Their coinbase is the one
+// GetWork would give them, its 4 byte extranonce split between the session's
+// extranonce1, its partition, and the miner's extranonce2
+
+var stratumAddr = flag.String("stratum", "", "address to serve Stratum v1 on, eg :3333, none if empty")
+
+const (
+	extranonce1Size = 2 // the session's partition, see extranonce.go
+	extranonce2Size = 2 // rolled by the miner
+)
+
+// stratumMiner is the miner racing for a Stratum session
+type stratumMiner struct {
+	name   string
+	cancel context.CancelFunc
+}
+
+// stratumPool implements stratum.Handler on the server's races and jobs
+type stratumPool struct {
+	sync.Mutex
+	s          *server
+	miners     map[uint64]*stratumMiner        // by session
+	partitions map[uint64]extranonce.Partition // by session, from mining.subscribe
+}
+
+// serveStratum serves Stratum on *stratumAddr, if it is set
+func serveStratum(s *server) {
+	if *stratumAddr == "" {
+		return
+	}
+	lis, err := net.Listen("tcp", *stratumAddr)
+	fatalF("failed to listen for stratum", err)
+	srv := newStratum(s)
+	go func() {
+		<-quit
+		lis.Close()
+	}()
+	fmt.Printf("stratum on %s\n", lis.Addr())
+	go srv.Serve(lis)
+}
+
+// newStratum is a Stratum server on the races and jobs of s
+func newStratum(s *server) *stratum.Server {
+	pool := &stratumPool{s: s, miners: make(map[uint64]*stratumMiner), partitions: make(map[uint64]extranonce.Partition)}
+	return &stratum.Server{Handler: pool, Extranonce1Size: extranonce1Size, Extranonce2Size: extranonce2Size,
+		Extranonce1: pool.extranonce1}
+}
+
+func (p *stratumPool) miner(ss *stratum.Session) *stratumMiner {
+	p.Lock()
+	defer p.Unlock()
+	return p.miners[ss.ID]
+}
+
+// extranonce1 gives a subscribing session its partition, held in the
+// session's name until it authorizes
+func (p *stratumPool) extranonce1(ss *stratum.Session) ([]byte, error) {
+	part, err := partitions.Assign(fmt.Sprintf("stratum#%d", ss.ID))
+	if err != nil {
+		return nil, err
+	}
+	p.Lock()
+	p.partitions[ss.ID] = part
+	p.Unlock()
+	return part.Bytes(), nil
+}
+
+// Authorize logs in the worker USER[.DEVICE] if password is its user's key
+func (p *stratumPool) Authorize(ss *stratum.Session, worker, password string) error {
+	userName, device := worker, "stratum"
+	if i := strings.Index(worker, "."); i >= 0 {
+		userName, device = worker[:i], worker[i+1:]
+	}
+	ctx := peerContext(ss)
+	if err := refuseBanned(ctx, ""); err != nil {
+		return &stratum.Error{Code: 24, Message: status.Convert(err).Message()}
+	}
+	user, err := strconv.ParseUint(userName, 10, 32)
+	if err != nil {
+		misbehaved(ctx, "", bans.Auth)
+		return stratum.ErrUnauthorized
+	}
+	key, err := store.Key(uint32(user))
+	if err != nil || !accounts.SameKey(password, key) {
+		debugF("stratum worker %s: refused", worker)
+		misbehaved(ctx, "", bans.Auth)
+		return stratum.ErrUnauthorized
+	}
+	if err := refuse(banned.Banned(time.Now(), bans.User(uint32(user)))); err != nil {
+		return &stratum.Error{Code: 24, Message: status.Convert(err).Message()}
+	}
+	name := fmt.Sprintf("%s#%d", worker, ss.ID)
+	p.Lock()
+	part := p.partitions[ss.ID]
+	p.Unlock()
+	users.Lock()
+	if full() {
+		users.Unlock()
+		return &stratum.Error{Code: 20, Message: "Capacity reached!"}
+	}
+	id, err := ids.Assign(uint32(user), device) // only for a worker let in
+	if err != nil {
+		users.Unlock()
+		return &stratum.Error{Code: 20, Message: err.Error()}
+	}
+	partitions.Rename(part, name)
+	admit(name, uint32(user), id, part)
+	users.Unlock()
+	ctx, cancel := context.WithCancel(ctx)
+	p.Lock()
+	p.miners[ss.ID] = &stratumMiner{name: name, cancel: cancel}
+	delete(p.partitions, ss.ID) // the miner's now, see dismiss
+	p.Unlock()
+	fmt.Printf("STRATUM: %s, extranonce %s\n", name, part)
+	go p.race(ctx, ss, name)
+	return nil
+}
+
+// race enters the session's miner in each race, notifying it of the work.
+// The cancellation needs no message, the next job is clean
+func (p *stratumPool) race(ctx context.Context, ss *stratum.Session, name string) {
+	out := make(chan *cpb.ServerMessage, 2)
+	go raceFor(ctx, name, out)
+	var bits uint32
+	for {
+		select {
+		case m := <-out:
+			if m.Kind != "work" {
+				continue
+			}
+			j, err := recent.Find(m.Work.Job)
+			if err != nil { // superseded already, the next race has work
+				continue
+			}
+			job, err := stratumJob(name, j.ID, j.Data.(blockdata))
+			if err != nil {
+				debugF("stratum job for %s: %v", name, err)
+				continue
+			}
+			if m.Work.Share != bits {
+				bits = m.Work.Share
+				ss.SetDifficulty(coin.Difficulty(coin.Bits2Target(bits)))
+			}
+			ss.Notify(job)
+		case <-ctx.Done():
+			return
+		case <-ss.Done():
+			return
+		}
+	}
+}
+
+// stratumJob is job id with data as miner name works on it: its coinbase
+// either side of the extranonce and the merkle branch of the skeleton
+func stratumJob(name string, id uint64, data blockdata) (*stratum.Job, error) {
+	cb, err := minerCoinbase(name, data)
+	if err != nil {
+		return nil, err
+	}
+	start, end, err := extranonceAt(cb, data)
+	if err != nil {
+		return nil, err
+	}
+	if end-start != extranonce1Size+extranonce2Size {
+		return nil, fmt.Errorf("coinbase extranonce of %d bytes, stratum needs %d", end-start, extranonce1Size+extranonce2Size)
+	}
+	job := &stratum.Job{
+		ID:      strconv.FormatUint(id, 16),
+		Prev:    data.blk[4:36],
+		Coinb1:  cb[:start],
+		Coinb2:  cb[end:],
+		Version: binary.LittleEndian.Uint32(data.blk[0:4]),
+		Time:    binary.LittleEndian.Uint32(data.blk[68:72]),
+		Bits:    binary.LittleEndian.Uint32(data.blk[72:76]),
+		Clean:   true,
+	}
+	for i := 0; i+32 <= len(data.merk); i += 32 {
+		job.Branch = append(job.Branch, data.merk[i:i+32])
+	}
+	return job, nil
+}
+
+// Submit counts a share as SubmitShare would, and announces it if it is a block
+func (p *stratumPool) Submit(ss *stratum.Session, sub stratum.Submit) error {
+	m := p.miner(ss)
+	if m == nil {
+		return stratum.ErrUnauthorized
+	}
+	id, err := strconv.ParseUint(sub.JobID, 16, 64)
+	if err != nil {
+		return stratum.ErrJobNotFound
+	}
+	j, err := recent.Find(id)
+	if err != nil {
+		return &stratum.Error{Code: 21, Message: err.Error()}
+	}
+	data := j.Data.(blockdata)
+	job, err := stratumJob(m.name, id, data)
+	if err != nil {
+		return &stratum.Error{Code: 20, Message: err.Error()}
+	}
+	header, _, err := job.Header(ss.Extranonce1, sub.Extranonce2, sub.Time, sub.Nonce)
+	if err != nil {
+		return &stratum.Error{Code: 20, Message: err.Error()}
+	}
+	extranonce := append(append([]byte{}, ss.Extranonce1...), sub.Extranonce2...)
+	ctx := peerContext(ss)
+	before := diff.Current(m.name)
+	r, err := p.s.SubmitShare(ctx, &cpb.SubmitShareRequest{Name: m.name, Block: header, Job: id, Extranonce: extranonce})
+	if err != nil { // dropped as DEAD, or banned
+		ss.Close()
+		return stratum.ErrUnauthorized
+	}
+	switch shares.Result(r.Result) {
+	case shares.Accepted:
+	case shares.Stale:
+		return &stratum.Error{Code: 21, Message: r.Reason}
+	case shares.Duplicate:
+		return stratum.ErrDuplicate
+	case shares.Invalid:
+		if r.Reason == errLowShare.Error() {
+			return stratum.ErrLowDiff
+		}
+		return &stratum.Error{Code: 20, Message: r.Reason}
+	}
+	if hash, err := coin.Block(header).Hash(); err == nil && coin.MeetsTarget(hash, coin.Bits2Target(data.bits)) {
+		win := &cpb.Win{Block: header, Nonce: sub.Nonce, Identity: m.name, Job: id, Extranonce: extranonce}
+		go func() { // Announce holds on until the race is over
+			r, err := p.s.Announce(ctx, &cpb.AnnounceRequest{Win: win})
+			if err == nil && !r.Ok {
+				debugF("stratum win from %s refused: %s", m.name, r.Reason)
+			}
+		}()
+	}
+	if bits := diff.Work(m.name, time.Now()); bits != before {
+		ss.SetDifficulty(coin.Difficulty(coin.Bits2Target(bits)))
+	}
+	return nil
+}
+
+// peerContext carries the session's address, as gRPC's does, for scoring
+func peerContext(ss *stratum.Session) context.Context {
+	return peer.NewContext(context.Background(), &peer.Peer{Addr: ss.RemoteAddr()})
+}
+
+// Closed logs out the session's miner, or gives up the partition of a
+// session that never authorized
+func (p *stratumPool) Closed(ss *stratum.Session) {
+	p.Lock()
+	m := p.miners[ss.ID]
+	delete(p.miners, ss.ID)
+	part, unclaimed := p.partitions[ss.ID]
+	delete(p.partitions, ss.ID)
+	p.Unlock()
+	if unclaimed {
+		partitions.Release(part)
+	}
+	if m == nil {
+		return
+	}
+	m.cancel()
+	users.Lock()
+	defer users.Unlock()
+	if _, ok := users.loggedIn[m.name]; ok {
+		dismiss(m.name)
+	}
+	fmt.Printf("STRATUM LOGOUT: %s\n", m.name)
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"io"
+	"time"
+
+	cpb "coin/service"
+
+	"golang.org/x/net/context"
+)
+
+// Streams ================================================
+
+// MineStream carries everything a miner and the server say to each other
+// after Login : implements cpb.CoinServer. The miner is the one the session
+// authenticated, it takes part in the races as its GetWork and GetCancel
+// calls would
+func (s *server) MineStream(stream cpb.Coin_MineStreamServer) error {
+	ctx := stream.Context()
+	name := callerOf(ctx)
+	users.RLock()
+	_, ok := users.loggedIn[name]
+	users.RUnlock()
+	if !ok || name == "EXTERNAL" {
+		return errNotLoggedIn
+	}
+	out := make(chan *cpb.ServerMessage, 16) // the only sender is below
+	go raceFor(ctx, name, out)
+	received := make(chan error, 1)
+	go func() {
+		received <- receive(ctx, s, name, stream, out)
+	}()
+	for {
+		select {
+		case m := <-out:
+			if err := stream.Send(m); err != nil {
+				return err
+			}
+		case err := <-received:
+			if err == io.EOF { // the miner closed the stream
+				return nil
+			}
+			return err
+		case <-quit:
+			return errShutdown
+		}
+	}
+}
+
+// push queues m for the miner unless its stream has ended
+func push(ctx context.Context, out chan *cpb.ServerMessage, m *cpb.ServerMessage) bool {
+	select {
+	case out <- m:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// raceFor enters miner name in each race, pushing it the work when the race
+// starts, or at once if one is on, and a cancellation when it ends
+func raceFor(ctx context.Context, name string, out chan *cpb.ServerMessage) {
+	for {
+		select {
+		case <-raceStart():
+		case <-quit:
+			return
+		case <-ctx.Done():
+			return
+		}
+		ended := entered(name)
+		if !push(ctx, out, &cpb.ServerMessage{Kind: "work", Work: setWork(name)}) {
+			return
+		}
+		select {
+		case <-ended:
+		case <-ctx.Done():
+			return
+		}
+		if !push(ctx, out, &cpb.ServerMessage{Kind: "cancel", Server: serverID}) {
+			return
+		}
+	}
+}
+
+// receive handles the shares, wins and heartbeats of miner name until its
+// stream ends. Each accepted share may retarget the miner's difficulty
+func receive(ctx context.Context, s *server, name string, stream cpb.Coin_MineStreamServer, out chan *cpb.ServerMessage) error {
+	for {
+		m, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+		seen(name)
+		switch m.Kind {
+		case "share":
+			before := diff.Current(name)
+			r, err := s.SubmitShare(ctx, &cpb.SubmitShareRequest{Name: name, Block: m.Block, Job: m.Job, Extranonce: m.Extranonce})
+			if err != nil {
+				return err
+			}
+			push(ctx, out, &cpb.ServerMessage{Kind: "share", Share: r})
+			if bits := diff.Work(name, time.Now()); bits != before {
+				push(ctx, out, &cpb.ServerMessage{Kind: "difficulty", Bits: bits})
+			}
+		case "win":
+			if m.Win == nil || m.Win.Identity != name {
+				push(ctx, out, &cpb.ServerMessage{Kind: "win", Win: &cpb.AnnounceReply{Reason: "Not your win"}})
+				continue
+			}
+			go func(win *cpb.Win) { // Announce holds on until the race is over
+				r, err := s.Announce(ctx, &cpb.AnnounceRequest{Win: win})
+				if err != nil {
+					r = &cpb.AnnounceReply{Reason: err.Error()}
+				}
+				push(ctx, out, &cpb.ServerMessage{Kind: "win", Win: r})
+			}(m.Win)
+		case "heartbeat":
+			push(ctx, out, &cpb.ServerMessage{Kind: "heartbeat"})
+		default:
+			debugF("%s sent an unknown %q message", name, m.Kind)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"coin/certs"
+	"errors"
+	"flag"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+var (
+	tlsCert  = flag.String("tlscert", "", "certificate file, serves TLS with -tlskey (default plaintext)")
+	tlsKey   = flag.String("tlskey", "", "private key file of -tlscert")
+	caFile   = flag.String("ca", "", "CA bundle the conductor's client certificate must be signed by")
+	condMTLS = flag.Bool("cmtls", false, "require the conductor to present a client certificate signed by -ca")
+)
+
+// transport is the server option for TLS, none for plaintext
+func transport() []grpc.ServerOption {
+	if *tlsCert == "" {
+		if *condMTLS {
+			fatalF("-cmtls", errors.New("needs TLS, use -tlscert and -tlskey"))
+		}
+		return nil
+	}
+	if *condMTLS && *caFile == "" {
+		fatalF("-cmtls", errors.New("needs the CA bundle, use -ca"))
+	}
+	// miners have no certificates, so they are optional on the link and
+	// required of the conductor call by call, see conductorCert
+	config, err := certs.ServerConfig(*tlsCert, *tlsKey, *caFile, false)
+	fatalF("failed to load TLS certificate", err)
+	return []grpc.ServerOption{grpc.Creds(credentials.NewTLS(config))}
+}
+
+// conductorCert reports whether the caller with ctx presented a client
+// certificate signed by -ca, or it need not have with -cmtls unset
+func conductorCert(ctx context.Context) bool {
+	if !*condMTLS {
+		return true
+	}
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return false
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	return ok && len(info.State.VerifiedChains) > 0
+}
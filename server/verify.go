@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"coin"
+	"encoding/binary"
+	"errors"
+	"flag"
+	"fmt"
+	"time"
+
+	"coin/jobs"
+	cpb "coin/service"
+)
+
+// Proof of work ==========================================
+
+var ntimeRoll = flag.Duration("ntime", 10*time.Minute, "how far from its job's time a header's may be, Stratum miners roll it")
+
+// claimHeader rebuilds the full 80 byte header claimed by win, nonce in place
+func claimHeader(win *cpb.Win) (coin.Block, error) {
+	if len(win.Block) != 80 {
+		return nil, fmt.Errorf("claim carries %d bytes, not a blockheader", len(win.Block))
+	}
+	header := make(coin.Block, 80) // never write into the message
+	copy(header, win.Block)
+	header.PutNonce(win.Nonce)
+	return header, nil
+}
+
+// checkWork verifies that header is the work we gave miner name on the job
+// with data: the job's version, previous block and bits, its time give or
+// take -ntime, and the merkle root of the miner's own coinbase, with
+// extranonce in place if the miner rolled it within its partition. It
+// returns the hash of the header
+func checkWork(name string, header coin.Block, data blockdata, extranonce []byte) ([]byte, error) {
+	if len(header) != 80 {
+		return nil, fmt.Errorf("%d bytes is not a blockheader", len(header))
+	}
+	if !bytes.Equal(header[:36], data.blk[:36]) {
+		return nil, errors.New("wrong version or previous block")
+	}
+	if !bytes.Equal(header[72:76], data.blk[72:76]) {
+		return nil, errors.New("wrong bits")
+	}
+	if err := checkTime(header, data); err != nil {
+		return nil, err
+	}
+	cb, err := minerCoinbase(name, data)
+	if err != nil {
+		return nil, err
+	}
+	if len(extranonce) > 0 {
+		start, end, err := extranonceAt(cb, data)
+		if err != nil {
+			return nil, err
+		}
+		if len(extranonce) != end-start {
+			return nil, fmt.Errorf("extranonce of %d bytes, not %d", len(extranonce), end-start)
+		}
+		if err := inPartition(name, extranonce); err != nil {
+			return nil, err
+		}
+		copy(cb[start:end], extranonce)
+	}
+	root, err := coin.CoinbaseMerkle(cb, data.merk)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(header[36:68], root) {
+		return nil, errors.New("merkle root is not of the coinbase issued to " + name)
+	}
+	return header.Hash()
+}
+
+// checkWin verifies that the block claimed by win solves its job
+func checkWin(win *cpb.Win) error {
+	j, err := recent.Find(win.Job)
+	if err != nil {
+		return err
+	}
+	header, err := claimHeader(win)
+	if err != nil {
+		return err
+	}
+	data := j.Data.(blockdata)
+	hash, err := checkWork(win.Identity, header, data, win.Extranonce)
+	if err != nil {
+		return err
+	}
+	if !coin.MeetsTarget(hash, coin.Bits2Target(data.bits)) {
+		return shortWin(hash)
+	}
+	return nil
+}
+
+// shortWin is the error of a win on our work whose hash misses the target
+type shortWin []byte
+
+func (e shortWin) Error() string {
+	return fmt.Sprintf("block %x does not meet the target", []byte(e))
+}
+
+// checkTime checks that the time of header is within -ntime of its job's,
+// Stratum miners roll it
+func checkTime(header coin.Block, data blockdata) error {
+	rolled := int64(binary.LittleEndian.Uint32(header[68:72])) - int64(binary.LittleEndian.Uint32(data.blk[68:72]))
+	if rolled < 0 {
+		rolled = -rolled
+	}
+	if time.Duration(rolled)*time.Second > *ntimeRoll {
+		return fmt.Errorf("time %ds from the job's", rolled)
+	}
+	return nil
+}
+
+// claimExtranonce fills in the extranonce of a verified win on an upstream
+// pool's job, the pool needs it with the share. Only Stratum miners send it
+func claimExtranonce(win *cpb.Win) {
+	if len(win.Extranonce) > 0 {
+		return
+	}
+	j, err := recent.Find(win.Job)
+	if err != nil {
+		return
+	}
+	data := j.Data.(blockdata)
+	if data.coinb1 == nil {
+		return
+	}
+	cb, err := minerCoinbase(win.Identity, data)
+	if err != nil {
+		return
+	}
+	start, end, _ := extranonceAt(cb, data)
+	win.Extranonce = cb[start:end]
+}
+
+// isStale tells work on a stale job from bad work
+func isStale(err error) bool {
+	_, stale := err.(jobs.StaleError)
+	return stale || err == jobs.ErrUnknown
+}
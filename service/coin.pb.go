@@ -10,19 +10,54 @@ It is generated from these files:
 
 It has these top-level messages:
 	LoginRequest
+	ChallengeRequest
 	GetWorkRequest
 	AnnounceRequest
 	GetCancelRequest
 	IssueBlockRequest
 	GetResultRequest
+	LogoutRequest
+	SubmitShareRequest
+	GetTallyRequest
+	HeartbeatRequest
 	LoginReply
+	ChallengeReply
 	GetWorkReply
 	AnnounceReply
 	GetCancelReply
 	IssueBlockReply
 	GetResultReply
+	LogoutReply
 	Work
 	Win
+	SubmitShareReply
+	GetTallyReply
+	MinerMessage
+	ServerMessage
+	HeartbeatReply
+	Tally
+	BanRequest
+	BanReply
+	BansRequest
+	BansReply
+	Ban
+	MinersRequest
+	MinersReply
+	Miner
+	RoundRequest
+	RoundReply
+	UsersRequest
+	UsersReply
+	UserRequest
+	UserReply
+	User
+	DrainRequest
+	DrainReply
+	ServersRequest
+	ServersReply
+	ServerStatus
+	TemplateRequest
+	TemplateReply
 */
 package cpb
 
@@ -48,9 +83,10 @@ const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
 
 // The Login request message containing the user's name.
 type LoginRequest struct {
-	Name string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
-	Time string `protobuf:"bytes,2,opt,name=time" json:"time,omitempty"`
-	User uint32 `protobuf:"varint,3,opt,name=user" json:"user,omitempty"`
+	Name   string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+	Time   string `protobuf:"bytes,2,opt,name=time" json:"time,omitempty"`
+	User   uint32 `protobuf:"varint,3,opt,name=user" json:"user,omitempty"`
+	Device string `protobuf:"bytes,4,opt,name=device" json:"device,omitempty"`
 }
 
 func (m *LoginRequest) Reset()                    { *m = LoginRequest{} }
@@ -58,6 +94,16 @@ func (m *LoginRequest) String() string            { return proto.CompactTextStri
 func (*LoginRequest) ProtoMessage()               {}
 func (*LoginRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{0} }
 
+// Challenge request carries the user about to log in
+type ChallengeRequest struct {
+	User uint32 `protobuf:"varint,1,opt,name=user" json:"user,omitempty"`
+}
+
+func (m *ChallengeRequest) Reset()                    { *m = ChallengeRequest{} }
+func (m *ChallengeRequest) String() string            { return proto.CompactTextString(m) }
+func (*ChallengeRequest) ProtoMessage()               {}
+func (*ChallengeRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+
 // GetWork request carries the same name as login
 type GetWorkRequest struct {
 	Name string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
@@ -66,7 +112,7 @@ type GetWorkRequest struct {
 func (m *GetWorkRequest) Reset()                    { *m = GetWorkRequest{} }
 func (m *GetWorkRequest) String() string            { return proto.CompactTextString(m) }
 func (*GetWorkRequest) ProtoMessage()               {}
-func (*GetWorkRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+func (*GetWorkRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{2} }
 
 // Announce request is a Win struct - coinbase + nonce
 type AnnounceRequest struct {
@@ -76,7 +122,7 @@ type AnnounceRequest struct {
 func (m *AnnounceRequest) Reset()                    { *m = AnnounceRequest{} }
 func (m *AnnounceRequest) String() string            { return proto.CompactTextString(m) }
 func (*AnnounceRequest) ProtoMessage()               {}
-func (*AnnounceRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{2} }
+func (*AnnounceRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{3} }
 
 func (m *AnnounceRequest) GetWin() *Win {
 	if m != nil {
@@ -93,7 +139,7 @@ type GetCancelRequest struct {
 func (m *GetCancelRequest) Reset()                    { *m = GetCancelRequest{} }
 func (m *GetCancelRequest) String() string            { return proto.CompactTextString(m) }
 func (*GetCancelRequest) ProtoMessage()               {}
-func (*GetCancelRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{3} }
+func (*GetCancelRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
 
 // IssueBlock requests carries the string block
 type IssueBlockRequest struct {
@@ -104,12 +150,17 @@ type IssueBlockRequest struct {
 	Merkle      []byte `protobuf:"bytes,5,opt,name=merkle,proto3" json:"merkle,omitempty"`
 	Bits        uint32 `protobuf:"varint,6,opt,name=bits" json:"bits,omitempty"`
 	Server      string `protobuf:"bytes,7,opt,name=server" json:"server,omitempty"`
+	Epoch       uint64 `protobuf:"varint,8,opt,name=epoch" json:"epoch,omitempty"`
+	Fees        uint64 `protobuf:"varint,9,opt,name=fees" json:"fees,omitempty"`
+	Coinb1      []byte `protobuf:"bytes,10,opt,name=coinb1,proto3" json:"coinb1,omitempty"`
+	Coinb2      []byte `protobuf:"bytes,11,opt,name=coinb2,proto3" json:"coinb2,omitempty"`
+	Extranonce  uint32 `protobuf:"varint,12,opt,name=extranonce" json:"extranonce,omitempty"`
 }
 
 func (m *IssueBlockRequest) Reset()                    { *m = IssueBlockRequest{} }
 func (m *IssueBlockRequest) String() string            { return proto.CompactTextString(m) }
 func (*IssueBlockRequest) ProtoMessage()               {}
-func (*IssueBlockRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
+func (*IssueBlockRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{5} }
 
 // GetResult requests carries the same name as login
 type GetResultRequest struct {
@@ -119,17 +170,73 @@ type GetResultRequest struct {
 func (m *GetResultRequest) Reset()                    { *m = GetResultRequest{} }
 func (m *GetResultRequest) String() string            { return proto.CompactTextString(m) }
 func (*GetResultRequest) ProtoMessage()               {}
-func (*GetResultRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{5} }
+func (*GetResultRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{6} }
+
+// Logout request carries the same name as login
+type LogoutRequest struct {
+	Name string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+}
+
+func (m *LogoutRequest) Reset()                    { *m = LogoutRequest{} }
+func (m *LogoutRequest) String() string            { return proto.CompactTextString(m) }
+func (*LogoutRequest) ProtoMessage()               {}
+func (*LogoutRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{7} }
+
+// SubmitShare request carries the same name as login and the full header
+type SubmitShareRequest struct {
+	Name       string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+	Block      []byte `protobuf:"bytes,2,opt,name=block,proto3" json:"block,omitempty"`
+	Job        uint64 `protobuf:"varint,3,opt,name=job" json:"job,omitempty"`
+	Extranonce []byte `protobuf:"bytes,4,opt,name=extranonce,proto3" json:"extranonce,omitempty"`
+}
+
+func (m *SubmitShareRequest) Reset()                    { *m = SubmitShareRequest{} }
+func (m *SubmitShareRequest) String() string            { return proto.CompactTextString(m) }
+func (*SubmitShareRequest) ProtoMessage()               {}
+func (*SubmitShareRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{8} }
+
+// GetTally request names a miner (login) or a user, the miner wins if both are set
+type GetTallyRequest struct {
+	Name string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+	User uint32 `protobuf:"varint,2,opt,name=user" json:"user,omitempty"`
+}
+
+func (m *GetTallyRequest) Reset()                    { *m = GetTallyRequest{} }
+func (m *GetTallyRequest) String() string            { return proto.CompactTextString(m) }
+func (*GetTallyRequest) ProtoMessage()               {}
+func (*GetTallyRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{9} }
+
+// Heartbeat request carries the same name as login
+type HeartbeatRequest struct {
+	Name string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+}
+
+func (m *HeartbeatRequest) Reset()                    { *m = HeartbeatRequest{} }
+func (m *HeartbeatRequest) String() string            { return proto.CompactTextString(m) }
+func (*HeartbeatRequest) ProtoMessage()               {}
+func (*HeartbeatRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{10} }
 
 // Login response message containing the assigned id and work
 type LoginReply struct {
-	Id uint32 `protobuf:"varint,1,opt,name=id" json:"id,omitempty"`
+	Id      uint32 `protobuf:"varint,1,opt,name=id" json:"id,omitempty"`
+	Token   string `protobuf:"bytes,2,opt,name=token" json:"token,omitempty"`
+	Expires int64  `protobuf:"varint,3,opt,name=expires" json:"expires,omitempty"`
 }
 
 func (m *LoginReply) Reset()                    { *m = LoginReply{} }
 func (m *LoginReply) String() string            { return proto.CompactTextString(m) }
 func (*LoginReply) ProtoMessage()               {}
-func (*LoginReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{6} }
+func (*LoginReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{11} }
+
+// Challenge response is the nonce, valid for one login within the server's window
+type ChallengeReply struct {
+	Nonce string `protobuf:"bytes,1,opt,name=nonce" json:"nonce,omitempty"`
+}
+
+func (m *ChallengeReply) Reset()                    { *m = ChallengeReply{} }
+func (m *ChallengeReply) String() string            { return proto.CompactTextString(m) }
+func (*ChallengeReply) ProtoMessage()               {}
+func (*ChallengeReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{12} }
 
 // GetWork response is a work struct
 type GetWorkReply struct {
@@ -139,7 +246,7 @@ type GetWorkReply struct {
 func (m *GetWorkReply) Reset()                    { *m = GetWorkReply{} }
 func (m *GetWorkReply) String() string            { return proto.CompactTextString(m) }
 func (*GetWorkReply) ProtoMessage()               {}
-func (*GetWorkReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{7} }
+func (*GetWorkReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{13} }
 
 func (m *GetWorkReply) GetWork() *Work {
 	if m != nil {
@@ -150,13 +257,14 @@ func (m *GetWorkReply) GetWork() *Work {
 
 // Announce response is boolean
 type AnnounceReply struct {
-	Ok bool `protobuf:"varint,1,opt,name=ok" json:"ok,omitempty"`
+	Ok     bool   `protobuf:"varint,1,opt,name=ok" json:"ok,omitempty"`
+	Reason string `protobuf:"bytes,2,opt,name=reason" json:"reason,omitempty"`
 }
 
 func (m *AnnounceReply) Reset()                    { *m = AnnounceReply{} }
 func (m *AnnounceReply) String() string            { return proto.CompactTextString(m) }
 func (*AnnounceReply) ProtoMessage()               {}
-func (*AnnounceReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{8} }
+func (*AnnounceReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{14} }
 
 // GetCancel response is the canonical name of server // index of server
 type GetCancelReply struct {
@@ -166,7 +274,7 @@ type GetCancelReply struct {
 func (m *GetCancelReply) Reset()                    { *m = GetCancelReply{} }
 func (m *GetCancelReply) String() string            { return proto.CompactTextString(m) }
 func (*GetCancelReply) ProtoMessage()               {}
-func (*GetCancelReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{9} }
+func (*GetCancelReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{15} }
 
 // IssueBlock response is boolean
 type IssueBlockReply struct {
@@ -176,7 +284,7 @@ type IssueBlockReply struct {
 func (m *IssueBlockReply) Reset()                    { *m = IssueBlockReply{} }
 func (m *IssueBlockReply) String() string            { return proto.CompactTextString(m) }
 func (*IssueBlockReply) ProtoMessage()               {}
-func (*IssueBlockReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{10} }
+func (*IssueBlockReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{16} }
 
 // GetResult response is the winner details + server name // index
 type GetResultReply struct {
@@ -187,7 +295,7 @@ type GetResultReply struct {
 func (m *GetResultReply) Reset()                    { *m = GetResultReply{} }
 func (m *GetResultReply) String() string            { return proto.CompactTextString(m) }
 func (*GetResultReply) ProtoMessage()               {}
-func (*GetResultReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{11} }
+func (*GetResultReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{17} }
 
 func (m *GetResultReply) GetWinner() *Win {
 	if m != nil {
@@ -196,45 +304,481 @@ func (m *GetResultReply) GetWinner() *Win {
 	return nil
 }
 
+// Logout response is boolean - false if not logged in
+type LogoutReply struct {
+	Ok bool `protobuf:"varint,1,opt,name=ok" json:"ok,omitempty"`
+}
+
+func (m *LogoutReply) Reset()                    { *m = LogoutReply{} }
+func (m *LogoutReply) String() string            { return proto.CompactTextString(m) }
+func (*LogoutReply) ProtoMessage()               {}
+func (*LogoutReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{18} }
+
 type Work struct {
-	Coinbase []byte `protobuf:"bytes,1,opt,name=coinbase,proto3" json:"coinbase,omitempty"`
-	Block    []byte `protobuf:"bytes,2,opt,name=block,proto3" json:"block,omitempty"`
-	Skel     []byte `protobuf:"bytes,3,opt,name=skel,proto3" json:"skel,omitempty"`
-	Bits     uint32 `protobuf:"varint,4,opt,name=bits" json:"bits,omitempty"`
-	Share    uint32 `protobuf:"varint,5,opt,name=share" json:"share,omitempty"`
+	Coinbase   []byte `protobuf:"bytes,1,opt,name=coinbase,proto3" json:"coinbase,omitempty"`
+	Block      []byte `protobuf:"bytes,2,opt,name=block,proto3" json:"block,omitempty"`
+	Skel       []byte `protobuf:"bytes,3,opt,name=skel,proto3" json:"skel,omitempty"`
+	Bits       uint32 `protobuf:"varint,4,opt,name=bits" json:"bits,omitempty"`
+	Share      uint32 `protobuf:"varint,5,opt,name=share" json:"share,omitempty"`
+	Job        uint64 `protobuf:"varint,6,opt,name=job" json:"job,omitempty"`
+	Issued     int64  `protobuf:"varint,7,opt,name=issued" json:"issued,omitempty"`
+	Prev       []byte `protobuf:"bytes,8,opt,name=prev,proto3" json:"prev,omitempty"`
+	Extranonce []byte `protobuf:"bytes,9,opt,name=extranonce,proto3" json:"extranonce,omitempty"`
 }
 
 func (m *Work) Reset()                    { *m = Work{} }
 func (m *Work) String() string            { return proto.CompactTextString(m) }
 func (*Work) ProtoMessage()               {}
-func (*Work) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{12} }
+func (*Work) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{19} }
 
 type Win struct {
-	Block    []byte `protobuf:"bytes,1,opt,name=block,proto3" json:"block,omitempty"`
-	Nonce    uint32 `protobuf:"varint,2,opt,name=nonce" json:"nonce,omitempty"`
-	Identity string `protobuf:"bytes,3,opt,name=identity" json:"identity,omitempty"`
+	Block      []byte `protobuf:"bytes,1,opt,name=block,proto3" json:"block,omitempty"`
+	Nonce      uint32 `protobuf:"varint,2,opt,name=nonce" json:"nonce,omitempty"`
+	Identity   string `protobuf:"bytes,3,opt,name=identity" json:"identity,omitempty"`
+	Job        uint64 `protobuf:"varint,4,opt,name=job" json:"job,omitempty"`
+	Extranonce []byte `protobuf:"bytes,5,opt,name=extranonce,proto3" json:"extranonce,omitempty"`
 }
 
 func (m *Win) Reset()                    { *m = Win{} }
 func (m *Win) String() string            { return proto.CompactTextString(m) }
 func (*Win) ProtoMessage()               {}
-func (*Win) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{13} }
+func (*Win) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{20} }
+
+// SubmitShare response gives the verdict - accepted, stale, duplicate or invalid
+type SubmitShareReply struct {
+	Ok     bool   `protobuf:"varint,1,opt,name=ok" json:"ok,omitempty"`
+	Result string `protobuf:"bytes,2,opt,name=result" json:"result,omitempty"`
+	Reason string `protobuf:"bytes,3,opt,name=reason" json:"reason,omitempty"`
+}
+
+func (m *SubmitShareReply) Reset()                    { *m = SubmitShareReply{} }
+func (m *SubmitShareReply) String() string            { return proto.CompactTextString(m) }
+func (*SubmitShareReply) ProtoMessage()               {}
+func (*SubmitShareReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{21} }
+
+// GetTally response, user is the owner of the miner when a name is given
+type GetTallyReply struct {
+	Miner *Tally `protobuf:"bytes,1,opt,name=miner" json:"miner,omitempty"`
+	User  *Tally `protobuf:"bytes,2,opt,name=user" json:"user,omitempty"`
+}
+
+func (m *GetTallyReply) Reset()                    { *m = GetTallyReply{} }
+func (m *GetTallyReply) String() string            { return proto.CompactTextString(m) }
+func (*GetTallyReply) ProtoMessage()               {}
+func (*GetTallyReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{22} }
+
+func (m *GetTallyReply) GetMiner() *Tally {
+	if m != nil {
+		return m.Miner
+	}
+	return nil
+}
+
+func (m *GetTallyReply) GetUser() *Tally {
+	if m != nil {
+		return m.User
+	}
+	return nil
+}
+
+// MinerMessage is sent by a miner on MineStream, kind says which fields are set:
+// share (block, job), win (win) or heartbeat (none)
+type MinerMessage struct {
+	Kind       string `protobuf:"bytes,1,opt,name=kind" json:"kind,omitempty"`
+	Block      []byte `protobuf:"bytes,2,opt,name=block,proto3" json:"block,omitempty"`
+	Job        uint64 `protobuf:"varint,3,opt,name=job" json:"job,omitempty"`
+	Win        *Win   `protobuf:"bytes,4,opt,name=win" json:"win,omitempty"`
+	Extranonce []byte `protobuf:"bytes,5,opt,name=extranonce,proto3" json:"extranonce,omitempty"`
+}
+
+func (m *MinerMessage) Reset()                    { *m = MinerMessage{} }
+func (m *MinerMessage) String() string            { return proto.CompactTextString(m) }
+func (*MinerMessage) ProtoMessage()               {}
+func (*MinerMessage) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{23} }
+
+func (m *MinerMessage) GetWin() *Win {
+	if m != nil {
+		return m.Win
+	}
+	return nil
+}
+
+// ServerMessage is sent to a miner on MineStream, kind says which fields are set:
+// work (work), cancel (server), share (share), win (win), difficulty (bits) or heartbeat (none)
+type ServerMessage struct {
+	Kind   string            `protobuf:"bytes,1,opt,name=kind" json:"kind,omitempty"`
+	Work   *Work             `protobuf:"bytes,2,opt,name=work" json:"work,omitempty"`
+	Server string            `protobuf:"bytes,3,opt,name=server" json:"server,omitempty"`
+	Share  *SubmitShareReply `protobuf:"bytes,4,opt,name=share" json:"share,omitempty"`
+	Win    *AnnounceReply    `protobuf:"bytes,5,opt,name=win" json:"win,omitempty"`
+	Bits   uint32            `protobuf:"varint,6,opt,name=bits" json:"bits,omitempty"`
+}
+
+func (m *ServerMessage) Reset()                    { *m = ServerMessage{} }
+func (m *ServerMessage) String() string            { return proto.CompactTextString(m) }
+func (*ServerMessage) ProtoMessage()               {}
+func (*ServerMessage) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{24} }
+
+func (m *ServerMessage) GetWork() *Work {
+	if m != nil {
+		return m.Work
+	}
+	return nil
+}
+
+func (m *ServerMessage) GetShare() *SubmitShareReply {
+	if m != nil {
+		return m.Share
+	}
+	return nil
+}
+
+func (m *ServerMessage) GetWin() *AnnounceReply {
+	if m != nil {
+		return m.Win
+	}
+	return nil
+}
+
+// Heartbeat response gives the server's grace, a miner not heard from for longer is dropped
+type HeartbeatReply struct {
+	Grace int64 `protobuf:"varint,1,opt,name=grace" json:"grace,omitempty"`
+}
+
+func (m *HeartbeatReply) Reset()                    { *m = HeartbeatReply{} }
+func (m *HeartbeatReply) String() string            { return proto.CompactTextString(m) }
+func (*HeartbeatReply) ProtoMessage()               {}
+func (*HeartbeatReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{25} }
+
+type Tally struct {
+	Accepted  uint64 `protobuf:"varint,1,opt,name=accepted" json:"accepted,omitempty"`
+	Stale     uint64 `protobuf:"varint,2,opt,name=stale" json:"stale,omitempty"`
+	Duplicate uint64 `protobuf:"varint,3,opt,name=duplicate" json:"duplicate,omitempty"`
+	Invalid   uint64 `protobuf:"varint,4,opt,name=invalid" json:"invalid,omitempty"`
+}
+
+func (m *Tally) Reset()                    { *m = Tally{} }
+func (m *Tally) String() string            { return proto.CompactTextString(m) }
+func (*Tally) ProtoMessage()               {}
+func (*Tally) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{26} }
+
+// Ban and Unban requests name a user, user:ID, or an address, ip:ADDR
+type BanRequest struct {
+	Key     string `protobuf:"bytes,1,opt,name=key" json:"key,omitempty"`
+	Seconds int64  `protobuf:"varint,2,opt,name=seconds" json:"seconds,omitempty"`
+	Reason  string `protobuf:"bytes,3,opt,name=reason" json:"reason,omitempty"`
+}
+
+func (m *BanRequest) Reset()                    { *m = BanRequest{} }
+func (m *BanRequest) String() string            { return proto.CompactTextString(m) }
+func (*BanRequest) ProtoMessage()               {}
+func (*BanRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{27} }
+
+type BanReply struct {
+	Ok    bool  `protobuf:"varint,1,opt,name=ok" json:"ok,omitempty"`
+	Until int64 `protobuf:"varint,2,opt,name=until" json:"until,omitempty"`
+}
+
+func (m *BanReply) Reset()                    { *m = BanReply{} }
+func (m *BanReply) String() string            { return proto.CompactTextString(m) }
+func (*BanReply) ProtoMessage()               {}
+func (*BanReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{28} }
+
+type BansRequest struct {
+}
+
+func (m *BansRequest) Reset()                    { *m = BansRequest{} }
+func (m *BansRequest) String() string            { return proto.CompactTextString(m) }
+func (*BansRequest) ProtoMessage()               {}
+func (*BansRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{29} }
+
+type BansReply struct {
+	Bans []*Ban `protobuf:"bytes,1,rep,name=bans" json:"bans,omitempty"`
+}
+
+func (m *BansReply) Reset()                    { *m = BansReply{} }
+func (m *BansReply) String() string            { return proto.CompactTextString(m) }
+func (*BansReply) ProtoMessage()               {}
+func (*BansReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{30} }
+
+func (m *BansReply) GetBans() []*Ban {
+	if m != nil {
+		return m.Bans
+	}
+	return nil
+}
+
+type Ban struct {
+	Key    string `protobuf:"bytes,1,opt,name=key" json:"key,omitempty"`
+	Until  int64  `protobuf:"varint,2,opt,name=until" json:"until,omitempty"`
+	Reason string `protobuf:"bytes,3,opt,name=reason" json:"reason,omitempty"`
+}
+
+func (m *Ban) Reset()                    { *m = Ban{} }
+func (m *Ban) String() string            { return proto.CompactTextString(m) }
+func (*Ban) ProtoMessage()               {}
+func (*Ban) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{31} }
+
+// Miners request names the user whose miners to list, 0 for all
+type MinersRequest struct {
+	User uint32 `protobuf:"varint,1,opt,name=user" json:"user,omitempty"`
+}
+
+func (m *MinersRequest) Reset()                    { *m = MinersRequest{} }
+func (m *MinersRequest) String() string            { return proto.CompactTextString(m) }
+func (*MinersRequest) ProtoMessage()               {}
+func (*MinersRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{32} }
+
+// Miners response lists the miners by login
+type MinersReply struct {
+	Miners []*Miner `protobuf:"bytes,1,rep,name=miners" json:"miners,omitempty"`
+}
+
+func (m *MinersReply) Reset()                    { *m = MinersReply{} }
+func (m *MinersReply) String() string            { return proto.CompactTextString(m) }
+func (*MinersReply) ProtoMessage()               {}
+func (*MinersReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{33} }
+
+func (m *MinersReply) GetMiners() []*Miner {
+	if m != nil {
+		return m.Miners
+	}
+	return nil
+}
+
+type Miner struct {
+	Name       string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+	User       uint32 `protobuf:"varint,2,opt,name=user" json:"user,omitempty"`
+	Id         uint32 `protobuf:"varint,3,opt,name=id" json:"id,omitempty"`
+	Extranonce []byte `protobuf:"bytes,4,opt,name=extranonce,proto3" json:"extranonce,omitempty"`
+	Seen       int64  `protobuf:"varint,5,opt,name=seen" json:"seen,omitempty"`
+	Tally      *Tally `protobuf:"bytes,6,opt,name=tally" json:"tally,omitempty"`
+}
+
+func (m *Miner) Reset()                    { *m = Miner{} }
+func (m *Miner) String() string            { return proto.CompactTextString(m) }
+func (*Miner) ProtoMessage()               {}
+func (*Miner) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{34} }
+
+func (m *Miner) GetTally() *Tally {
+	if m != nil {
+		return m.Tally
+	}
+	return nil
+}
+
+type RoundRequest struct {
+}
+
+func (m *RoundRequest) Reset()                    { *m = RoundRequest{} }
+func (m *RoundRequest) String() string            { return proto.CompactTextString(m) }
+func (*RoundRequest) ProtoMessage()               {}
+func (*RoundRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{35} }
+
+// Round response describes the round, of a server or of the conductor
+type RoundReply struct {
+	Server  string `protobuf:"bytes,1,opt,name=server" json:"server,omitempty"`
+	Epoch   uint64 `protobuf:"varint,2,opt,name=epoch" json:"epoch,omitempty"`
+	Job     uint64 `protobuf:"varint,3,opt,name=job" json:"job,omitempty"`
+	Height  uint32 `protobuf:"varint,4,opt,name=height" json:"height,omitempty"`
+	Bits    uint32 `protobuf:"varint,5,opt,name=bits" json:"bits,omitempty"`
+	Racing  bool   `protobuf:"varint,6,opt,name=racing" json:"racing,omitempty"`
+	Started int64  `protobuf:"varint,7,opt,name=started" json:"started,omitempty"`
+	Miners  uint32 `protobuf:"varint,8,opt,name=miners" json:"miners,omitempty"`
+	Prev    string `protobuf:"bytes,9,opt,name=prev" json:"prev,omitempty"`
+}
+
+func (m *RoundReply) Reset()                    { *m = RoundReply{} }
+func (m *RoundReply) String() string            { return proto.CompactTextString(m) }
+func (*RoundReply) ProtoMessage()               {}
+func (*RoundReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{36} }
+
+type UsersRequest struct {
+}
+
+func (m *UsersRequest) Reset()                    { *m = UsersRequest{} }
+func (m *UsersRequest) String() string            { return proto.CompactTextString(m) }
+func (*UsersRequest) ProtoMessage()               {}
+func (*UsersRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{37} }
+
+// Users response lists the users by id, without their keys
+type UsersReply struct {
+	Users []*User `protobuf:"bytes,1,rep,name=users" json:"users,omitempty"`
+}
+
+func (m *UsersReply) Reset()                    { *m = UsersReply{} }
+func (m *UsersReply) String() string            { return proto.CompactTextString(m) }
+func (*UsersReply) ProtoMessage()               {}
+func (*UsersReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{38} }
+
+func (m *UsersReply) GetUsers() []*User {
+	if m != nil {
+		return m.Users
+	}
+	return nil
+}
+
+// User requests name a user, and the key to add it with if any
+type UserRequest struct {
+	Id  uint32 `protobuf:"varint,1,opt,name=id" json:"id,omitempty"`
+	Key string `protobuf:"bytes,2,opt,name=key" json:"key,omitempty"`
+}
+
+func (m *UserRequest) Reset()                    { *m = UserRequest{} }
+func (m *UserRequest) String() string            { return proto.CompactTextString(m) }
+func (*UserRequest) ProtoMessage()               {}
+func (*UserRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{39} }
+
+// User response is the user changed, with its key when added or rotated
+type UserReply struct {
+	User *User `protobuf:"bytes,1,opt,name=user" json:"user,omitempty"`
+}
+
+func (m *UserReply) Reset()                    { *m = UserReply{} }
+func (m *UserReply) String() string            { return proto.CompactTextString(m) }
+func (*UserReply) ProtoMessage()               {}
+func (*UserReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{40} }
+
+func (m *UserReply) GetUser() *User {
+	if m != nil {
+		return m.User
+	}
+	return nil
+}
+
+type User struct {
+	Id       uint32 `protobuf:"varint,1,opt,name=id" json:"id,omitempty"`
+	Key      string `protobuf:"bytes,2,opt,name=key" json:"key,omitempty"`
+	Disabled bool   `protobuf:"varint,3,opt,name=disabled" json:"disabled,omitempty"`
+	Created  int64  `protobuf:"varint,4,opt,name=created" json:"created,omitempty"`
+	Rotated  int64  `protobuf:"varint,5,opt,name=rotated" json:"rotated,omitempty"`
+}
+
+func (m *User) Reset()                    { *m = User{} }
+func (m *User) String() string            { return proto.CompactTextString(m) }
+func (*User) ProtoMessage()               {}
+func (*User) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{41} }
+
+type DrainRequest struct {
+}
+
+func (m *DrainRequest) Reset()                    { *m = DrainRequest{} }
+func (m *DrainRequest) String() string            { return proto.CompactTextString(m) }
+func (*DrainRequest) ProtoMessage()               {}
+func (*DrainRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{42} }
+
+type DrainReply struct {
+	Ok bool `protobuf:"varint,1,opt,name=ok" json:"ok,omitempty"`
+}
+
+func (m *DrainReply) Reset()                    { *m = DrainReply{} }
+func (m *DrainReply) String() string            { return proto.CompactTextString(m) }
+func (*DrainReply) ProtoMessage()               {}
+func (*DrainReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{43} }
+
+type ServersRequest struct {
+}
+
+func (m *ServersRequest) Reset()                    { *m = ServersRequest{} }
+func (m *ServersRequest) String() string            { return proto.CompactTextString(m) }
+func (*ServersRequest) ProtoMessage()               {}
+func (*ServersRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{44} }
+
+// Servers response lists the servers the conductor dialed
+type ServersReply struct {
+	Servers []*ServerStatus `protobuf:"bytes,1,rep,name=servers" json:"servers,omitempty"`
+}
+
+func (m *ServersReply) Reset()                    { *m = ServersReply{} }
+func (m *ServersReply) String() string            { return proto.CompactTextString(m) }
+func (*ServersReply) ProtoMessage()               {}
+func (*ServersReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{45} }
+
+func (m *ServersReply) GetServers() []*ServerStatus {
+	if m != nil {
+		return m.Servers
+	}
+	return nil
+}
+
+type ServerStatus struct {
+	Address string `protobuf:"bytes,1,opt,name=address" json:"address,omitempty"`
+	Status  string `protobuf:"bytes,2,opt,name=status" json:"status,omitempty"`
+}
+
+func (m *ServerStatus) Reset()                    { *m = ServerStatus{} }
+func (m *ServerStatus) String() string            { return proto.CompactTextString(m) }
+func (*ServerStatus) ProtoMessage()               {}
+func (*ServerStatus) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{46} }
+
+type TemplateRequest struct {
+}
+
+func (m *TemplateRequest) Reset()                    { *m = TemplateRequest{} }
+func (m *TemplateRequest) String() string            { return proto.CompactTextString(m) }
+func (*TemplateRequest) ProtoMessage()               {}
+func (*TemplateRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{47} }
+
+// Template response says whether a round was ended for the new template
+type TemplateReply struct {
+	Ok bool `protobuf:"varint,1,opt,name=ok" json:"ok,omitempty"`
+}
+
+func (m *TemplateReply) Reset()                    { *m = TemplateReply{} }
+func (m *TemplateReply) String() string            { return proto.CompactTextString(m) }
+func (*TemplateReply) ProtoMessage()               {}
+func (*TemplateReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{48} }
 
 func init() {
 	proto.RegisterType((*LoginRequest)(nil), "cpb.LoginRequest")
+	proto.RegisterType((*ChallengeRequest)(nil), "cpb.ChallengeRequest")
 	proto.RegisterType((*GetWorkRequest)(nil), "cpb.GetWorkRequest")
 	proto.RegisterType((*AnnounceRequest)(nil), "cpb.AnnounceRequest")
 	proto.RegisterType((*GetCancelRequest)(nil), "cpb.GetCancelRequest")
 	proto.RegisterType((*IssueBlockRequest)(nil), "cpb.IssueBlockRequest")
 	proto.RegisterType((*GetResultRequest)(nil), "cpb.GetResultRequest")
+	proto.RegisterType((*LogoutRequest)(nil), "cpb.LogoutRequest")
+	proto.RegisterType((*SubmitShareRequest)(nil), "cpb.SubmitShareRequest")
+	proto.RegisterType((*GetTallyRequest)(nil), "cpb.GetTallyRequest")
+	proto.RegisterType((*HeartbeatRequest)(nil), "cpb.HeartbeatRequest")
 	proto.RegisterType((*LoginReply)(nil), "cpb.LoginReply")
+	proto.RegisterType((*ChallengeReply)(nil), "cpb.ChallengeReply")
 	proto.RegisterType((*GetWorkReply)(nil), "cpb.GetWorkReply")
 	proto.RegisterType((*AnnounceReply)(nil), "cpb.AnnounceReply")
 	proto.RegisterType((*GetCancelReply)(nil), "cpb.GetCancelReply")
 	proto.RegisterType((*IssueBlockReply)(nil), "cpb.IssueBlockReply")
 	proto.RegisterType((*GetResultReply)(nil), "cpb.GetResultReply")
+	proto.RegisterType((*LogoutReply)(nil), "cpb.LogoutReply")
 	proto.RegisterType((*Work)(nil), "cpb.Work")
 	proto.RegisterType((*Win)(nil), "cpb.Win")
+	proto.RegisterType((*SubmitShareReply)(nil), "cpb.SubmitShareReply")
+	proto.RegisterType((*GetTallyReply)(nil), "cpb.GetTallyReply")
+	proto.RegisterType((*MinerMessage)(nil), "cpb.MinerMessage")
+	proto.RegisterType((*ServerMessage)(nil), "cpb.ServerMessage")
+	proto.RegisterType((*HeartbeatReply)(nil), "cpb.HeartbeatReply")
+	proto.RegisterType((*Tally)(nil), "cpb.Tally")
+	proto.RegisterType((*BanRequest)(nil), "cpb.BanRequest")
+	proto.RegisterType((*BanReply)(nil), "cpb.BanReply")
+	proto.RegisterType((*BansRequest)(nil), "cpb.BansRequest")
+	proto.RegisterType((*BansReply)(nil), "cpb.BansReply")
+	proto.RegisterType((*Ban)(nil), "cpb.Ban")
+	proto.RegisterType((*MinersRequest)(nil), "cpb.MinersRequest")
+	proto.RegisterType((*MinersReply)(nil), "cpb.MinersReply")
+	proto.RegisterType((*Miner)(nil), "cpb.Miner")
+	proto.RegisterType((*RoundRequest)(nil), "cpb.RoundRequest")
+	proto.RegisterType((*RoundReply)(nil), "cpb.RoundReply")
+	proto.RegisterType((*UsersRequest)(nil), "cpb.UsersRequest")
+	proto.RegisterType((*UsersReply)(nil), "cpb.UsersReply")
+	proto.RegisterType((*UserRequest)(nil), "cpb.UserRequest")
+	proto.RegisterType((*UserReply)(nil), "cpb.UserReply")
+	proto.RegisterType((*User)(nil), "cpb.User")
+	proto.RegisterType((*DrainRequest)(nil), "cpb.DrainRequest")
+	proto.RegisterType((*DrainReply)(nil), "cpb.DrainReply")
+	proto.RegisterType((*ServersRequest)(nil), "cpb.ServersRequest")
+	proto.RegisterType((*ServersReply)(nil), "cpb.ServersReply")
+	proto.RegisterType((*ServerStatus)(nil), "cpb.ServerStatus")
+	proto.RegisterType((*TemplateRequest)(nil), "cpb.TemplateRequest")
+	proto.RegisterType((*TemplateReply)(nil), "cpb.TemplateReply")
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -250,6 +794,8 @@ const _ = grpc.SupportPackageIsVersion3
 type CoinClient interface {
 	// very first message client -> server requests login details
 	Login(ctx context.Context, in *LoginRequest, opts ...grpc.CallOption) (*LoginReply, error)
+	// Challenge issues a nonce to log in with in place of the time
+	Challenge(ctx context.Context, in *ChallengeRequest, opts ...grpc.CallOption) (*ChallengeReply, error)
 	// GetWork is a request to start mining
 	GetWork(ctx context.Context, in *GetWorkRequest, opts ...grpc.CallOption) (*GetWorkReply, error)
 	// Announce is a request to accept a win discovery
@@ -260,6 +806,17 @@ type CoinClient interface {
 	IssueBlock(ctx context.Context, in *IssueBlockRequest, opts ...grpc.CallOption) (*IssueBlockReply, error)
 	// GetResult is a  request for a solution
 	GetResult(ctx context.Context, in *GetResultRequest, opts ...grpc.CallOption) (*GetResultReply, error)
+	// Logout is a miner leaving the server
+	Logout(ctx context.Context, in *LogoutRequest, opts ...grpc.CallOption) (*LogoutReply, error)
+	// SubmitShare offers a header meeting the share target
+	SubmitShare(ctx context.Context, in *SubmitShareRequest, opts ...grpc.CallOption) (*SubmitShareReply, error)
+	// GetTally reports the share counts of a miner or a user
+	GetTally(ctx context.Context, in *GetTallyRequest, opts ...grpc.CallOption) (*GetTallyReply, error)
+	// MineStream replaces GetWork, GetCancel, SubmitShare and Announce for a
+	// logged in miner: work and cancellations are pushed as each race starts and ends
+	MineStream(ctx context.Context, opts ...grpc.CallOption) (Coin_MineStreamClient, error)
+	// Heartbeat keeps a miner that long-polls alive between its calls
+	Heartbeat(ctx context.Context, in *HeartbeatRequest, opts ...grpc.CallOption) (*HeartbeatReply, error)
 }
 
 type coinClient struct {
@@ -279,6 +836,15 @@ func (c *coinClient) Login(ctx context.Context, in *LoginRequest, opts ...grpc.C
 	return out, nil
 }
 
+func (c *coinClient) Challenge(ctx context.Context, in *ChallengeRequest, opts ...grpc.CallOption) (*ChallengeReply, error) {
+	out := new(ChallengeReply)
+	err := grpc.Invoke(ctx, "/cpb.Coin/Challenge", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *coinClient) GetWork(ctx context.Context, in *GetWorkRequest, opts ...grpc.CallOption) (*GetWorkReply, error) {
 	out := new(GetWorkReply)
 	err := grpc.Invoke(ctx, "/cpb.Coin/GetWork", in, out, c.cc, opts...)
@@ -324,11 +890,80 @@ func (c *coinClient) GetResult(ctx context.Context, in *GetResultRequest, opts .
 	return out, nil
 }
 
+func (c *coinClient) Logout(ctx context.Context, in *LogoutRequest, opts ...grpc.CallOption) (*LogoutReply, error) {
+	out := new(LogoutReply)
+	err := grpc.Invoke(ctx, "/cpb.Coin/Logout", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *coinClient) SubmitShare(ctx context.Context, in *SubmitShareRequest, opts ...grpc.CallOption) (*SubmitShareReply, error) {
+	out := new(SubmitShareReply)
+	err := grpc.Invoke(ctx, "/cpb.Coin/SubmitShare", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *coinClient) GetTally(ctx context.Context, in *GetTallyRequest, opts ...grpc.CallOption) (*GetTallyReply, error) {
+	out := new(GetTallyReply)
+	err := grpc.Invoke(ctx, "/cpb.Coin/GetTally", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *coinClient) MineStream(ctx context.Context, opts ...grpc.CallOption) (Coin_MineStreamClient, error) {
+	stream, err := grpc.NewClientStream(ctx, &_Coin_serviceDesc.Streams[0], c.cc, "/cpb.Coin/MineStream", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &coinMineStreamClient{stream}
+	return x, nil
+}
+
+type Coin_MineStreamClient interface {
+	Send(*MinerMessage) error
+	Recv() (*ServerMessage, error)
+	grpc.ClientStream
+}
+
+type coinMineStreamClient struct {
+	grpc.ClientStream
+}
+
+func (x *coinMineStreamClient) Send(m *MinerMessage) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *coinMineStreamClient) Recv() (*ServerMessage, error) {
+	m := new(ServerMessage)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *coinClient) Heartbeat(ctx context.Context, in *HeartbeatRequest, opts ...grpc.CallOption) (*HeartbeatReply, error) {
+	out := new(HeartbeatReply)
+	err := grpc.Invoke(ctx, "/cpb.Coin/Heartbeat", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // Server API for Coin service
 
 type CoinServer interface {
 	// very first message client -> server requests login details
 	Login(context.Context, *LoginRequest) (*LoginReply, error)
+	// Challenge issues a nonce to log in with in place of the time
+	Challenge(context.Context, *ChallengeRequest) (*ChallengeReply, error)
 	// GetWork is a request to start mining
 	GetWork(context.Context, *GetWorkRequest) (*GetWorkReply, error)
 	// Announce is a request to accept a win discovery
@@ -339,6 +974,17 @@ type CoinServer interface {
 	IssueBlock(context.Context, *IssueBlockRequest) (*IssueBlockReply, error)
 	// GetResult is a  request for a solution
 	GetResult(context.Context, *GetResultRequest) (*GetResultReply, error)
+	// Logout is a miner leaving the server
+	Logout(context.Context, *LogoutRequest) (*LogoutReply, error)
+	// SubmitShare offers a header meeting the share target
+	SubmitShare(context.Context, *SubmitShareRequest) (*SubmitShareReply, error)
+	// GetTally reports the share counts of a miner or a user
+	GetTally(context.Context, *GetTallyRequest) (*GetTallyReply, error)
+	// MineStream replaces GetWork, GetCancel, SubmitShare and Announce for a
+	// logged in miner: work and cancellations are pushed as each race starts and ends
+	MineStream(Coin_MineStreamServer) error
+	// Heartbeat keeps a miner that long-polls alive between its calls
+	Heartbeat(context.Context, *HeartbeatRequest) (*HeartbeatReply, error)
 }
 
 func RegisterCoinServer(s *grpc.Server, srv CoinServer) {
@@ -363,6 +1009,24 @@ func _Coin_Login_Handler(srv interface{}, ctx context.Context, dec func(interfac
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Coin_Challenge_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ChallengeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CoinServer).Challenge(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cpb.Coin/Challenge",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CoinServer).Challenge(ctx, req.(*ChallengeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _Coin_GetWork_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(GetWorkRequest)
 	if err := dec(in); err != nil {
@@ -453,34 +1117,711 @@ func _Coin_GetResult_Handler(srv interface{}, ctx context.Context, dec func(inte
 	return interceptor(ctx, in, info, handler)
 }
 
-var _Coin_serviceDesc = grpc.ServiceDesc{
-	ServiceName: "cpb.Coin",
-	HandlerType: (*CoinServer)(nil),
-	Methods: []grpc.MethodDesc{
-		{
-			MethodName: "Login",
-			Handler:    _Coin_Login_Handler,
-		},
-		{
-			MethodName: "GetWork",
-			Handler:    _Coin_GetWork_Handler,
-		},
-		{
-			MethodName: "Announce",
-			Handler:    _Coin_Announce_Handler,
-		},
-		{
-			MethodName: "GetCancel",
-			Handler:    _Coin_GetCancel_Handler,
-		},
-		{
-			MethodName: "IssueBlock",
+func _Coin_Logout_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LogoutRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CoinServer).Logout(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cpb.Coin/Logout",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CoinServer).Logout(ctx, req.(*LogoutRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Coin_SubmitShare_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SubmitShareRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CoinServer).SubmitShare(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cpb.Coin/SubmitShare",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CoinServer).SubmitShare(ctx, req.(*SubmitShareRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Coin_GetTally_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetTallyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CoinServer).GetTally(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cpb.Coin/GetTally",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CoinServer).GetTally(ctx, req.(*GetTallyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Coin_MineStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(CoinServer).MineStream(&coinMineStreamServer{stream})
+}
+
+type Coin_MineStreamServer interface {
+	Send(*ServerMessage) error
+	Recv() (*MinerMessage, error)
+	grpc.ServerStream
+}
+
+type coinMineStreamServer struct {
+	grpc.ServerStream
+}
+
+func (x *coinMineStreamServer) Send(m *ServerMessage) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *coinMineStreamServer) Recv() (*MinerMessage, error) {
+	m := new(MinerMessage)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _Coin_Heartbeat_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HeartbeatRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CoinServer).Heartbeat(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cpb.Coin/Heartbeat",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CoinServer).Heartbeat(ctx, req.(*HeartbeatRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _Coin_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "cpb.Coin",
+	HandlerType: (*CoinServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Login",
+			Handler:    _Coin_Login_Handler,
+		},
+		{
+			MethodName: "Challenge",
+			Handler:    _Coin_Challenge_Handler,
+		},
+		{
+			MethodName: "GetWork",
+			Handler:    _Coin_GetWork_Handler,
+		},
+		{
+			MethodName: "Announce",
+			Handler:    _Coin_Announce_Handler,
+		},
+		{
+			MethodName: "GetCancel",
+			Handler:    _Coin_GetCancel_Handler,
+		},
+		{
+			MethodName: "IssueBlock",
 			Handler:    _Coin_IssueBlock_Handler,
 		},
 		{
 			MethodName: "GetResult",
 			Handler:    _Coin_GetResult_Handler,
 		},
+		{
+			MethodName: "Logout",
+			Handler:    _Coin_Logout_Handler,
+		},
+		{
+			MethodName: "SubmitShare",
+			Handler:    _Coin_SubmitShare_Handler,
+		},
+		{
+			MethodName: "GetTally",
+			Handler:    _Coin_GetTally_Handler,
+		},
+		{
+			MethodName: "Heartbeat",
+			Handler:    _Coin_Heartbeat_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "MineStream",
+			Handler:       _Coin_MineStream_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: fileDescriptor0,
+}
+
+// Client API for Admin service
+
+type AdminClient interface {
+	// Ban bans a user or an address, for a while
+	Ban(ctx context.Context, in *BanRequest, opts ...grpc.CallOption) (*BanReply, error)
+	// Unban lifts a ban
+	Unban(ctx context.Context, in *BanRequest, opts ...grpc.CallOption) (*BanReply, error)
+	// Bans lists the bans in force
+	Bans(ctx context.Context, in *BansRequest, opts ...grpc.CallOption) (*BansReply, error)
+	// Miners lists the miners logged in, those of one user if it is given
+	Miners(ctx context.Context, in *MinersRequest, opts ...grpc.CallOption) (*MinersReply, error)
+	// Round reports the job and whether the race for it is on
+	Round(ctx context.Context, in *RoundRequest, opts ...grpc.CallOption) (*RoundReply, error)
+	// Users lists the users of the server's store, without their keys
+	Users(ctx context.Context, in *UsersRequest, opts ...grpc.CallOption) (*UsersReply, error)
+	// AddUser adds a user, with a new random key unless one is given
+	AddUser(ctx context.Context, in *UserRequest, opts ...grpc.CallOption) (*UserReply, error)
+	// DisableUser stops a user logging in and logs out its miners
+	DisableUser(ctx context.Context, in *UserRequest, opts ...grpc.CallOption) (*UserReply, error)
+	// EnableUser lets a disabled user log in again
+	EnableUser(ctx context.Context, in *UserRequest, opts ...grpc.CallOption) (*UserReply, error)
+	// RotateKey gives a user a new random key
+	RotateKey(ctx context.Context, in *UserRequest, opts ...grpc.CallOption) (*UserReply, error)
+	// Drain ends the race and stops the server, as SIGTERM does
+	Drain(ctx context.Context, in *DrainRequest, opts ...grpc.CallOption) (*DrainReply, error)
+}
+
+type adminClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewAdminClient(cc *grpc.ClientConn) AdminClient {
+	return &adminClient{cc}
+}
+
+func (c *adminClient) Ban(ctx context.Context, in *BanRequest, opts ...grpc.CallOption) (*BanReply, error) {
+	out := new(BanReply)
+	err := grpc.Invoke(ctx, "/cpb.Admin/Ban", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) Unban(ctx context.Context, in *BanRequest, opts ...grpc.CallOption) (*BanReply, error) {
+	out := new(BanReply)
+	err := grpc.Invoke(ctx, "/cpb.Admin/Unban", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) Bans(ctx context.Context, in *BansRequest, opts ...grpc.CallOption) (*BansReply, error) {
+	out := new(BansReply)
+	err := grpc.Invoke(ctx, "/cpb.Admin/Bans", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) Miners(ctx context.Context, in *MinersRequest, opts ...grpc.CallOption) (*MinersReply, error) {
+	out := new(MinersReply)
+	err := grpc.Invoke(ctx, "/cpb.Admin/Miners", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) Round(ctx context.Context, in *RoundRequest, opts ...grpc.CallOption) (*RoundReply, error) {
+	out := new(RoundReply)
+	err := grpc.Invoke(ctx, "/cpb.Admin/Round", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) Users(ctx context.Context, in *UsersRequest, opts ...grpc.CallOption) (*UsersReply, error) {
+	out := new(UsersReply)
+	err := grpc.Invoke(ctx, "/cpb.Admin/Users", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) AddUser(ctx context.Context, in *UserRequest, opts ...grpc.CallOption) (*UserReply, error) {
+	out := new(UserReply)
+	err := grpc.Invoke(ctx, "/cpb.Admin/AddUser", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) DisableUser(ctx context.Context, in *UserRequest, opts ...grpc.CallOption) (*UserReply, error) {
+	out := new(UserReply)
+	err := grpc.Invoke(ctx, "/cpb.Admin/DisableUser", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) EnableUser(ctx context.Context, in *UserRequest, opts ...grpc.CallOption) (*UserReply, error) {
+	out := new(UserReply)
+	err := grpc.Invoke(ctx, "/cpb.Admin/EnableUser", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) RotateKey(ctx context.Context, in *UserRequest, opts ...grpc.CallOption) (*UserReply, error) {
+	out := new(UserReply)
+	err := grpc.Invoke(ctx, "/cpb.Admin/RotateKey", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) Drain(ctx context.Context, in *DrainRequest, opts ...grpc.CallOption) (*DrainReply, error) {
+	out := new(DrainReply)
+	err := grpc.Invoke(ctx, "/cpb.Admin/Drain", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Server API for Admin service
+
+type AdminServer interface {
+	// Ban bans a user or an address, for a while
+	Ban(context.Context, *BanRequest) (*BanReply, error)
+	// Unban lifts a ban
+	Unban(context.Context, *BanRequest) (*BanReply, error)
+	// Bans lists the bans in force
+	Bans(context.Context, *BansRequest) (*BansReply, error)
+	// Miners lists the miners logged in, those of one user if it is given
+	Miners(context.Context, *MinersRequest) (*MinersReply, error)
+	// Round reports the job and whether the race for it is on
+	Round(context.Context, *RoundRequest) (*RoundReply, error)
+	// Users lists the users of the server's store, without their keys
+	Users(context.Context, *UsersRequest) (*UsersReply, error)
+	// AddUser adds a user, with a new random key unless one is given
+	AddUser(context.Context, *UserRequest) (*UserReply, error)
+	// DisableUser stops a user logging in and logs out its miners
+	DisableUser(context.Context, *UserRequest) (*UserReply, error)
+	// EnableUser lets a disabled user log in again
+	EnableUser(context.Context, *UserRequest) (*UserReply, error)
+	// RotateKey gives a user a new random key
+	RotateKey(context.Context, *UserRequest) (*UserReply, error)
+	// Drain ends the race and stops the server, as SIGTERM does
+	Drain(context.Context, *DrainRequest) (*DrainReply, error)
+}
+
+func RegisterAdminServer(s *grpc.Server, srv AdminServer) {
+	s.RegisterService(&_Admin_serviceDesc, srv)
+}
+
+func _Admin_Ban_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BanRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).Ban(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cpb.Admin/Ban",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).Ban(ctx, req.(*BanRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_Unban_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BanRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).Unban(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cpb.Admin/Unban",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).Unban(ctx, req.(*BanRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_Bans_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BansRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).Bans(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cpb.Admin/Bans",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).Bans(ctx, req.(*BansRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_Miners_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MinersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).Miners(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cpb.Admin/Miners",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).Miners(ctx, req.(*MinersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_Round_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RoundRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).Round(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cpb.Admin/Round",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).Round(ctx, req.(*RoundRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_Users_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UsersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).Users(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cpb.Admin/Users",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).Users(ctx, req.(*UsersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_AddUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).AddUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cpb.Admin/AddUser",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).AddUser(ctx, req.(*UserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_DisableUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).DisableUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cpb.Admin/DisableUser",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).DisableUser(ctx, req.(*UserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_EnableUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).EnableUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cpb.Admin/EnableUser",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).EnableUser(ctx, req.(*UserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_RotateKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).RotateKey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cpb.Admin/RotateKey",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).RotateKey(ctx, req.(*UserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_Drain_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DrainRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).Drain(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cpb.Admin/Drain",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).Drain(ctx, req.(*DrainRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _Admin_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "cpb.Admin",
+	HandlerType: (*AdminServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Ban",
+			Handler:    _Admin_Ban_Handler,
+		},
+		{
+			MethodName: "Unban",
+			Handler:    _Admin_Unban_Handler,
+		},
+		{
+			MethodName: "Bans",
+			Handler:    _Admin_Bans_Handler,
+		},
+		{
+			MethodName: "Miners",
+			Handler:    _Admin_Miners_Handler,
+		},
+		{
+			MethodName: "Round",
+			Handler:    _Admin_Round_Handler,
+		},
+		{
+			MethodName: "Users",
+			Handler:    _Admin_Users_Handler,
+		},
+		{
+			MethodName: "AddUser",
+			Handler:    _Admin_AddUser_Handler,
+		},
+		{
+			MethodName: "DisableUser",
+			Handler:    _Admin_DisableUser_Handler,
+		},
+		{
+			MethodName: "EnableUser",
+			Handler:    _Admin_EnableUser_Handler,
+		},
+		{
+			MethodName: "RotateKey",
+			Handler:    _Admin_RotateKey_Handler,
+		},
+		{
+			MethodName: "Drain",
+			Handler:    _Admin_Drain_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: fileDescriptor0,
+}
+
+// Client API for ConductorAdmin service
+
+type ConductorAdminClient interface {
+	// Servers lists the servers and how they took the last blocks issued
+	Servers(ctx context.Context, in *ServersRequest, opts ...grpc.CallOption) (*ServersReply, error)
+	// Round reports the round in progress
+	Round(ctx context.Context, in *RoundRequest, opts ...grpc.CallOption) (*RoundReply, error)
+	// NewTemplate ends the round as if the network had found its block, and issues a new template
+	NewTemplate(ctx context.Context, in *TemplateRequest, opts ...grpc.CallOption) (*TemplateReply, error)
+}
+
+type conductorAdminClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewConductorAdminClient(cc *grpc.ClientConn) ConductorAdminClient {
+	return &conductorAdminClient{cc}
+}
+
+func (c *conductorAdminClient) Servers(ctx context.Context, in *ServersRequest, opts ...grpc.CallOption) (*ServersReply, error) {
+	out := new(ServersReply)
+	err := grpc.Invoke(ctx, "/cpb.ConductorAdmin/Servers", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *conductorAdminClient) Round(ctx context.Context, in *RoundRequest, opts ...grpc.CallOption) (*RoundReply, error) {
+	out := new(RoundReply)
+	err := grpc.Invoke(ctx, "/cpb.ConductorAdmin/Round", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *conductorAdminClient) NewTemplate(ctx context.Context, in *TemplateRequest, opts ...grpc.CallOption) (*TemplateReply, error) {
+	out := new(TemplateReply)
+	err := grpc.Invoke(ctx, "/cpb.ConductorAdmin/NewTemplate", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Server API for ConductorAdmin service
+
+type ConductorAdminServer interface {
+	// Servers lists the servers and how they took the last blocks issued
+	Servers(context.Context, *ServersRequest) (*ServersReply, error)
+	// Round reports the round in progress
+	Round(context.Context, *RoundRequest) (*RoundReply, error)
+	// NewTemplate ends the round as if the network had found its block, and issues a new template
+	NewTemplate(context.Context, *TemplateRequest) (*TemplateReply, error)
+}
+
+func RegisterConductorAdminServer(s *grpc.Server, srv ConductorAdminServer) {
+	s.RegisterService(&_ConductorAdmin_serviceDesc, srv)
+}
+
+func _ConductorAdmin_Servers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ServersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConductorAdminServer).Servers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cpb.ConductorAdmin/Servers",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConductorAdminServer).Servers(ctx, req.(*ServersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ConductorAdmin_Round_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RoundRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConductorAdminServer).Round(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cpb.ConductorAdmin/Round",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConductorAdminServer).Round(ctx, req.(*RoundRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ConductorAdmin_NewTemplate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TemplateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConductorAdminServer).NewTemplate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cpb.ConductorAdmin/NewTemplate",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConductorAdminServer).NewTemplate(ctx, req.(*TemplateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _ConductorAdmin_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "cpb.ConductorAdmin",
+	HandlerType: (*ConductorAdminServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Servers",
+			Handler:    _ConductorAdmin_Servers_Handler,
+		},
+		{
+			MethodName: "Round",
+			Handler:    _ConductorAdmin_Round_Handler,
+		},
+		{
+			MethodName: "NewTemplate",
+			Handler:    _ConductorAdmin_NewTemplate_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: fileDescriptor0,
@@ -489,40 +1830,111 @@ var _Coin_serviceDesc = grpc.ServiceDesc{
 func init() { proto.RegisterFile("coin.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 559 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0x7c, 0x54, 0xcd, 0x6e, 0xda, 0x4c,
-	0x14, 0x8d, 0x8d, 0x21, 0x70, 0x03, 0xe1, 0x63, 0xc2, 0x17, 0x59, 0x56, 0xab, 0x52, 0xab, 0xaa,
-	0xd8, 0xc0, 0x22, 0x91, 0x2a, 0x55, 0xea, 0xa6, 0xcd, 0x22, 0x6a, 0xd4, 0x6e, 0x66, 0xc3, 0x1a,
-	0x9b, 0x51, 0x18, 0x61, 0x66, 0x5c, 0xff, 0x04, 0xf1, 0x6c, 0x7d, 0x8f, 0x3e, 0x4f, 0x35, 0x77,
-	0xc6, 0xf6, 0x98, 0x54, 0xec, 0xe6, 0x1c, 0xdf, 0xff, 0x73, 0xaf, 0x01, 0x62, 0xc9, 0xc5, 0x32,
-	0xcd, 0x64, 0x21, 0x49, 0x27, 0x4e, 0xa3, 0xf0, 0x09, 0x86, 0x3f, 0xe4, 0x33, 0x17, 0x94, 0xfd,
-	0x2a, 0x59, 0x5e, 0x10, 0x02, 0x9e, 0x58, 0xef, 0x99, 0xef, 0xcc, 0x9c, 0xf9, 0x80, 0xe2, 0x5b,
-	0x71, 0x05, 0xdf, 0x33, 0xdf, 0xd5, 0x9c, 0x7a, 0x2b, 0xae, 0xcc, 0x59, 0xe6, 0x77, 0x66, 0xce,
-	0x7c, 0x44, 0xf1, 0x1d, 0x7e, 0x80, 0xeb, 0x47, 0x56, 0xac, 0x64, 0xb6, 0x3b, 0x13, 0x2d, 0x5c,
-	0xc0, 0xf8, 0xab, 0x10, 0xb2, 0x14, 0x31, 0xab, 0xcc, 0x02, 0xe8, 0x1c, 0xb8, 0x40, 0xab, 0xab,
-	0xbb, 0xfe, 0x32, 0x4e, 0xa3, 0xe5, 0x8a, 0x0b, 0xaa, 0xc8, 0xf0, 0x23, 0xfc, 0xf7, 0xc8, 0x8a,
-	0x87, 0xb5, 0x88, 0x59, 0x72, 0x2e, 0xec, 0x6f, 0x07, 0x26, 0xdf, 0xf3, 0xbc, 0x64, 0xdf, 0x12,
-	0x19, 0xd7, 0x05, 0x4c, 0xa1, 0x5b, 0xa6, 0x29, 0xcb, 0xd0, 0x74, 0x48, 0x35, 0x50, 0x6c, 0x22,
-	0x0f, 0x2c, 0xc3, 0x8e, 0x86, 0x54, 0x03, 0x32, 0x83, 0xab, 0x48, 0xf9, 0x6e, 0x19, 0x7f, 0xde,
-	0x16, 0xa6, 0x33, 0x9b, 0x52, 0x7e, 0x08, 0x7d, 0x4f, 0xfb, 0x21, 0x20, 0xb7, 0xd0, 0xdb, 0xb3,
-	0x6c, 0x97, 0x30, 0xbf, 0x8b, 0xb4, 0x41, 0xaa, 0xca, 0x88, 0x17, 0xb9, 0xdf, 0xd3, 0x23, 0x52,
-	0x6f, 0x65, 0x9b, 0xb3, 0xec, 0x85, 0x65, 0xfe, 0x25, 0xd6, 0x6e, 0x90, 0xe9, 0x92, 0xb2, 0xbc,
-	0x4c, 0x8a, 0x73, 0x5d, 0xbe, 0x01, 0x30, 0x72, 0xa5, 0xc9, 0x91, 0x5c, 0x83, 0xcb, 0x37, 0xf8,
-	0x7d, 0x44, 0x5d, 0xbe, 0x09, 0x17, 0x30, 0xac, 0x05, 0x50, 0xdf, 0xdf, 0x82, 0x77, 0x90, 0xd9,
-	0xce, 0x0c, 0x76, 0xa0, 0x07, 0xab, 0xbe, 0x22, 0x1d, 0xbe, 0x83, 0x51, 0xa3, 0x84, 0x89, 0x27,
-	0xb5, 0x75, 0x9f, 0xba, 0x72, 0x17, 0xce, 0x51, 0xd0, 0x6a, 0xf6, 0xca, 0xa2, 0xa9, 0xdf, 0x69,
-	0xd5, 0xff, 0x1e, 0xc6, 0xf6, 0xf0, 0xff, 0x15, 0xec, 0x09, 0x83, 0x55, 0x2d, 0x2a, 0x8b, 0x19,
-	0xf4, 0x0e, 0x5c, 0x08, 0x13, 0xcc, 0x56, 0xde, 0xf0, 0x56, 0x3a, 0xb7, 0x95, 0xee, 0x05, 0x3c,
-	0xd5, 0x07, 0x09, 0xa0, 0xaf, 0x16, 0x3a, 0x5a, 0xe7, 0xcc, 0x28, 0x5c, 0xe3, 0x46, 0x2c, 0xd7,
-	0x16, 0x8b, 0x80, 0x97, 0xef, 0x58, 0x82, 0xea, 0x0e, 0x29, 0xbe, 0x6b, 0xa1, 0x3c, 0x4b, 0xa8,
-	0x29, 0x74, 0xf3, 0xed, 0x3a, 0xd3, 0x9a, 0x8e, 0xa8, 0x06, 0xe1, 0x4f, 0xe8, 0xac, 0xb8, 0x68,
-	0x42, 0x3b, 0x76, 0xe8, 0x29, 0x74, 0x85, 0x14, 0xb1, 0xbe, 0x93, 0x11, 0xd5, 0x40, 0x95, 0xc8,
-	0x37, 0x4c, 0x14, 0xbc, 0x38, 0x62, 0xd2, 0x01, 0xad, 0xf1, 0xdd, 0x1f, 0x17, 0xbc, 0x07, 0xc9,
-	0x05, 0x59, 0x40, 0x17, 0x65, 0x25, 0x13, 0x1c, 0x81, 0x7d, 0x91, 0xc1, 0xd8, 0xa6, 0xd2, 0xe4,
-	0x18, 0x5e, 0x90, 0x7b, 0xb8, 0x34, 0x3a, 0x93, 0x1b, 0xfc, 0xda, 0x3e, 0xbb, 0x60, 0xd2, 0x26,
-	0xb5, 0xd3, 0x27, 0xe8, 0x57, 0x6a, 0x93, 0x29, 0x1a, 0x9c, 0x9c, 0x61, 0x40, 0x4e, 0x58, 0xed,
-	0xf7, 0x19, 0x06, 0xf5, 0x12, 0x90, 0xff, 0xab, 0xc8, 0xad, 0x83, 0x0c, 0x6e, 0x4e, 0x69, 0xed,
-	0xfa, 0x05, 0xa0, 0xd9, 0x0a, 0x72, 0x8b, 0x46, 0xaf, 0x6e, 0x34, 0x98, 0xbe, 0xe2, 0xed, 0xc4,
-	0x7a, 0x61, 0x9a, 0xc4, 0xad, 0x1b, 0x69, 0x12, 0x5b, 0x7b, 0x15, 0x5e, 0x44, 0x3d, 0xfc, 0xc3,
-	0xdd, 0xff, 0x0d, 0x00, 0x00, 0xff, 0xff, 0x6b, 0x34, 0x31, 0x1f, 0xef, 0x04, 0x00, 0x00,
+	// 1689 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0x9c, 0x58, 0xcd, 0x6e, 0xdc, 0x46,
+	0x12, 0x36, 0x87, 0xe4, 0x68, 0xa6, 0xe6, 0x57, 0x94, 0x56, 0x3b, 0x20, 0xfc, 0xa3, 0xa5, 0x0d,
+	0xaf, 0x6c, 0x43, 0xb6, 0x2c, 0x03, 0xbb, 0x30, 0x76, 0x81, 0x5d, 0x4b, 0x36, 0xbc, 0x3f, 0xf6,
+	0x62, 0xd3, 0xb2, 0xe1, 0x33, 0x87, 0xec, 0x48, 0x8c, 0x38, 0xcd, 0x31, 0xc9, 0x91, 0xac, 0x53,
+	0x6e, 0xb9, 0xe4, 0x9c, 0xe7, 0x08, 0x10, 0x20, 0x0f, 0x90, 0x77, 0x08, 0xf2, 0x3c, 0x41, 0x57,
+	0x35, 0xc9, 0x26, 0x35, 0x1a, 0x2b, 0xb9, 0x75, 0x7d, 0xac, 0xea, 0xae, 0x2e, 0x7e, 0xf5, 0x43,
+	0x02, 0x04, 0x49, 0x24, 0x1e, 0xcf, 0xd3, 0x24, 0x4f, 0x1c, 0x33, 0x98, 0x4f, 0xbd, 0x29, 0xf4,
+	0xdf, 0x24, 0xc7, 0x91, 0x60, 0xfc, 0xe3, 0x82, 0x67, 0xb9, 0xe3, 0x80, 0x25, 0xfc, 0x19, 0x9f,
+	0x18, 0xdb, 0xc6, 0x4e, 0x97, 0xe1, 0x5a, 0x62, 0x79, 0x34, 0xe3, 0x93, 0x16, 0x61, 0x79, 0x44,
+	0xd8, 0x22, 0xe3, 0xe9, 0xc4, 0xdc, 0x36, 0x76, 0x06, 0x0c, 0xd7, 0xce, 0x16, 0xb4, 0x43, 0x7e,
+	0x16, 0x05, 0x7c, 0x62, 0xa1, 0xa6, 0x92, 0xbc, 0xfb, 0x30, 0x3e, 0x3c, 0xf1, 0xe3, 0x98, 0x8b,
+	0x63, 0xae, 0x9d, 0x83, 0xf6, 0x46, 0x65, 0xef, 0xdd, 0x83, 0xe1, 0x6b, 0x9e, 0x7f, 0x48, 0xd2,
+	0xd3, 0x15, 0xde, 0x78, 0xbb, 0x30, 0x7a, 0x21, 0x44, 0xb2, 0x10, 0x41, 0xb9, 0x99, 0x0b, 0xe6,
+	0x79, 0x24, 0x50, 0xab, 0xb7, 0xdf, 0x79, 0x1c, 0xcc, 0xa7, 0x8f, 0x3f, 0x44, 0x82, 0x49, 0x50,
+	0x1e, 0xfe, 0x9a, 0xe7, 0x87, 0xbe, 0x08, 0x78, 0xbc, 0x6a, 0xdb, 0x1f, 0x5b, 0xb0, 0xfe, 0xef,
+	0x2c, 0x5b, 0xf0, 0x83, 0x38, 0x09, 0x4a, 0x07, 0x36, 0xc1, 0x5e, 0xcc, 0xe7, 0xca, 0xcf, 0x3e,
+	0x23, 0x41, 0xa2, 0x71, 0x72, 0xce, 0x53, 0x8c, 0x48, 0x9f, 0x91, 0xe0, 0x6c, 0x43, 0x6f, 0x2a,
+	0x6d, 0x4f, 0x78, 0x74, 0x7c, 0x92, 0xab, 0xc8, 0xe8, 0x90, 0xb4, 0x43, 0x11, 0xe3, 0xd3, 0x67,
+	0x24, 0xc8, 0xb0, 0xcd, 0x78, 0x7a, 0x1a, 0xf3, 0x89, 0x8d, 0xb0, 0x92, 0xa4, 0x97, 0xd3, 0x28,
+	0xcf, 0x26, 0x6d, 0x0a, 0x91, 0x5c, 0x4b, 0xdd, 0x8c, 0xa7, 0x67, 0x3c, 0x9d, 0xac, 0x51, 0x88,
+	0x49, 0x92, 0x3b, 0xf3, 0x79, 0x12, 0x9c, 0x4c, 0x3a, 0xdb, 0xc6, 0x8e, 0xc5, 0x48, 0x90, 0x3b,
+	0x7c, 0xc9, 0x79, 0x36, 0xe9, 0x22, 0x88, 0x6b, 0xb9, 0x83, 0xe4, 0xc0, 0xf4, 0xe9, 0x04, 0xe8,
+	0x34, 0x92, 0x4a, 0x7c, 0x7f, 0xd2, 0xd3, 0xf0, 0x7d, 0xe7, 0x36, 0x00, 0xff, 0x94, 0xa7, 0xbe,
+	0x48, 0x44, 0xc0, 0x27, 0x7d, 0xf4, 0x45, 0x43, 0x54, 0x7c, 0x19, 0xcf, 0x16, 0x71, 0xbe, 0x2a,
+	0xbe, 0x77, 0x61, 0xf0, 0x26, 0x39, 0x4e, 0x16, 0x2b, 0x95, 0xe6, 0xe0, 0x1c, 0x2d, 0xa6, 0xb3,
+	0x28, 0x3f, 0x3a, 0xf1, 0x53, 0xbe, 0x8a, 0x93, 0x65, 0x28, 0x5b, 0x7a, 0x28, 0xc7, 0x60, 0x7e,
+	0x95, 0x4c, 0x31, 0xf4, 0x16, 0x93, 0xcb, 0x86, 0xfb, 0x14, 0x77, 0xdd, 0xfd, 0xe7, 0x30, 0x7a,
+	0xcd, 0xf3, 0x77, 0x7e, 0x1c, 0x5f, 0x7c, 0x26, 0x05, 0x90, 0xae, 0x2d, 0x8d, 0xae, 0xf7, 0x61,
+	0xfc, 0x2f, 0xee, 0xa7, 0xf9, 0x94, 0xfb, 0x2b, 0x2f, 0xf5, 0x06, 0x40, 0xa5, 0xd8, 0x3c, 0xbe,
+	0x70, 0x86, 0xd0, 0x8a, 0x42, 0x45, 0xfb, 0x56, 0x14, 0xca, 0x8b, 0xe4, 0xc9, 0x29, 0x17, 0x2a,
+	0xbb, 0x48, 0x70, 0x26, 0xb0, 0xc6, 0x3f, 0xcd, 0xa3, 0x94, 0x67, 0x78, 0x19, 0x93, 0x15, 0xa2,
+	0x77, 0x1f, 0x86, 0x5a, 0x32, 0xc9, 0x1d, 0x37, 0xc1, 0xa6, 0xdb, 0xd1, 0xa1, 0x24, 0x78, 0xbb,
+	0xd0, 0x2f, 0x93, 0x49, 0x6a, 0xdd, 0x02, 0xeb, 0x3c, 0x49, 0x4f, 0x55, 0x92, 0x74, 0x29, 0x49,
+	0xe4, 0x53, 0x84, 0xbd, 0xbf, 0xc2, 0xa0, 0xca, 0x2a, 0xe5, 0x67, 0x42, 0xda, 0x1d, 0xd6, 0x4a,
+	0x90, 0xa5, 0x29, 0xf7, 0xb3, 0xa4, 0x70, 0x54, 0x49, 0xde, 0x0e, 0x0c, 0xb5, 0xfc, 0x92, 0x96,
+	0x15, 0x47, 0x0d, 0x9d, 0xa3, 0xde, 0x9f, 0x60, 0xa4, 0x27, 0xd8, 0x92, 0x43, 0xbc, 0xff, 0xc0,
+	0x50, 0x23, 0x93, 0xd4, 0xd8, 0x86, 0xf6, 0x79, 0x24, 0x04, 0x4f, 0x2f, 0x65, 0xb7, 0xc2, 0xb5,
+	0xe3, 0x5a, 0xb5, 0xe3, 0x6e, 0x41, 0xaf, 0x20, 0xdc, 0xb2, 0xa3, 0x7e, 0x36, 0xc0, 0x92, 0xf7,
+	0x77, 0x5c, 0xe8, 0x20, 0xd5, 0xfd, 0x8c, 0xab, 0x2c, 0x2f, 0xe5, 0x2b, 0x58, 0xe6, 0x80, 0x95,
+	0x9d, 0xf2, 0x18, 0xdf, 0x4c, 0x9f, 0xe1, 0xba, 0x4c, 0x56, 0x4b, 0x4b, 0xd6, 0x4d, 0xb0, 0x33,
+	0xc9, 0x63, 0xcc, 0xeb, 0x01, 0x23, 0xa1, 0xe0, 0x68, 0xbb, 0xe2, 0xe8, 0x16, 0xb4, 0x23, 0x19,
+	0x98, 0x10, 0x93, 0xda, 0x64, 0x4a, 0x92, 0x7b, 0xce, 0x53, 0x7e, 0x86, 0x39, 0xdd, 0x67, 0xb8,
+	0x6e, 0xf0, 0xb9, 0x7b, 0x89, 0xcf, 0x5f, 0x83, 0xf9, 0x21, 0x12, 0x95, 0xe3, 0x86, 0xee, 0x78,
+	0xc9, 0x14, 0xa2, 0x31, 0x09, 0x32, 0x00, 0x51, 0xc8, 0x45, 0x1e, 0xe5, 0x17, 0x78, 0xa5, 0x2e,
+	0x2b, 0xe5, 0xc2, 0x59, 0xeb, 0xaa, 0x84, 0xb2, 0x2f, 0x39, 0xc0, 0x60, 0x5c, 0x4b, 0xe1, 0x2b,
+	0xb9, 0x24, 0xdf, 0x71, 0xc5, 0x25, 0x29, 0x69, 0x1c, 0x33, 0x6b, 0x1c, 0xfb, 0x02, 0x06, 0x55,
+	0x92, 0x12, 0x2b, 0xec, 0x59, 0x54, 0x91, 0x02, 0x90, 0x14, 0xf4, 0x9c, 0x1e, 0x38, 0xb7, 0xb5,
+	0x84, 0xad, 0x2b, 0x50, 0xf2, 0x7e, 0x63, 0x40, 0xff, 0xad, 0xd4, 0x7c, 0xcb, 0xb3, 0xcc, 0x3f,
+	0xc6, 0x0c, 0x3f, 0x8d, 0x44, 0x58, 0x64, 0xae, 0x5c, 0x5f, 0xbb, 0xc8, 0xa8, 0xfe, 0x63, 0x2d,
+	0xe9, 0x3f, 0x9f, 0x8d, 0xd7, 0x4f, 0x06, 0x0c, 0x8e, 0x90, 0xb1, 0xab, 0x3c, 0x29, 0xb2, 0xb7,
+	0xb5, 0x34, 0x7b, 0xb5, 0x1c, 0x30, 0x6b, 0x6d, 0xe1, 0x51, 0xc1, 0x40, 0x72, 0xed, 0x0f, 0x68,
+	0xd7, 0x7c, 0x3d, 0x05, 0x31, 0xef, 0xd1, 0x2d, 0x6c, 0x54, 0x75, 0x50, 0xb5, 0x56, 0x12, 0xe8,
+	0x3e, 0x4b, 0xba, 0x92, 0xac, 0x49, 0x5a, 0x25, 0x54, 0x35, 0xe9, 0x38, 0xf5, 0x55, 0x4d, 0x32,
+	0x19, 0x09, 0xde, 0x47, 0xb0, 0xf1, 0x1d, 0x48, 0xca, 0xf9, 0x41, 0xc0, 0xe7, 0x39, 0xa7, 0x6b,
+	0x5a, 0xac, 0x94, 0x31, 0x6b, 0x72, 0x3f, 0x26, 0x92, 0x5a, 0x8c, 0x04, 0xe7, 0x26, 0x74, 0xc3,
+	0xc5, 0x3c, 0x8e, 0x02, 0x3f, 0xe7, 0x2a, 0xf4, 0x15, 0x20, 0xcb, 0x65, 0x24, 0xce, 0xfc, 0x38,
+	0x0a, 0x15, 0x55, 0x0b, 0xd1, 0xfb, 0x3f, 0xc0, 0x81, 0x5f, 0x4e, 0x37, 0x63, 0x30, 0x4f, 0xf9,
+	0x85, 0x8a, 0xac, 0x5c, 0x4a, 0xcb, 0x8c, 0x07, 0x89, 0x08, 0x33, 0x3c, 0xcf, 0x64, 0x85, 0x78,
+	0x25, 0x19, 0xf7, 0xa0, 0x83, 0x3b, 0x2e, 0x23, 0xb6, 0x1c, 0x17, 0x44, 0x1e, 0xc5, 0x6a, 0x2f,
+	0x12, 0xbc, 0x01, 0xf4, 0x0e, 0x7c, 0x91, 0x29, 0x27, 0xbc, 0x07, 0xd0, 0x25, 0x51, 0xee, 0x70,
+	0x13, 0xac, 0xa9, 0x2f, 0xb2, 0x89, 0xb1, 0x6d, 0x96, 0xdc, 0x91, 0xdb, 0x23, 0xea, 0xbd, 0x02,
+	0xf3, 0xc0, 0x17, 0x4b, 0xdc, 0x5e, 0x7a, 0xd0, 0x95, 0x2e, 0xdf, 0x85, 0x01, 0x72, 0x3d, 0x5b,
+	0x35, 0x7d, 0x3d, 0x85, 0x5e, 0xa1, 0x24, 0x1d, 0xf3, 0xa0, 0x8d, 0x99, 0x54, 0xb8, 0x46, 0x29,
+	0x84, 0x1a, 0x4c, 0x3d, 0xf1, 0xbe, 0x33, 0xc0, 0x46, 0xe4, 0xba, 0x3d, 0x53, 0x75, 0x3f, 0xb3,
+	0xec, 0x7e, 0x9f, 0x69, 0xcf, 0x58, 0x6a, 0x39, 0x27, 0x52, 0x9a, 0x0c, 0xd7, 0x32, 0xf9, 0x73,
+	0xc9, 0x22, 0xa4, 0x60, 0x23, 0xf9, 0xf1, 0x81, 0x37, 0x84, 0x3e, 0x4b, 0x16, 0x22, 0x2c, 0x22,
+	0xfe, 0x8b, 0x01, 0xa0, 0x80, 0x15, 0x0d, 0xaa, 0x1a, 0xa2, 0x5a, 0xfa, 0x10, 0x75, 0x39, 0xdd,
+	0xb7, 0xa0, 0xad, 0x66, 0x3c, 0xaa, 0xf6, 0x4a, 0x2a, 0x53, 0xc3, 0xae, 0x0f, 0x6c, 0xa9, 0x1f,
+	0x44, 0xe2, 0x18, 0xbd, 0xed, 0x30, 0x25, 0x21, 0xef, 0x72, 0x3f, 0xcd, 0xcb, 0xa2, 0x5f, 0x88,
+	0x38, 0x0e, 0x52, 0xe0, 0x3b, 0xb4, 0x3b, 0x49, 0x65, 0x37, 0xe8, 0x52, 0x88, 0xe5, 0x5a, 0x5e,
+	0xf4, 0x7d, 0x56, 0xbd, 0x57, 0x6f, 0x17, 0x40, 0xc9, 0xf2, 0x9e, 0x77, 0xc0, 0x5e, 0x64, 0xd5,
+	0x1b, 0xa4, 0xaa, 0x21, 0x9f, 0x33, 0xc2, 0xbd, 0x27, 0xd0, 0x43, 0x51, 0xb1, 0xa2, 0x39, 0x9a,
+	0x28, 0xda, 0xb5, 0x4a, 0xda, 0x79, 0x0f, 0xa1, 0x4b, 0x06, 0x6a, 0xa2, 0x28, 0x49, 0x54, 0xdb,
+	0x9d, 0xf8, 0xf4, 0x09, 0xac, 0xf7, 0xd5, 0x2b, 0x5f, 0xb1, 0xab, 0xac, 0x06, 0x61, 0x94, 0xf9,
+	0xd3, 0x98, 0x13, 0x35, 0x3a, 0xac, 0x94, 0x65, 0x9c, 0x82, 0x94, 0xfb, 0x32, 0x4e, 0x16, 0xc5,
+	0x49, 0x89, 0xf2, 0x49, 0x9a, 0xe4, 0xf8, 0x84, 0xd8, 0x51, 0x88, 0x32, 0x2a, 0x2f, 0x53, 0xbf,
+	0xfc, 0xa6, 0xf1, 0x6e, 0x02, 0x28, 0x79, 0xd9, 0x20, 0x30, 0x86, 0x21, 0xd5, 0xdf, 0x32, 0x8a,
+	0x7f, 0x83, 0x7e, 0x89, 0x48, 0x8b, 0x47, 0xb2, 0x46, 0xa4, 0x67, 0x55, 0x24, 0xd7, 0xa9, 0x8e,
+	0x22, 0x76, 0x94, 0xfb, 0xf9, 0x22, 0x63, 0x85, 0x86, 0xf7, 0x4f, 0xe8, 0xeb, 0x0f, 0xa4, 0x9b,
+	0x7e, 0x18, 0xa6, 0x3c, 0xcb, 0x14, 0xdb, 0x0a, 0x11, 0x69, 0x88, 0x3a, 0xe5, 0xe0, 0x82, 0x92,
+	0xb7, 0x0e, 0xa3, 0x77, 0x7c, 0x36, 0x8f, 0xfd, 0xbc, 0x98, 0x80, 0xbd, 0x3b, 0x30, 0xa8, 0xa0,
+	0x25, 0x97, 0xd8, 0xff, 0xc1, 0x06, 0xeb, 0x30, 0x89, 0x84, 0xb3, 0x0b, 0x36, 0x0e, 0x9b, 0x0e,
+	0xf9, 0xa8, 0x7f, 0xdb, 0xb9, 0x23, 0x1d, 0x9a, 0xc7, 0x17, 0xde, 0x0d, 0xe7, 0x39, 0x74, 0xcb,
+	0x69, 0xd2, 0xa1, 0xf6, 0xd0, 0xfc, 0x54, 0x73, 0x37, 0x9a, 0x30, 0x99, 0x3e, 0x83, 0x35, 0x35,
+	0x60, 0x3a, 0xa4, 0x51, 0xff, 0x76, 0x73, 0xd7, 0xeb, 0x20, 0x19, 0xfd, 0x05, 0x3a, 0x45, 0x4f,
+	0x71, 0x36, 0x1b, 0x2d, 0x86, 0xcc, 0x96, 0x34, 0x1e, 0xf2, 0xb3, 0x9c, 0x32, 0x95, 0x9f, 0xcd,
+	0xaf, 0x3a, 0x77, 0xa3, 0x09, 0x93, 0xe9, 0xdf, 0x01, 0xaa, 0xb1, 0xd3, 0xd9, 0x42, 0xa5, 0x4b,
+	0x1f, 0x7a, 0xee, 0xe6, 0x25, 0x5c, 0x3f, 0x98, 0x26, 0xd2, 0xea, 0xe0, 0xda, 0xe7, 0x8e, 0xbb,
+	0xd1, 0x84, 0xc9, 0x74, 0x0f, 0xda, 0x34, 0x80, 0x3a, 0x4e, 0x11, 0xf8, 0xea, 0xf3, 0xc7, 0x1d,
+	0xd7, 0x30, 0xb2, 0xf8, 0x07, 0xf4, 0xb4, 0xe6, 0xec, 0xfc, 0xf1, 0x72, 0xbb, 0x26, 0xdb, 0xe5,
+	0x7d, 0x9c, 0xc2, 0x5b, 0x0c, 0x4a, 0x2a, 0xbc, 0x8d, 0x8f, 0x1b, 0xd7, 0x69, 0xa0, 0xc5, 0x2d,
+	0x41, 0xd6, 0xf1, 0xa3, 0x3c, 0xe5, 0xfe, 0x4c, 0x51, 0x47, 0x9f, 0x8e, 0x94, 0x59, 0x6d, 0x4e,
+	0xf1, 0x6e, 0xec, 0x18, 0x7b, 0x86, 0x0c, 0x50, 0xd9, 0xfb, 0x55, 0x80, 0x9a, 0x5f, 0x45, 0xee,
+	0x46, 0x13, 0xc6, 0x53, 0xf7, 0xbf, 0xb5, 0xc0, 0x7e, 0x11, 0xce, 0x22, 0xe1, 0xfc, 0x99, 0xfa,
+	0xdc, 0xa8, 0x6c, 0x7f, 0xca, 0x70, 0x50, 0x01, 0xe4, 0xe8, 0x03, 0xb0, 0xdf, 0x8b, 0xe9, 0xb5,
+	0x54, 0x1f, 0x82, 0x25, 0xdb, 0xac, 0x33, 0x2e, 0x1e, 0x14, 0xf9, 0xed, 0x0e, 0x35, 0xa4, 0x7c,
+	0x55, 0x6f, 0x55, 0x95, 0xad, 0xee, 0x9e, 0xd5, 0x5f, 0x95, 0xd6, 0x1c, 0xbd, 0x1b, 0x32, 0xcf,
+	0xb0, 0xa3, 0xa8, 0x60, 0xe9, 0xed, 0xc6, 0x1d, 0xe9, 0x50, 0xa9, 0x8e, 0x85, 0x59, 0xa9, 0xeb,
+	0x45, 0xdb, 0x1d, 0xe9, 0x50, 0xa1, 0xbe, 0xf6, 0x22, 0x0c, 0x25, 0xa4, 0xdc, 0xd7, 0xca, 0xb4,
+	0x3b, 0xd4, 0x10, 0x52, 0x7f, 0x0a, 0xbd, 0x97, 0x54, 0x30, 0xaf, 0x6d, 0xb2, 0x07, 0xf0, 0x4a,
+	0xfc, 0x26, 0x8b, 0x27, 0xd0, 0x65, 0x58, 0x60, 0xff, 0xcb, 0x2f, 0xae, 0x65, 0xb0, 0x0b, 0x36,
+	0x96, 0x5d, 0x75, 0x67, 0xbd, 0x24, 0xbb, 0x23, 0x1d, 0x22, 0x36, 0x7c, 0x6f, 0xc0, 0xf0, 0x30,
+	0x11, 0xe1, 0x22, 0xc8, 0x93, 0x94, 0x68, 0xf1, 0x0c, 0xd6, 0x54, 0x21, 0x56, 0x25, 0xa6, 0x5e,
+	0xa8, 0xdd, 0xf5, 0x3a, 0xf8, 0xbb, 0xde, 0xcc, 0x73, 0xe8, 0xfd, 0x8f, 0x9f, 0x17, 0xd5, 0x55,
+	0x65, 0x4d, 0xa3, 0xfe, 0xba, 0x4e, 0x03, 0x45, 0xd3, 0x69, 0x1b, 0xff, 0xa3, 0x3d, 0xfb, 0x75,
+	0x00, 0x15, 0x11, 0x15, 0xfb, 0x55, 0x13, 0x00, 0x00,
 }
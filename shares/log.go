@@ -0,0 +1,163 @@
+package shares
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Entry is an accepted share as it is weighed for rewards
+type Entry struct {
+	Time       time.Time `json:"time"`
+	Miner      string    `json:"miner"`
+	ID         uint32    `json:"id,omitempty"` // the miner id, the same across logins
+	User       uint32    `json:"user"`
+	Difficulty float64   `json:"difficulty"` // of the share target the miner was set
+}
+
+// Log is the append-only log of accepted shares, one JSON entry per line
+type Log struct {
+	sync.Mutex
+	f    *os.File
+	path string
+}
+
+// OpenLog opens (creating if need be) the share log at path for appending,
+// cutting off an entry torn by a crash
+func OpenLog(path string) (*Log, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+	_, end, err := scan(f, path)
+	if err == nil {
+		err = f.Truncate(end)
+	}
+	if err == nil {
+		_, err = f.Seek(end, io.SeekStart)
+	}
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &Log{f: f, path: path}, nil
+}
+
+// Append writes e. Shares are many and individually cheap, so unlike a
+// round they are not synced: a crash may lose the last few
+func (l *Log) Append(e Entry) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	l.Lock()
+	defer l.Unlock()
+	_, err = l.f.Write(append(line, '\n'))
+	return err
+}
+
+// Prune drops the oldest n entries, which can no longer count for a block,
+// see rewards.Outside. The log is rewritten whole, so that a crash leaves
+// the previous copy, and appending carries on at its end
+func (l *Log) Prune(n int) error {
+	if n <= 0 {
+		return nil
+	}
+	l.Lock()
+	defer l.Unlock()
+	if _, err := l.f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	entries, _, err := scan(l.f, l.path)
+	if _, serr := l.f.Seek(0, io.SeekEnd); err == nil {
+		err = serr
+	}
+	if err != nil {
+		return err
+	}
+	if n > len(entries) {
+		n = len(entries)
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(l.path), filepath.Base(l.path)+".")
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(tmp)
+	for _, e := range entries[n:] {
+		line, _ := json.Marshal(e)
+		w.Write(append(line, '\n'))
+	}
+	err = w.Flush()
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0644)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), l.path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	f, err := os.OpenFile(l.path, os.O_RDWR|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	l.f.Close()
+	l.f = f
+	return nil
+}
+
+// Close closes the underlying file
+func (l *Log) Close() error {
+	return l.f.Close()
+}
+
+// ReadLog returns every entry in the share log at path, oldest first
+func ReadLog(path string) ([]Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	entries, _, err := scan(f, path)
+	return entries, err
+}
+
+// scan decodes the entries in r and returns the offset just past the last complete one
+func scan(r io.Reader, path string) ([]Entry, int64, error) {
+	var (
+		entries []Entry
+		end     int64
+	)
+	reader := bufio.NewReader(r)
+	for n := 1; ; n++ {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF { // no newline: empty, or the torn tail
+			return entries, end, nil
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		if text := strings.TrimSpace(string(line)); text != "" {
+			var e Entry
+			if err := json.Unmarshal([]byte(text), &e); err != nil {
+				return nil, 0, fmt.Errorf("%s line %d: %v", path, n, err)
+			}
+			entries = append(entries, e)
+		}
+		end += int64(len(line))
+	}
+}
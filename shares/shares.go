@@ -0,0 +1,151 @@
+// Package shares keeps a server's account of the shares submitted by its
+// miners. Tallies are kept per miner, by the miner id of its device, and per
+// user and survive restarts, so that contributions can be measured across
+// rounds and logins.
+package shares
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+)
+
+// Result is the verdict on a submitted share
+type Result string
+
+// The possible verdicts
+const (
+	Accepted  Result = "accepted"  // meets the share target for the current job
+	Stale     Result = "stale"     // for a job that is no longer current
+	Duplicate Result = "duplicate" // already submitted for this job
+	Invalid   Result = "invalid"   // malformed, not our work, or above the share target
+)
+
+// Tally counts shares by verdict
+type Tally struct {
+	Accepted  uint64 `json:"accepted"`
+	Stale     uint64 `json:"stale"`
+	Duplicate uint64 `json:"duplicate"`
+	Invalid   uint64 `json:"invalid"`
+}
+
+func (t *Tally) add(r Result) {
+	switch r {
+	case Accepted:
+		t.Accepted++
+	case Stale:
+		t.Stale++
+	case Duplicate:
+		t.Duplicate++
+	case Invalid:
+		t.Invalid++
+	}
+}
+
+// Book holds the tallies of every miner, by miner id, and of every user
+type Book struct {
+	sync.Mutex
+	Miners map[uint32]*Tally `json:"miners"`
+	Users  map[uint32]*Tally `json:"users"`
+}
+
+// NewBook returns an empty book
+func NewBook() *Book {
+	return &Book{Miners: make(map[uint32]*Tally), Users: make(map[uint32]*Tally)}
+}
+
+// Add counts a share with verdict r from miner id miner, owned by user
+func (b *Book) Add(miner, user uint32, r Result) {
+	b.Lock()
+	defer b.Unlock()
+	if b.Miners[miner] == nil {
+		b.Miners[miner] = new(Tally)
+	}
+	if b.Users[user] == nil {
+		b.Users[user] = new(Tally)
+	}
+	b.Miners[miner].add(r)
+	b.Users[user].add(r)
+}
+
+// Miner returns the tally of miner id miner, zero if it has submitted nothing
+func (b *Book) Miner(miner uint32) Tally {
+	b.Lock()
+	defer b.Unlock()
+	if t := b.Miners[miner]; t != nil {
+		return *t
+	}
+	return Tally{}
+}
+
+// User returns the tally of all the miners of user
+func (b *Book) User(user uint32) Tally {
+	b.Lock()
+	defer b.Unlock()
+	if t := b.Users[user]; t != nil {
+		return *t
+	}
+	return Tally{}
+}
+
+// Save writes the book to path, replacing it whole so that a crash leaves the previous copy
+func (b *Book) Save(path string) error {
+	b.Lock()
+	data, err := json.MarshalIndent(b, "", "  ")
+	b.Unlock()
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Load reads the book saved at path, an empty book if there is none yet.
+// Books saved before miner ids kept miners by login, one tally a session;
+// those are dropped, their users' tallies count them still
+func Load(path string) (*Book, error) {
+	b := NewBook()
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return b, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var saved struct {
+		Miners map[string]*Tally `json:"miners"`
+		Users  map[uint32]*Tally `json:"users"`
+	}
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, err
+	}
+	for key, t := range saved.Miners {
+		if id, err := strconv.ParseUint(key, 10, 32); err == nil {
+			b.Miners[uint32(id)] = t
+		}
+	}
+	if saved.Users != nil {
+		b.Users = saved.Users
+	}
+	return b, nil
+}
@@ -0,0 +1,120 @@
+package shares
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBookSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "shares.json")
+	b, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.Add(5001, 1, Accepted)
+	b.Add(5001, 1, Accepted)
+	b.Add(5001, 1, Duplicate)
+	b.Add(5002, 1, Stale)
+	b.Add(5003, 2, Invalid)
+	if err := b.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err = Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := b.Miner(5001), (Tally{Accepted: 2, Duplicate: 1}); got != exp {
+		t.Errorf("miner\nExp: %+v\nGot: %+v\n", exp, got)
+	}
+	if got, exp := b.User(1), (Tally{Accepted: 2, Stale: 1, Duplicate: 1}); got != exp {
+		t.Errorf("user 1\nExp: %+v\nGot: %+v\n", exp, got)
+	}
+	if got, exp := b.User(2), (Tally{Invalid: 1}); got != exp {
+		t.Errorf("user 2\nExp: %+v\nGot: %+v\n", exp, got)
+	}
+	if got := b.Miner(9); got != (Tally{}) {
+		t.Errorf("unknown miner should have an empty tally, got %+v", got)
+	}
+	b.Add(5003, 2, Accepted) // counting goes on after a reload
+	if got := b.User(2).Accepted; got != 1 {
+		t.Errorf("expected 1 accepted for user 2, got %d", got)
+	}
+}
+
+func TestLoadByLogin(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "shares.json")
+	old := `{"miners": {"0:abc": {"accepted": 3}, "5001": {"accepted": 1}}, "users": {"1": {"accepted": 4}}}`
+	if err := ioutil.WriteFile(path, []byte(old), 0644); err != nil {
+		t.Fatal(err)
+	}
+	b, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b.Miners) != 1 || b.Miner(5001).Accepted != 1 || b.User(1).Accepted != 4 {
+		t.Errorf("expected the tallies by login dropped, the user's kept: %+v %+v", b.Miners, b.Users)
+	}
+}
+
+func TestLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "shares.log")
+	l, err := OpenLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2016, 10, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		if err := l.Append(Entry{Time: start.Add(time.Duration(i) * time.Second), Miner: "0:abc", User: 1, Difficulty: 0.5}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	l.f.WriteString(`{"time":"2016-10`) // torn by a crash
+	l.Close()
+
+	l, err = OpenLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Append(Entry{Time: start.Add(time.Minute), Miner: "0:def", User: 2, Difficulty: 1})
+	l.Close()
+	entries, err := ReadLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 4 || entries[3].User != 2 || entries[0].Difficulty != 0.5 {
+		t.Errorf("unexpected entries after torn write: %+v", entries)
+	}
+}
+
+func TestLogPrune(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "shares.log")
+	l, err := OpenLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	start := time.Date(2016, 10, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		l.Append(Entry{Time: start.Add(time.Duration(i) * time.Second), ID: 5001, User: 1, Difficulty: float64(i)})
+	}
+	if err := l.Prune(3); err != nil {
+		t.Fatal(err)
+	}
+	l.Append(Entry{Time: start.Add(time.Minute), ID: 5002, User: 2, Difficulty: 9})
+	entries, err := ReadLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 || entries[0].Difficulty != 3 || entries[2].ID != 5002 {
+		t.Errorf("expected the 2 newest and the one appended after, got %+v", entries)
+	}
+	if err := l.Prune(10); err != nil {
+		t.Fatal(err)
+	}
+	if entries, _ := ReadLog(path); len(entries) != 0 {
+		t.Errorf("expected an empty log, got %+v", entries)
+	}
+}
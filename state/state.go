@@ -0,0 +1,91 @@
+// Package state keeps what a server knows of the round in a file: the job
+// being raced for, the miners logged in with their sessions, miner ids and
+// extranonce partitions, and the epoch of its conductor. A server restarted
+// after a crash loads it and carries on, and its miners resume their
+// sessions and their work without logging in again.
+package state
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"coin/extranonce"
+)
+
+// Job is the template being raced for, as the conductor issued it
+type Job struct {
+	ID             uint64
+	Issued         time.Time
+	Upper, Lower   []byte // the coinbase either side of the miner's part
+	Height         uint32
+	Block          []byte // the partial header
+	Merkle         []byte // the merkle root skeleton
+	Bits           uint32
+	Fees           uint64
+	Coinb1, Coinb2 []byte // an upstream pool's coinbase, in place of Upper and Lower
+	Extranonce     int    // bytes of extranonce between Coinb1 and Coinb2
+}
+
+// Miner is a miner logged in, and its session
+type Miner struct {
+	Name      string // the login
+	User      uint32
+	ID        uint32 // the miner id of its device
+	Partition extranonce.Partition
+	Token     string // the session token, a secret
+	Expires   time.Time
+}
+
+// Round is the state of a server
+type Round struct {
+	Saved  time.Time
+	Server string // the name the conductor gives the server
+	Epoch  uint64 // of the conductor
+	Job    *Job   // nil before the first
+	Racing bool   // the race for Job is on
+	Miners []Miner
+}
+
+// Save writes r to path, replacing it whole so that a crash leaves the
+// previous copy. The file is readable by its owner only, it holds the tokens
+func (r *Round) Save(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".") // mode 0600
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Load reads the round saved at path, an empty round if there is none yet
+func Load(path string) (*Round, error) {
+	r := new(Round)
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
@@ -0,0 +1,60 @@
+package state
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"coin/extranonce"
+)
+
+func TestSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	r, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Job != nil || len(r.Miners) != 0 {
+		t.Fatalf("expected an empty round before the first save, got %+v", r)
+	}
+
+	now := time.Unix(0x57f00000, 0).UTC()
+	r = &Round{
+		Saved:  now,
+		Server: "localhost:50051",
+		Epoch:  3,
+		Job:    &Job{ID: 100, Issued: now, Upper: []byte{1}, Lower: []byte{2}, Height: 7, Block: make([]byte, 80), Bits: 0x207fffff},
+		Racing: true,
+		Miners: []Miner{{Name: "abc", User: 1, ID: 5001, Partition: extranonce.Partition{Prefix: 1, Len: 2}, Token: "t", Expires: now}},
+	}
+	if err := r.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	if fi, err := os.Stat(path); err != nil || fi.Mode().Perm() != 0600 {
+		t.Errorf("expected the file readable by its owner only: %v %v", fi.Mode(), err)
+	}
+
+	got, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Server != r.Server || got.Epoch != 3 || !got.Racing || !got.Saved.Equal(now) {
+		t.Errorf("round\nExp: %+v\nGot: %+v\n", r, got)
+	}
+	if got.Job == nil || got.Job.ID != 100 || got.Job.Height != 7 || !bytes.Equal(got.Job.Block, r.Job.Block) || got.Job.Coinb1 != nil {
+		t.Errorf("job\nExp: %+v\nGot: %+v\n", r.Job, got.Job)
+	}
+	if len(got.Miners) != 1 || got.Miners[0].Partition != r.Miners[0].Partition || got.Miners[0].Token != "t" {
+		t.Errorf("miners\nExp: %+v\nGot: %+v\n", r.Miners, got.Miners)
+	}
+
+	if err := ioutil.WriteFile(path, []byte("{"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Error("expected an error for a damaged file")
+	}
+}
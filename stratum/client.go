@@ -0,0 +1,255 @@
+package stratum
+
+import (
+	"bufio"
+	"coin"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"sync"
+	"time"
+)
+
+// ErrClosed is returned for calls on a connection that has gone
+var ErrClosed = errors.New("stratum connection closed")
+
+const callTimeout = 30 * time.Second
+
+// Client is the miner's end of a Stratum connection to an upstream pool
+type Client struct {
+	Extranonce1     []byte // set by the pool at mining.subscribe
+	Extranonce2Size int
+
+	conn       net.Conn
+	enc        *json.Encoder
+	mu         sync.Mutex // serialises writes, guards the fields below
+	next       int
+	pending    map[int]chan message
+	difficulty float64
+	jobs       chan *Job
+	done       chan struct{}
+}
+
+// message is any line, a request or notification from the pool or a reply
+type message struct {
+	ID     json.RawMessage   `json:"id"`
+	Method string            `json:"method"`
+	Params []json.RawMessage `json:"params"`
+	Result json.RawMessage   `json:"result"`
+	Error  *Error            `json:"error"`
+}
+
+// Dial connects to the pool at addr and subscribes as agent
+func Dial(addr, agent string) (*Client, error) {
+	conn, err := net.DialTimeout("tcp", addr, callTimeout)
+	if err != nil {
+		return nil, err
+	}
+	c := &Client{conn: conn, enc: json.NewEncoder(conn), pending: make(map[int]chan message),
+		difficulty: 1, jobs: make(chan *Job, 4), done: make(chan struct{})}
+	go c.read()
+	var sub []json.RawMessage
+	if err := c.call(&sub, "mining.subscribe", agent); err != nil {
+		c.Close()
+		return nil, err
+	}
+	var en1 string
+	if len(sub) < 3 || json.Unmarshal(sub[1], &en1) != nil || json.Unmarshal(sub[2], &c.Extranonce2Size) != nil {
+		c.Close()
+		return nil, fmt.Errorf("bad mining.subscribe reply %s", sub)
+	}
+	if c.Extranonce1, err = hex.DecodeString(en1); err != nil {
+		c.Close()
+		return nil, fmt.Errorf("bad extranonce1 %q", en1)
+	}
+	return c, nil
+}
+
+// Authorize logs in worker with password
+func (c *Client) Authorize(worker, password string) error {
+	var ok bool
+	if err := c.call(&ok, "mining.authorize", worker, password); err != nil {
+		return err
+	}
+	if !ok {
+		return ErrUnauthorized
+	}
+	return nil
+}
+
+// Submit sends a share, the pool refuses it with an *Error
+func (c *Client) Submit(sub Submit) error {
+	var ok bool
+	err := c.call(&ok, "mining.submit", sub.Worker, sub.JobID, hex.EncodeToString(sub.Extranonce2),
+		fmt.Sprintf("%08x", sub.Time), fmt.Sprintf("%08x", sub.Nonce))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrOther
+	}
+	return nil
+}
+
+// Jobs delivers the pool's jobs, each with the difficulty then in force. It
+// is closed with the connection and must be drained
+func (c *Client) Jobs() <-chan *Job {
+	return c.jobs
+}
+
+// Done is closed when the connection has gone
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+// Close ends the connection
+func (c *Client) Close() {
+	c.conn.Close()
+}
+
+// call sends method and decodes its result into result
+func (c *Client) call(result interface{}, method string, params ...interface{}) error {
+	c.mu.Lock()
+	c.next++
+	id := c.next
+	reply := make(chan message, 1)
+	c.pending[id] = reply
+	err := c.enc.Encode(map[string]interface{}{"id": id, "method": method, "params": params})
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+	}()
+	if err != nil {
+		return err
+	}
+	select {
+	case m := <-reply:
+		if m.Error != nil {
+			return m.Error
+		}
+		return json.Unmarshal(m.Result, result)
+	case <-c.done:
+		return ErrClosed
+	case <-time.After(callTimeout):
+		return fmt.Errorf("no reply to %s", method)
+	}
+}
+
+// read dispatches replies to their calls and handles notifications
+func (c *Client) read() {
+	defer func() {
+		close(c.done)
+		close(c.jobs)
+	}()
+	in := bufio.NewScanner(c.conn)
+	for in.Scan() {
+		var m message
+		if err := json.Unmarshal(in.Bytes(), &m); err != nil {
+			c.Close()
+			return
+		}
+		switch m.Method {
+		case "": // a reply
+			var id int
+			if json.Unmarshal(m.ID, &id) != nil {
+				continue
+			}
+			c.mu.Lock()
+			reply := c.pending[id]
+			c.mu.Unlock()
+			if reply != nil {
+				reply <- m
+			}
+		case "mining.set_difficulty":
+			var d float64
+			if len(m.Params) > 0 && json.Unmarshal(m.Params[0], &d) == nil && d > 0 {
+				c.mu.Lock()
+				c.difficulty = d
+				c.mu.Unlock()
+			}
+		case "mining.notify":
+			j, err := parseJob(m.Params)
+			if err != nil {
+				continue
+			}
+			c.mu.Lock()
+			j.Difficulty = c.difficulty
+			c.mu.Unlock()
+			c.jobs <- j
+		}
+	}
+}
+
+// parseJob is the inverse of Job.params
+func parseJob(params []json.RawMessage) (*Job, error) {
+	if len(params) < 9 {
+		return nil, fmt.Errorf("mining.notify with %d params", len(params))
+	}
+	j := &Job{}
+	var prev, coinb1, coinb2, version, bits, time string
+	var branch []string
+	if err := stringParams(params, &j.ID, &prev, &coinb1, &coinb2); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(params[4], &branch); err != nil {
+		return nil, err
+	}
+	if err := stringParams(params[5:], &version, &bits, &time); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(params[8], &j.Clean); err != nil {
+		return nil, err
+	}
+	var err error
+	if j.Prev, err = hex.DecodeString(prev); err != nil || len(j.Prev) != 32 {
+		return nil, fmt.Errorf("bad prevhash %q", prev)
+	}
+	for i := 0; i < 32; i += 4 {
+		copy(j.Prev[i:i+4], coin.Reverse(j.Prev[i:i+4]))
+	}
+	if j.Coinb1, err = hex.DecodeString(coinb1); err != nil {
+		return nil, err
+	}
+	if j.Coinb2, err = hex.DecodeString(coinb2); err != nil {
+		return nil, err
+	}
+	for _, b := range branch {
+		h, err := hex.DecodeString(b)
+		if err != nil || len(h) != 32 {
+			return nil, fmt.Errorf("bad merkle branch %q", b)
+		}
+		j.Branch = append(j.Branch, h)
+	}
+	if j.Version, err = hexUint32(version); err != nil {
+		return nil, err
+	}
+	if j.Bits, err = hexUint32(bits); err != nil {
+		return nil, err
+	}
+	if j.Time, err = hexUint32(time); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// Target is the share target of difficulty d, the difficulty 1 target over d.
+// It is capped at 32 bytes of 0xff
+func Target(d float64) []byte {
+	one := new(big.Float).SetInt(new(big.Int).SetBytes(coin.Bits2Target(0x1d00ffff)))
+	t, _ := new(big.Float).Quo(one, big.NewFloat(d)).Int(nil)
+	target := make([]byte, 32)
+	if len(t.Bytes()) > 32 {
+		for i := range target {
+			target[i] = 0xff
+		}
+		return target
+	}
+	b := t.Bytes()
+	copy(target[32-len(b):], b)
+	return target
+}
@@ -0,0 +1,364 @@
+// Package stratum serves the Stratum v1 mining protocol, newline separated
+// JSON-RPC over TCP, so that standard mining software can join a pool. The
+// Server speaks the protocol; a Handler admits workers and judges their
+// submissions, and pushes jobs and difficulty to each Session.
+//
+// Each session's coinbase is coinb1 + extranonce1 + extranonce2 + coinb2: the
+// server gives every session its own extranonce1, and the miner rolls
+// extranonce2 through the extranonce2 size.
+package stratum
+
+import (
+	"bufio"
+	"coin"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"sync"
+)
+
+// Error is a Stratum error, sent as [code, message, null]
+type Error struct {
+	Code    int
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%d %s", e.Code, e.Message)
+}
+
+// MarshalJSON implements json.Marshaler
+func (e *Error) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]interface{}{e.Code, e.Message, nil})
+}
+
+// UnmarshalJSON implements json.Unmarshaler, for the errors of a pool
+func (e *Error) UnmarshalJSON(b []byte) error {
+	var a []interface{}
+	if err := json.Unmarshal(b, &a); err != nil || len(a) < 2 {
+		var s string // some pools send a bare message
+		if err := json.Unmarshal(b, &s); err != nil {
+			return fmt.Errorf("bad stratum error %s", b)
+		}
+		e.Code, e.Message = 20, s
+		return nil
+	}
+	code, _ := a[0].(float64)
+	e.Code = int(code)
+	e.Message = fmt.Sprint(a[1])
+	return nil
+}
+
+// The standard errors
+var (
+	ErrOther         = &Error{20, "Other/Unknown"}
+	ErrJobNotFound   = &Error{21, "Job not found"}
+	ErrDuplicate     = &Error{22, "Duplicate share"}
+	ErrLowDiff       = &Error{23, "Low difficulty share"}
+	ErrUnauthorized  = &Error{24, "Unauthorized worker"}
+	ErrNotSubscribed = &Error{25, "Not subscribed"}
+)
+
+// Job is a mining.notify: the template a session's miner works on
+type Job struct {
+	ID                  string
+	Prev                []byte   // previous block hash, as in the header
+	Coinb1              []byte   // coinbase up to extranonce1
+	Coinb2              []byte   // coinbase after extranonce2
+	Branch              [][]byte // merkle branch of the coinbase, from the skeleton
+	Version, Bits, Time uint32
+	Clean               bool    // abandon earlier jobs
+	Difficulty          float64 // share difficulty, as set by a Client's pool
+}
+
+// Header rebuilds the block header, and coinbase, of a miner's work on j
+func (j *Job) Header(extranonce1, extranonce2 []byte, time, nonce uint32) (header, coinbase []byte, err error) {
+	if len(j.Prev) != 32 {
+		return nil, nil, errors.New("job has no previous block")
+	}
+	coinbase = make([]byte, 0, len(j.Coinb1)+len(extranonce1)+len(extranonce2)+len(j.Coinb2))
+	coinbase = append(append(append(append(coinbase, j.Coinb1...), extranonce1...), extranonce2...), j.Coinb2...)
+	root, err := coin.DoubleSha256(coinbase)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, b := range j.Branch {
+		root = coin.Hash2(root, b)
+	}
+	header = make([]byte, 80)
+	binary.LittleEndian.PutUint32(header[0:], j.Version)
+	copy(header[4:36], j.Prev)
+	copy(header[36:68], root)
+	binary.LittleEndian.PutUint32(header[68:], time)
+	binary.LittleEndian.PutUint32(header[72:], j.Bits)
+	binary.LittleEndian.PutUint32(header[76:], nonce)
+	return header, coinbase, nil
+}
+
+// Height is the block height that BIP 34 puts at the start of the coinbase script
+func (j *Job) Height() (uint32, error) {
+	const script = 42 // version, input count, previous output and script length
+	if len(j.Coinb1) <= script {
+		return 0, errors.New("coinbase too short for a height")
+	}
+	n := int(j.Coinb1[script])
+	if n < 1 || n > 4 || len(j.Coinb1) <= script+n {
+		return 0, errors.New("coinbase script does not start with a height")
+	}
+	var h uint32
+	for i := n; i > 0; i-- {
+		h = h<<8 | uint32(j.Coinb1[script+i])
+	}
+	return h, nil
+}
+
+// params is the Job as mining.notify sends it. The previous block hash goes
+// with the bytes of each 4 byte word swapped, as miners expect
+func (j *Job) params() []interface{} {
+	prev := make([]byte, 32)
+	for i := 0; i < 32; i += 4 {
+		copy(prev[i:i+4], coin.Reverse(j.Prev[i:i+4]))
+	}
+	branch := make([]string, len(j.Branch))
+	for i, b := range j.Branch {
+		branch[i] = hex.EncodeToString(b)
+	}
+	return []interface{}{j.ID, hex.EncodeToString(prev), hex.EncodeToString(j.Coinb1), hex.EncodeToString(j.Coinb2),
+		branch, fmt.Sprintf("%08x", j.Version), fmt.Sprintf("%08x", j.Bits), fmt.Sprintf("%08x", j.Time), j.Clean}
+}
+
+// Submit is a mining.submit
+type Submit struct {
+	Worker      string
+	JobID       string
+	Extranonce2 []byte
+	Time, Nonce uint32
+}
+
+// Handler admits the workers of a Server and judges their work
+type Handler interface {
+	// Authorize admits worker on s with password, returning an *Error or
+	// other error to refuse it. Once admitted the handler sends s its jobs
+	Authorize(s *Session, worker, password string) error
+	// Submit judges a share, returning an *Error to refuse it
+	Submit(s *Session, sub Submit) error
+	// Closed tells the handler that the connection of s, once subscribed, has gone
+	Closed(s *Session)
+}
+
+// Server serves Stratum connections to a Handler. Sessions are numbered
+// from 1 and a session's extranonce1 is its number in Extranonce1Size bytes,
+// unless Extranonce1 gives it one
+type Server struct {
+	Handler                          Handler
+	Extranonce1Size, Extranonce2Size int
+	Extranonce1                      func(s *Session) ([]byte, error) // on mining.subscribe
+
+	mu   sync.Mutex
+	next uint64
+}
+
+// Serve accepts connections on l until it fails
+func (srv *Server) Serve(l net.Listener) error {
+	for {
+		c, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go srv.ServeConn(c)
+	}
+}
+
+// ServeConn speaks Stratum on c until it is closed
+func (srv *Server) ServeConn(c net.Conn) {
+	srv.mu.Lock()
+	srv.next++
+	id := srv.next
+	srv.mu.Unlock()
+	s := &Session{ID: id, conn: c, enc: json.NewEncoder(c), done: make(chan struct{})}
+	defer func() {
+		close(s.done)
+		c.Close()
+		if s.Extranonce1 != nil {
+			srv.Handler.Closed(s)
+		}
+	}()
+	in := bufio.NewScanner(c)
+	for in.Scan() {
+		var req request
+		if err := json.Unmarshal(in.Bytes(), &req); err != nil {
+			log.Printf("stratum session %d: %v", id, err)
+			return
+		}
+		result, err := srv.handle(s, &req)
+		if req.ID == nil { // a notification, no reply
+			continue
+		}
+		if err := s.reply(req.ID, result, err); err != nil {
+			return
+		}
+	}
+}
+
+type request struct {
+	ID     interface{}       `json:"id"`
+	Method string            `json:"method"`
+	Params []json.RawMessage `json:"params"`
+}
+
+// handle answers one request from s
+func (srv *Server) handle(s *Session, req *request) (interface{}, error) {
+	switch req.Method {
+	case "mining.subscribe":
+		if s.Extranonce1 == nil {
+			en1, err := srv.extranonce1(s)
+			if err != nil {
+				return nil, err
+			}
+			s.Extranonce1 = en1
+		}
+		sub := strconv.FormatUint(s.ID, 16)
+		return []interface{}{
+			[][]string{{"mining.set_difficulty", sub}, {"mining.notify", sub}},
+			hex.EncodeToString(s.Extranonce1), srv.Extranonce2Size}, nil
+	case "mining.extranonce.subscribe": // ours never changes
+		return true, nil
+	case "mining.authorize":
+		var worker, password string
+		if err := stringParams(req.Params, &worker, &password); err != nil {
+			return nil, err
+		}
+		if s.Extranonce1 == nil {
+			return nil, ErrNotSubscribed
+		}
+		if s.Worker != "" {
+			return s.Worker == worker, nil // one worker a connection
+		}
+		if err := srv.Handler.Authorize(s, worker, password); err != nil {
+			if _, ok := err.(*Error); !ok {
+				err = ErrUnauthorized
+			}
+			return false, err
+		}
+		s.Worker = worker
+		return true, nil
+	case "mining.submit":
+		var sub Submit
+		var en2, ntime, nonce string
+		if err := stringParams(req.Params, &sub.Worker, &sub.JobID, &en2, &ntime, &nonce); err != nil {
+			return nil, err
+		}
+		if s.Worker == "" || sub.Worker != s.Worker {
+			return nil, ErrUnauthorized
+		}
+		var err error
+		if sub.Extranonce2, err = hex.DecodeString(en2); err != nil || len(sub.Extranonce2) != srv.Extranonce2Size {
+			return nil, &Error{20, "Bad extranonce2"}
+		}
+		if sub.Time, err = hexUint32(ntime); err != nil {
+			return nil, &Error{20, "Bad ntime"}
+		}
+		if sub.Nonce, err = hexUint32(nonce); err != nil {
+			return nil, &Error{20, "Bad nonce"}
+		}
+		if err := srv.Handler.Submit(s, sub); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+	return nil, &Error{20, "Method not found: " + req.Method}
+}
+
+// extranonce1 is what Extranonce1 gives s, or else its id in Extranonce1Size
+// bytes, big endian
+func (srv *Server) extranonce1(s *Session) ([]byte, error) {
+	if srv.Extranonce1 != nil {
+		return srv.Extranonce1(s)
+	}
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, s.ID)
+	return b[8-srv.Extranonce1Size:], nil
+}
+
+// stringParams decodes the leading params into vs
+func stringParams(params []json.RawMessage, vs ...*string) error {
+	if len(params) < len(vs) {
+		return &Error{20, fmt.Sprintf("expected %d params, got %d", len(vs), len(params))}
+	}
+	for i, v := range vs {
+		if err := json.Unmarshal(params[i], v); err != nil {
+			return &Error{20, fmt.Sprintf("param %d: %v", i, err)}
+		}
+	}
+	return nil
+}
+
+// hexUint32 decodes 8 hex digits, as Stratum sends ntime and nonce
+func hexUint32(s string) (uint32, error) {
+	if len(s) != 8 {
+		return 0, errors.New("expected 8 hex digits")
+	}
+	v, err := strconv.ParseUint(s, 16, 32)
+	return uint32(v), err
+}
+
+// Session is one miner's connection
+type Session struct {
+	ID          uint64
+	Extranonce1 []byte // set by mining.subscribe
+	Worker      string // set by mining.authorize
+
+	conn net.Conn
+	mu   sync.Mutex // serialises writes
+	enc  *json.Encoder
+	done chan struct{}
+}
+
+// RemoteAddr is the address of the miner
+func (s *Session) RemoteAddr() net.Addr {
+	return s.conn.RemoteAddr()
+}
+
+// Done is closed when the connection has gone
+func (s *Session) Done() <-chan struct{} {
+	return s.done
+}
+
+// Close ends the session
+func (s *Session) Close() {
+	s.conn.Close()
+}
+
+// Notify sends j to the miner
+func (s *Session) Notify(j *Job) error {
+	return s.send(map[string]interface{}{"id": nil, "method": "mining.notify", "params": j.params()})
+}
+
+// SetDifficulty sets the share difficulty of the jobs that follow, 1 is the
+// target of bits 0x1d00ffff
+func (s *Session) SetDifficulty(d float64) error {
+	return s.send(map[string]interface{}{"id": nil, "method": "mining.set_difficulty", "params": []float64{d}})
+}
+
+func (s *Session) reply(id, result interface{}, err error) error {
+	var e interface{}
+	if err != nil {
+		se, ok := err.(*Error)
+		if !ok {
+			se = &Error{20, err.Error()}
+		}
+		e = se
+	}
+	return s.send(map[string]interface{}{"id": id, "result": result, "error": e})
+}
+
+func (s *Session) send(msg interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.enc.Encode(msg) // one line each
+}
@@ -0,0 +1,274 @@
+package stratum
+
+import (
+	"bufio"
+	"bytes"
+	"coin"
+	"encoding/hex"
+	"encoding/json"
+	"net"
+	"testing"
+)
+
+// pool is a Handler with one job, admitting worker 1.pi with key thekey
+type pool struct {
+	job    *Job
+	solved chan []byte // headers of accepted shares
+}
+
+func (p *pool) Authorize(s *Session, worker, password string) error {
+	if worker != "1.pi" || password != "thekey" {
+		return ErrUnauthorized
+	}
+	go func() {
+		s.SetDifficulty(0.5)
+		s.Notify(p.job)
+	}()
+	return nil
+}
+
+func (p *pool) Submit(s *Session, sub Submit) error {
+	if sub.JobID != p.job.ID {
+		return ErrJobNotFound
+	}
+	header, _, err := p.job.Header(s.Extranonce1, sub.Extranonce2, sub.Time, sub.Nonce)
+	if err != nil {
+		return err
+	}
+	p.solved <- header
+	return nil
+}
+
+func (p *pool) Closed(s *Session) {}
+
+// client is a scripted miner
+type client struct {
+	t     *testing.T
+	w     net.Conn
+	r     *bufio.Reader
+	id    int
+	notes []map[string]interface{} // notifications read while waiting for replies
+}
+
+// call sends method and returns its result and error
+func (c *client) call(method string, params ...interface{}) (interface{}, interface{}) {
+	c.id++
+	line, _ := json.Marshal(map[string]interface{}{"id": c.id, "method": method, "params": params})
+	if _, err := c.w.Write(append(line, '\n')); err != nil {
+		c.t.Fatal(err)
+	}
+	for {
+		m := c.read()
+		if m["id"] == nil {
+			c.notes = append(c.notes, m)
+			continue
+		}
+		if m["id"] != float64(c.id) {
+			c.t.Fatalf("reply to %v, expected %d", m["id"], c.id)
+		}
+		return m["result"], m["error"]
+	}
+}
+
+// note returns the next notification
+func (c *client) note() map[string]interface{} {
+	if len(c.notes) == 0 {
+		return c.read()
+	}
+	m := c.notes[0]
+	c.notes = c.notes[1:]
+	return m
+}
+
+func (c *client) read() map[string]interface{} {
+	line, err := c.r.ReadBytes('\n')
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(line, &m); err != nil {
+		c.t.Fatalf("%s: %v", line, err)
+	}
+	return m
+}
+
+// code is the code of a Stratum error
+func code(e interface{}) float64 {
+	if a, ok := e.([]interface{}); ok && len(a) == 3 {
+		return a[0].(float64)
+	}
+	return 0
+}
+
+// fixture is a job on a coinbase and skeleton, with the header template
+type fixture struct {
+	job        *Job
+	cb, skel   []byte
+	start, end int // the coinbase extranonce
+	template   coin.Block
+}
+
+func newFixture(t *testing.T) *fixture {
+	upper, lower, err := coin.CoinbaseTemplates(433789, 8756123, "0225c141d69b74adac8ab984a8eb9fee42c4ce79cf6cb2be166b1ddc0356b37086")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cb, err := coin.GenCoinbase(upper, lower, 433789, 9, "0:pi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start, end, err := coin.Transaction(cb).Extranonce()
+	if err != nil {
+		t.Fatal(err)
+	}
+	skel, err := coin.Skeleton([]string{
+		"91c5e9f288437262f218c60f986e8bc10fb35ab3b9f6de477ff0eb554da89dea",
+		"46685c94b82b84fa05b6a0f36de6ff46475520113d5cb8c6fb060e043a0dbc5c",
+		"ba7ed2544c78ad793ef5bb0ebe0b1c62e8eb9404691165ffcb08662d1733d7a8"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	template, err := coin.BlockHeader(2, "000000000000000117c80378b8da0e33559b5997f2ad55e2f7d18ec1975b9717", 0x53058b35, 0x19015f53)
+	if err != nil {
+		t.Fatal(err)
+	}
+	job := &Job{ID: "1f", Prev: template[4:36], Coinb1: cb[:start], Coinb2: cb[end:], Version: 2, Bits: 0x19015f53, Time: 0x53058b35, Clean: true}
+	for i := 0; i < len(skel); i += 32 {
+		job.Branch = append(job.Branch, skel[i:i+32])
+	}
+	return &fixture{job, cb, skel, start, end, template}
+}
+
+func TestSession(t *testing.T) {
+	f := newFixture(t)
+	job, cb, skel, start, end, template := f.job, f.cb, f.skel, f.start, f.end, f.template
+	p := &pool{job: job, solved: make(chan []byte, 1)}
+	srv := &Server{Handler: p, Extranonce1Size: 2, Extranonce2Size: 2}
+	a, b := net.Pipe()
+	defer b.Close()
+	go srv.ServeConn(a)
+	c := &client{t: t, w: b, r: bufio.NewReader(b)}
+
+	if _, e := c.call("mining.authorize", "1.pi", "thekey"); code(e) != 25 {
+		t.Errorf("authorize before subscribe: expected error 25, got %v", e)
+	}
+	r, e := c.call("mining.subscribe", "test/1.0")
+	sub, ok := r.([]interface{})
+	if e != nil || !ok || len(sub) != 3 || sub[1] != "0001" || sub[2] != float64(2) {
+		t.Fatalf("subscribe: %v %v", r, e)
+	}
+	if r, e := c.call("mining.authorize", "1.pi", "wrong"); r != false || code(e) != 24 {
+		t.Errorf("bad password: expected false and error 24, got %v %v", r, e)
+	}
+	if r, e := c.call("mining.authorize", "1.pi", "thekey"); r != true || e != nil {
+		t.Fatalf("authorize: %v %v", r, e)
+	}
+	if n := c.note(); n["method"] != "mining.set_difficulty" || n["params"].([]interface{})[0] != 0.5 {
+		t.Errorf("expected set_difficulty 0.5, got %v", n)
+	}
+	n := c.note()
+	params := n["params"].([]interface{})
+	if n["method"] != "mining.notify" || len(params) != 9 || params[0] != "1f" {
+		t.Fatalf("expected notify of job 1f, got %v", n)
+	}
+	// the previous block hash goes with each 4 byte word swapped
+	prev, want := hex.EncodeToString(template[4:36]), ""
+	for i := 0; i < 64; i += 8 {
+		want += prev[i+6:i+8] + prev[i+4:i+6] + prev[i+2:i+4] + prev[i:i+2]
+	}
+	if params[1] != want {
+		t.Errorf("prevhash\nExp: %s\nGot: %s\n", want, params[1])
+	}
+	if params[5] != "00000002" || params[6] != "19015f53" || params[7] != "53058b35" || params[8] != true {
+		t.Errorf("version, bits, time and clean: %v", params[5:])
+	}
+
+	// a share rebuilds to the header the coinbase would give
+	if r, e := c.call("mining.submit", "1.pi", "1f", "0a0b", "53058b35", "000000ff"); r != true || e != nil {
+		t.Fatalf("submit: %v %v", r, e)
+	}
+	got := <-p.solved
+	rolled := append([]byte(nil), cb...)
+	copy(rolled[start:end], []byte{0, 1, 0x0a, 0x0b}) // extranonce1 then extranonce2
+	root, err := coin.CoinbaseMerkle(rolled, skel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template.AddMerkle(root)
+	template.PutNonce(0xff)
+	if !bytes.Equal(got, template) {
+		t.Errorf("header\nExp: %x\nGot: %x\n", []byte(template), got)
+	}
+
+	for _, bad := range []struct {
+		params []interface{}
+		code   float64
+	}{
+		{[]interface{}{"1.pi", "2e", "0a0b", "53058b35", "000000ff"}, 21},
+		{[]interface{}{"2.pi", "1f", "0a0b", "53058b35", "000000ff"}, 24},
+		{[]interface{}{"1.pi", "1f", "0a0b0c", "53058b35", "000000ff"}, 20},
+		{[]interface{}{"1.pi", "1f", "0a0b", "53058b", "000000ff"}, 20},
+	} {
+		if r, e := c.call("mining.submit", bad.params...); r == true || code(e) != bad.code {
+			t.Errorf("submit %v: expected error %v, got %v %v", bad.params, bad.code, r, e)
+		}
+	}
+	if _, e := c.call("mining.nonsense"); code(e) != 20 {
+		t.Errorf("unknown method: expected error 20, got %v", e)
+	}
+}
+
+func TestClient(t *testing.T) {
+	f := newFixture(t)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	p := &pool{job: f.job, solved: make(chan []byte, 1)}
+	go (&Server{Handler: p, Extranonce1Size: 2, Extranonce2Size: 2}).Serve(l)
+
+	c, err := Dial(l.Addr().String(), "test/1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if !bytes.Equal(c.Extranonce1, []byte{0, 1}) || c.Extranonce2Size != 2 {
+		t.Errorf("subscribed with extranonce1 %x and extranonce2 size %d", c.Extranonce1, c.Extranonce2Size)
+	}
+	if err := c.Authorize("1.pi", "wrong"); err == nil || err.(*Error).Code != 24 {
+		t.Errorf("bad password: expected error 24, got %v", err)
+	}
+	if err := c.Authorize("1.pi", "thekey"); err != nil {
+		t.Fatal(err)
+	}
+	j := <-c.Jobs()
+	if j.ID != f.job.ID || !bytes.Equal(j.Prev, f.job.Prev) || !bytes.Equal(j.Coinb1, f.job.Coinb1) ||
+		!bytes.Equal(j.Coinb2, f.job.Coinb2) || len(j.Branch) != len(f.job.Branch) || j.Version != 2 ||
+		j.Bits != 0x19015f53 || j.Time != 0x53058b35 || !j.Clean || j.Difficulty != 0.5 {
+		t.Errorf("job\nExp: %+v\nGot: %+v\n", f.job, j)
+	}
+	if h, err := j.Height(); err != nil || h != 433789 {
+		t.Errorf("height: expected 433789, got %d %v", h, err)
+	}
+
+	sub := Submit{Worker: "1.pi", JobID: j.ID, Extranonce2: []byte{0x0a, 0x0b}, Time: j.Time, Nonce: 0xff}
+	if err := c.Submit(sub); err != nil {
+		t.Fatal(err)
+	}
+	want, _, _ := j.Header(c.Extranonce1, sub.Extranonce2, sub.Time, sub.Nonce)
+	if got := <-p.solved; !bytes.Equal(got, want) {
+		t.Errorf("submitted header\nExp: %x\nGot: %x\n", want, got)
+	}
+	sub.JobID = "2e"
+	if err := c.Submit(sub); err == nil || err.(*Error).Code != 21 {
+		t.Errorf("stale job: expected error 21, got %v", err)
+	}
+
+	if got := coin.Target2Bits(Target(1)); got != 0x1d00ffff {
+		t.Errorf("difficulty 1\nExp: %x\nGot: %x\n", 0x1d00ffff, got)
+	}
+	if got := coin.Difficulty(Target(0.5)); got < 0.4999 || got > 0.5001 {
+		t.Errorf("difficulty 0.5 round trips to %v", got)
+	}
+}
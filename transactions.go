@@ -118,6 +118,22 @@ func GenCoinbase(upperTemplate []byte, lowerTemplate []byte,
 	return buffer.Bytes(), nil
 }
 
+// CoinbaseMinerID recovers the miner id that GenCoinbase wrote into coinbase
+func CoinbaseMinerID(coinbase []byte) (uint32, error) {
+	pos := posLenScriptSig + 1 // the coinbasedata
+	if len(coinbase) <= pos {
+		return 0, errors.New("coinbase too short")
+	}
+	bhlen := int(coinbase[pos])
+	pos += 1 + bhlen + extralen
+	if bhlen > 4 || len(coinbase) < pos+mineridlen {
+		return 0, errors.New("coinbase data does not carry a miner id")
+	}
+	id := make([]byte, 4)
+	copy(id, coinbase[pos:pos+mineridlen])
+	return binary.LittleEndian.Uint32(id), nil
+}
+
 // CoinbaseTemplates is what the server uses to deploy the upper & lower templates
 func CoinbaseTemplates(blockHeight uint32, blockFees int, pubkey string) (upper, lower []byte, err error) {
 	var upperBuffer, lowerBuffer bytes.Buffer
@@ -134,7 +150,7 @@ func CoinbaseTemplates(blockHeight uint32, blockFees int, pubkey string) (upper,
 	binary.BigEndian.PutUint32(sequence, 0xffffffff)
 	locktime := make([]byte, 4) // all 0s here
 	//Satoshis to send.
-	satoshis := getValue(blockHeight) + blockFees
+	satoshis := BlockValue(blockHeight, blockFees)
 	amount := make([]byte, 8)
 	binary.LittleEndian.PutUint64(amount, uint64(satoshis))
 	// outout script
@@ -158,6 +174,11 @@ func CoinbaseTemplates(blockHeight uint32, blockFees int, pubkey string) (upper,
 	return upperBuffer.Bytes(), lowerBuffer.Bytes(), nil
 }
 
+// BlockValue is what the coinbase of a block at blockHeight may claim: subsidy plus fees
+func BlockValue(blockHeight uint32, blockFees int) int {
+	return getValue(blockHeight) + blockFees
+}
+
 // calculate the mining reward at this height
 func getValue(blockHeight uint32) int {
 	subsidy := 50 * BTC
@@ -251,6 +272,20 @@ func (t Transaction) putNonce(nonce uint32) error {
 	return nil
 }
 
+// Extranonce locates the extranonce of a coinbase transaction, so that a miner
+// may roll it in place: the coinbase is t[:start] + extranonce + t[end:]
+func (t Transaction) Extranonce() (start, end int, err error) {
+	if len(t) < posLenScriptSig+2 || t.outindex() != "ffffffff" {
+		return 0, 0, errors.New("not a coinbase transaction")
+	}
+	start = posLenScriptSig + 2 + int(t[posLenScriptSig+1])
+	end = start + extralen
+	if len(t) < end {
+		return 0, 0, errors.New("coinbase too short")
+	}
+	return start, end, nil
+}
+
 // getNonce fetches the nonce of a coinbase transaction
 func (t Transaction) getNonce() (uint32, error) {
 	// make sure this is a coinbase txn
@@ -226,3 +226,67 @@ func TestCoinbase(t *testing.T) {
 // 0225c141d69b74adac8ab984a8eb9fee42c4ce79cf6cb2be166b1ddc0356b37086 - pubkey
 // 164f1d1d6fce7e2e491352b95b4ea47b880c1546 - after Hash160
 // KyufBz2L22mZgxgeftJuDK7Fot4rMarX4sQ7v5SNE9eZhq1wSqVf - privkey
+
+func TestBlockValue(t *testing.T) {
+	tests := []struct {
+		height uint32
+		fees   int
+		value  int
+	}{
+		{0, 0, 50 * BTC},
+		{277316, 9094928, 25*BTC + 9094928}, // as in TestCoinbase
+		{433789, 8756123, 12.5*BTC + 8756123},
+		{64 * HalvingInterval, 1000, 1000},
+	}
+	for _, test := range tests {
+		if got := BlockValue(test.height, test.fees); got != test.value {
+			t.Errorf("height %d\nExp: %d\nGot: %d\n", test.height, test.value, got)
+		}
+	}
+}
+
+func TestCoinbaseMinerID(t *testing.T) {
+	upper, lower, err := CoinbaseTemplates(433789, 8756123, "0225c141d69b74adac8ab984a8eb9fee42c4ce79cf6cb2be166b1ddc0356b37086")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []int{1, 261789, 1<<24 - 1} {
+		cb, err := GenCoinbase(upper, lower, 433789, id, "0:abc")
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := CoinbaseMinerID(cb)
+		if err != nil || got != uint32(id) {
+			t.Errorf("\nExp: %d\nGot: %d %v\n", id, got, err)
+		}
+	}
+	if _, err := CoinbaseMinerID(upper); err == nil {
+		t.Error("expected an error for a truncated coinbase")
+	}
+}
+
+func TestExtranonce(t *testing.T) {
+	upper, lower, err := CoinbaseTemplates(433789, 8756123, "0225c141d69b74adac8ab984a8eb9fee42c4ce79cf6cb2be166b1ddc0356b37086")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cb, err := GenCoinbase(upper, lower, 433789, 7, "0:abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx := Transaction(cb)
+	start, end, err := tx.Extranonce()
+	if err != nil {
+		t.Fatal(err)
+	}
+	copy(cb[start:end], []byte{0x2a, 0, 0, 0})
+	if n, _ := tx.getNonce(); n != 0x2a {
+		t.Errorf("\nExp: %d\nGot: %d\n", 0x2a, n)
+	}
+	if id, _ := CoinbaseMinerID(cb); id != 7 { // rolling leaves the rest alone
+		t.Errorf("\nExp: %d\nGot: %d\n", 7, id)
+	}
+	if _, _, err := Transaction(upper[:30]).Extranonce(); err == nil {
+		t.Error("expected an error for a truncated coinbase")
+	}
+}
@@ -0,0 +1,128 @@
+// Package vardiff sets each miner's share target from its recent shares so
+// that a Pi Zero and a desktop both submit at about the same steady rate.
+// Targets are compact bits, as in a blockheader, so any target can be set.
+package vardiff
+
+import (
+	"math/big"
+	"sync"
+	"time"
+
+	"coin"
+)
+
+const (
+	window    = 8 // shares counted before retargeting early
+	maxFactor = 4 // a single retarget changes the target at most this much
+)
+
+type miner struct {
+	current uint32    // bits in the work last handed out, shares are judged on these
+	next    uint32    // bits for the next work
+	since   time.Time // start of the count
+	count   int       // shares since then
+}
+
+// Table holds the share targets of every miner
+type Table struct {
+	sync.Mutex
+	rate   float64       // shares per minute aimed for
+	period time.Duration // time window shares should take at rate
+	start  uint32        // bits for a new miner
+	limit  []byte        // the easiest target allowed
+	miners map[string]*miner
+}
+
+// New aims for rate shares per minute, starting miners on bits start and
+// never setting a target easier than limit
+func New(rate float64, start, limit uint32) *Table {
+	return &Table{
+		rate:   rate,
+		period: time.Duration(float64(window) / rate * float64(time.Minute)),
+		start:  start,
+		limit:  coin.Bits2Target(limit),
+		miners: make(map[string]*miner),
+	}
+}
+
+func (t *Table) get(name string, now time.Time) *miner {
+	m := t.miners[name]
+	if m == nil {
+		m = &miner{current: t.start, next: t.start, since: now}
+		t.miners[name] = m
+	}
+	return m
+}
+
+// Work returns the bits for the next work handed to name, they become the current ones
+func (t *Table) Work(name string, now time.Time) uint32 {
+	t.Lock()
+	defer t.Unlock()
+	m := t.get(name, now)
+	t.retarget(m, now) // catches miners too slow to submit anything
+	m.current = m.next
+	return m.current
+}
+
+// Current returns the bits name's shares are judged on
+func (t *Table) Current(name string) uint32 {
+	t.Lock()
+	defer t.Unlock()
+	if m := t.miners[name]; m != nil {
+		return m.current
+	}
+	return t.start
+}
+
+// Share counts an accepted share from name
+func (t *Table) Share(name string, now time.Time) {
+	t.Lock()
+	defer t.Unlock()
+	m := t.get(name, now)
+	m.count++
+	t.retarget(m, now)
+}
+
+// Forget drops a miner that has left
+func (t *Table) Forget(name string) {
+	t.Lock()
+	delete(t.miners, name)
+	t.Unlock()
+}
+
+// retarget scales m's next target by the ratio of the rate aimed for to the
+// rate seen, once a window of shares is in or the time they should take has passed
+func (t *Table) retarget(m *miner, now time.Time) {
+	elapsed := now.Sub(m.since)
+	if m.count < window && elapsed < t.period {
+		return
+	}
+	factor := float64(maxFactor) // nothing at all: as easy as allowed
+	if m.count > 0 {
+		seen := float64(m.count) / elapsed.Minutes()
+		factor = t.rate / seen
+	}
+	if factor > maxFactor {
+		factor = maxFactor
+	}
+	if factor < 1.0/maxFactor {
+		factor = 1.0 / maxFactor
+	}
+	m.next = t.scale(m.next, factor)
+	m.since = now
+	m.count = 0
+}
+
+// scale multiplies the target of bits by factor, within 1 and the limit
+func (t *Table) scale(bits uint32, factor float64) uint32 {
+	target := new(big.Float).SetInt(new(big.Int).SetBytes(coin.Bits2Target(bits)))
+	n, _ := target.Mul(target, big.NewFloat(factor)).Int(nil)
+	limit := new(big.Int).SetBytes(t.limit)
+	if n.Cmp(limit) > 0 {
+		n = limit
+	}
+	if n.Sign() <= 0 {
+		n.SetInt64(1)
+	}
+	return coin.Target2Bits(n.Bytes())
+}
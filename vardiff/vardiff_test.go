@@ -0,0 +1,51 @@
+package vardiff
+
+import (
+	"testing"
+	"time"
+
+	"coin"
+)
+
+func TestRetarget(t *testing.T) {
+	now := time.Date(2016, 10, 1, 12, 0, 0, 0, time.UTC)
+	table := New(6, 0x1f00ffff, 0x207fffff) // a window of 8 shares should take 80s
+	start := coin.Difficulty(coin.Bits2Target(0x1f00ffff))
+
+	if got := table.Work("fast", now); got != 0x1f00ffff {
+		t.Fatalf("new miners start on the start bits, got %x", got)
+	}
+	// 8 shares in 20s is 4 times too fast
+	for i := 1; i <= window; i++ {
+		table.Share("fast", now.Add(time.Duration(i)*2500*time.Millisecond))
+	}
+	if got := table.Current("fast"); got != 0x1f00ffff {
+		t.Errorf("shares are judged on the work handed out until the next, got %x", got)
+	}
+	bits := table.Work("fast", now.Add(21*time.Second))
+	if d := coin.Difficulty(coin.Bits2Target(bits)); d < 3.9*start || d > 4.1*start {
+		t.Errorf("expected about 4 times the difficulty, got %v (bits %x)", d/start, bits)
+	}
+
+	// a miner that submits nothing in a period is made easier, up to the limit
+	table.Work("slow", now)
+	for i := 1; i <= 10; i++ {
+		bits = table.Work("slow", now.Add(time.Duration(i)*81*time.Second))
+	}
+	if bits != 0x207fffff {
+		t.Errorf("expected the limit 207fffff, got %x", bits)
+	}
+
+	// on rate, nothing changes
+	table.Work("steady", now)
+	for i := 1; i <= window; i++ {
+		table.Share("steady", now.Add(time.Duration(i)*10*time.Second))
+	}
+	if bits = table.Work("steady", now.Add(81*time.Second)); bits != 0x1f00ffff {
+		t.Errorf("a miner on rate should keep its target, got %x", bits)
+	}
+	table.Forget("steady")
+	if got := table.Current("steady"); got != 0x1f00ffff {
+		t.Errorf("a forgotten miner starts again, got %x", got)
+	}
+}